@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"cmdb-crawler/internal/client"
 	"cmdb-crawler/internal/crawler"
@@ -21,9 +26,24 @@ var (
 	outputFormat string
 	maxDepth     int
 	maxWorkers   int
+	batchSize    int
+	rateLimit    float64
+	burst        int
 	includeStats bool
+	includeM2M   bool
+	withSchema   bool
 	prettyPrint  bool
 	summaryOnly  bool
+	crawlTimeout time.Duration
+	checkpointTo string
+	resumeFrom   string
+	previousFile string
+	colorByType  bool
+	appendOutput bool
+	csvAttrMode  string
+	csvInclude   []string
+	csvExclude   []string
+	csvFlatten   bool
 )
 
 // crawlCmd 爬取命令
@@ -45,11 +65,47 @@ var crawlCmd = &cobra.Command{
   # 输出为CSV格式
   cmdb-crawler crawl --format csv --output ./data/trees.csv
 
+  # CSV按CI类型拆分文件，附带该类型的全部属性列
+  cmdb-crawler crawl --format csv --csv-attributes type --csv-exclude password --output ./data/trees.csv
+
+  # 输出为Excel，每个服务树一个工作表
+  cmdb-crawler crawl --format xlsx --output ./data/trees.xlsx
+
+  # 生成可发布到Wiki的HTML报告（可折叠、可搜索）
+  cmdb-crawler crawl --format html --output ./data/trees.html
+
+  # 流式输出NDJSON，每行一个节点，适合超大规模CMDB
+  cmdb-crawler crawl --format ndjson --output ./data/nodes.ndjson
+
+  # 输出为SQLite，并将本次爬取追加为新快照
+  cmdb-crawler crawl --format sqlite --append --output ./data/cmdb.db
+
+  # 输出为Graphviz DOT图，按CI类型着色
+  cmdb-crawler crawl --format dot --color-by-type --max-depth 3 --output ./data/trees.dot
+
+  # 输出为GraphML/GEXF，供yEd或Gephi分析
+  cmdb-crawler crawl --format gexf --output ./data/trees.gexf
+
   # 限制爬取深度为3层
   cmdb-crawler crawl --max-depth 3
 
+  # 不爬取CI类型模型（默认会一并导出类型、属性和类型关系）
+  cmdb-crawler crawl --include-schema=false
+
   # 只输出摘要信息
-  cmdb-crawler crawl --summary-only`,
+  cmdb-crawler crawl --summary-only
+
+  # 限制整次爬取最多运行10分钟
+  cmdb-crawler crawl --crawl-timeout 10m
+
+  # 记录检查点日志，中断后从日志继续
+  cmdb-crawler crawl --checkpoint ./output/crawl.journal
+  cmdb-crawler crawl --resume ./output/crawl.journal
+
+  # 增量爬取：只重新爬取上次快照之后发生变更的子树
+  cmdb-crawler crawl --incremental ./output/service_tree_data.json
+
+按下Ctrl+C（SIGINT）或收到SIGTERM时会停止爬取，并导出已获取的部分数据。`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runCrawl(cmd)
 	},
@@ -61,18 +117,36 @@ func init() {
 	// 命令标志
 	crawlCmd.Flags().StringSliceVar(&targetViews, "views", []string{}, "指定要爬取的服务树视图名称（逗号分隔）")
 	crawlCmd.Flags().StringVarP(&outputPath, "output", "o", "", "输出文件路径")
-	crawlCmd.Flags().StringVarP(&outputFormat, "format", "f", "", "输出格式 (json, yaml, csv)")
+	crawlCmd.Flags().StringVarP(&outputFormat, "format", "f", "", "输出格式 (json, yaml, csv, xlsx, ndjson, sqlite, markdown, html, dot, graphml, gexf)")
 	crawlCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "最大爬取深度 (-1表示无限制)")
 	crawlCmd.Flags().IntVar(&maxWorkers, "max-workers", 0, "最大并发数")
+	crawlCmd.Flags().IntVar(&batchSize, "batch-size", 0, "同一层合并为一次关系查询的最大节点数 (1表示逐个节点查询)")
+	crawlCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "所有并发请求合计的每秒请求数 (0表示使用配置文件)")
+	crawlCmd.Flags().IntVar(&burst, "burst", 0, "限流允许的突发请求数")
 	crawlCmd.Flags().BoolVar(&includeStats, "include-stats", true, "是否包含统计信息")
+	crawlCmd.Flags().BoolVar(&includeM2M, "m2m", false, "按多对多关系爬取：查询时带上祖先路径，并标记出现在多个父节点下的CI")
+	crawlCmd.Flags().BoolVar(&withSchema, "include-schema", true, "是否同时爬取并导出CI类型模型（类型、属性和类型关系）")
 	crawlCmd.Flags().BoolVar(&prettyPrint, "pretty", false, "是否美化输出格式")
 	crawlCmd.Flags().BoolVar(&summaryOnly, "summary-only", false, "只输出摘要信息")
+	crawlCmd.Flags().DurationVar(&crawlTimeout, "crawl-timeout", 0, "整次爬取的最长运行时间 (0表示无限制)")
+	crawlCmd.Flags().StringVar(&checkpointTo, "checkpoint", "", "检查点日志路径，记录已完成的视图和子树")
+	crawlCmd.Flags().StringVar(&resumeFrom, "resume", "", "从检查点日志恢复爬取，跳过已完成的部分并继续记录")
+	crawlCmd.Flags().BoolVar(&appendOutput, "append", false, "追加新快照而不是覆盖输出文件 (sqlite格式)")
+	crawlCmd.Flags().StringVar(&csvAttrMode, "csv-attributes", "", "CSV属性列: none, view (每个视图一个文件), type (每个CI类型一个文件)")
+	crawlCmd.Flags().StringSliceVar(&csvInclude, "csv-include", []string{}, "CSV只输出这些属性列（逗号分隔，按给定顺序）")
+	crawlCmd.Flags().StringSliceVar(&csvExclude, "csv-exclude", []string{}, "CSV不输出的属性列（逗号分隔）")
+	crawlCmd.Flags().BoolVar(&csvFlatten, "csv-flatten", false, "CSV展开对象属性为子列，列表用|连接（默认编码为JSON）")
+	crawlCmd.Flags().BoolVar(&colorByType, "color-by-type", false, "图形导出时按CI类型着色")
+	crawlCmd.Flags().StringVar(&previousFile, "incremental", "", "上次导出的JSON/YAML文件，基于变更历史只重新爬取变更的子树")
 }
 
 // runCrawl 执行爬取操作
 func runCrawl(cmd *cobra.Command) error {
 	logger := GetLogger()
-	config := GetConfig()
+	config, err := GetConfig()
+	if err != nil {
+		return fmt.Errorf("配置无效: %w", err)
+	}
 
 	// 合并命令行参数和配置文件
 	mergeFlags(config, cmd)
@@ -83,35 +157,68 @@ func runCrawl(cmd *cobra.Command) error {
 		zap.String("output_format", config.Output.Format),
 		zap.String("output_path", config.Output.FilePath))
 
-	// 创建CMDB客户端
-	cmdbClient := client.NewCMDBClient(config.CMDB.BaseURL, config.CMDB.APIVersion, logger)
+	// 爬取前校验导出配置，避免爬取完成后才发现配置错误
+	if _, err := newExporter(config, logger); err != nil {
+		return fmt.Errorf("输出配置无效: %w", err)
+	}
 
-	// 设置API Key认证
-	if config.CMDB.Auth.APIKey == "" || config.CMDB.Auth.APISecret == "" {
-		logger.Fatal("API Key和Secret不能为空，请在配置文件中设置")
+	// NDJSON格式边爬取边写入，检查点和增量模式依赖完整的树
+	streaming := !summaryOnly && strings.EqualFold(config.Output.Format, string(output.FormatNDJSON))
+	if streaming && (checkpointTo != "" || resumeFrom != "" || previousFile != "") {
+		return fmt.Errorf("NDJSON流式导出不支持 --checkpoint、--resume 和 --incremental")
 	}
 
-	logger.Info("Using API Key authentication")
-	cmdbClient.SetAPICredentials(config.CMDB.Auth.APIKey, config.CMDB.Auth.APISecret)
+	// 创建CMDB客户端和爬取器
+	cmdbClient := newCMDBClient(config, logger)
+	serviceCrawler := newServiceTreeCrawler(cmdbClient, config, logger)
 
-	// 设置请求配置
-	cmdbClient.SetTimeout(config.CMDB.Request.Timeout).
-		SetRetry(config.CMDB.Request.RetryCount, config.CMDB.Request.RetryWaitTime)
+	// 打开检查点日志
+	checkpoint, err := openCheckpoint(logger)
+	if err != nil {
+		return fmt.Errorf("打开检查点日志失败: %w", err)
+	}
+	if checkpoint != nil {
+		defer checkpoint.Close()
+		serviceCrawler.SetCheckpoint(checkpoint)
+	}
 
-	// 创建爬取器
-	serviceCrawler := crawler.NewServiceTreeCrawler(cmdbClient, logger)
-	serviceCrawler.SetMaxDepth(config.Crawler.ServiceTree.MaxDepth).
-		SetPageSize(config.Crawler.ServiceTree.PageSize).
-		SetMaxWorkers(config.Crawler.Concurrency.MaxWorkers).
-		SetIncludeStats(config.Crawler.ServiceTree.IncludeStatistics).
-		SetRequestInterval(config.Crawler.Concurrency.RequestInterval)
+	// 执行爬取，收到SIGINT/SIGTERM或超时后取消
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if crawlTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, crawlTimeout)
+		defer cancel()
+	}
+
+	var stream *output.NodeStream
+	if streaming {
+		// 固定输出路径，导出摘要时使用同一个文件名
+		config.Output.FilePath = outputFilePath(config, logger)
+		exporter, exporterErr := newExporter(config, logger)
+		if exporterErr != nil {
+			return exporterErr
+		}
+		stream, err = exporter.OpenNodeStream(config.Output.FilePath)
+		if err != nil {
+			return fmt.Errorf("打开NDJSON输出文件失败: %w", err)
+		}
+		serviceCrawler.SetNodeSink(stream)
+	}
 
-	// 执行爬取
-	ctx := context.Background()
 	var treeData []*models.ServiceTreeData
-	var err error
 
-	if len(config.Crawler.ServiceTree.TargetViews) > 0 {
+	if previousFile != "" {
+		previous, loadErr := output.LoadServiceTrees(previousFile)
+		if loadErr != nil {
+			return fmt.Errorf("读取上次导出文件失败: %w", loadErr)
+		}
+		logger.Info("增量爬取服务树视图",
+			zap.String("previous", previousFile),
+			zap.Int("previous_trees", len(previous.ServiceTrees)))
+		treeData, err = serviceCrawler.CrawlIncremental(ctx, previous.ServiceTrees, config.Crawler.ServiceTree.TargetViews)
+	} else if len(config.Crawler.ServiceTree.TargetViews) > 0 {
 		logger.Info("爬取指定的服务树视图",
 			zap.Strings("views", config.Crawler.ServiceTree.TargetViews))
 		treeData, err = serviceCrawler.CrawlSpecificViews(ctx, config.Crawler.ServiceTree.TargetViews)
@@ -120,29 +227,190 @@ func runCrawl(cmd *cobra.Command) error {
 		treeData, err = serviceCrawler.CrawlAllServiceTrees(ctx)
 	}
 
-	if err != nil {
+	if stream != nil {
+		if closeErr := stream.Close(); closeErr != nil {
+			return fmt.Errorf("写入NDJSON输出文件失败: %w", closeErr)
+		}
+	}
+
+	// 被中断时导出已获取的部分数据
+	interrupted := err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+	if err != nil && !interrupted {
 		logger.Error("爬取失败", zap.Error(err))
 		return fmt.Errorf("爬取服务树数据失败: %w", err)
 	}
 
+	if interrupted {
+		logger.Warn("爬取被中断，导出已获取的部分数据",
+			zap.Int("completed_trees", len(treeData)),
+			zap.Error(err))
+		fmt.Printf("警告: 爬取被中断 (%v)，以下为部分结果\n", err)
+		if checkpoint != nil {
+			fmt.Printf("可使用 --resume %s 继续爬取\n", checkpoint.Path())
+		}
+		if len(treeData) == 0 {
+			return fmt.Errorf("爬取被中断: %w", err)
+		}
+	}
+
 	if len(treeData) == 0 {
 		logger.Warn("未找到任何服务树数据")
 		fmt.Println("警告: 未找到任何服务树数据")
 		return nil
 	}
 
+	// 爬取CI类型模型，失败时只导出服务树
+	var schema *models.CITypeSchema
+	if config.Crawler.ServiceTree.IncludeSchema && !summaryOnly && !interrupted {
+		var schemaErr error
+		schema, schemaErr = serviceCrawler.CrawlSchema(ctx)
+		if schemaErr != nil {
+			logger.Warn("爬取CI类型模型失败，导出结果中不包含模型", zap.Error(schemaErr))
+			fmt.Printf("警告: 爬取CI类型模型失败: %v\n", schemaErr)
+		}
+	}
+
 	// 输出结果
-	if err := exportResults(treeData, config, logger); err != nil {
+	if err := exportResults(treeData, schema, config, logger, stream != nil); err != nil {
 		logger.Error("导出结果失败", zap.Error(err))
 		return fmt.Errorf("导出结果失败: %w", err)
 	}
 
 	// 输出统计信息
 	printSummary(treeData, logger)
+	logRateLimitStats(cmdbClient, logger)
+
+	if interrupted {
+		return fmt.Errorf("爬取被中断: %w", err)
+	}
 
 	return nil
 }
 
+// newCMDBClient 根据配置创建CMDB客户端
+func newCMDBClient(config *Config, logger *zap.Logger) *client.CMDBClient {
+	cmdbClient := client.NewCMDBClient(config.CMDB.BaseURL, config.CMDB.APIVersion, logger)
+
+	// 设置认证方式
+	auth, err := newAuthenticator(config.CMDB, logger)
+	if err != nil {
+		logger.Fatal("认证配置错误", zap.Error(err))
+	}
+	cmdbClient.SetAuthenticator(auth)
+
+	// 设置请求配置
+	retryPolicy := client.DefaultRetryPolicy()
+	retryPolicy.MaxRetries = config.CMDB.Request.RetryCount
+	retryPolicy.InitialInterval = config.CMDB.Request.RetryWaitTime
+	retryPolicy.MaxInterval = config.CMDB.Request.RetryMaxWaitTime
+	retryPolicy.Jitter = config.CMDB.Request.RetryJitter
+	retryPolicy.MaxElapsedTime = config.CMDB.Request.RetryMaxElapsedTime
+
+	rps, burst := clientRateLimit(config.Crawler.Concurrency)
+	cmdbClient.SetTimeout(config.CMDB.Request.Timeout).
+		SetRetryPolicy(retryPolicy).
+		SetRateLimit(rps, burst)
+
+	return cmdbClient
+}
+
+// clientRateLimit 返回客户端限流的每秒请求数和突发请求数
+// 没有配置 rate_limit 时，request_interval 按每个协程一个间隔换算为合计速率 max_workers/request_interval，
+// 请求频率统一由客户端限流器控制，爬取器不再单独等待
+func clientRateLimit(concurrency ConcurrencyConfig) (float64, int) {
+	if concurrency.RateLimit > 0 || concurrency.RequestInterval <= 0 {
+		return concurrency.RateLimit, concurrency.Burst
+	}
+
+	workers := concurrency.MaxWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	burst := concurrency.Burst
+	if burst <= 0 {
+		burst = workers
+	}
+	return float64(workers) / concurrency.RequestInterval.Seconds(), burst
+}
+
+// logRateLimitStats 记录限流等待统计
+func logRateLimitStats(cmdbClient *client.CMDBClient, logger *zap.Logger) {
+	stats := cmdbClient.RateLimitStats()
+	logger.Info("请求限流统计",
+		zap.Int64("requests", stats.Requests),
+		zap.Int64("delayed", stats.Delayed),
+		zap.Int64("throttled", stats.Throttled),
+		zap.Duration("total_wait", stats.TotalWait),
+		zap.Duration("max_wait", stats.MaxWait),
+		zap.Float64("current_rate", stats.CurrentRate))
+}
+
+// newAuthenticator 根据 cmdb.auth.type 创建认证方式
+func newAuthenticator(cmdb CMDBConfig, logger *zap.Logger) (client.Authenticator, error) {
+	auth := cmdb.Auth
+	loginURL := strings.TrimRight(cmdb.BaseURL, "/") + "/" + strings.TrimLeft(auth.LoginPath, "/")
+	hasLogin := auth.Username != "" && auth.Password != ""
+
+	switch auth.Type {
+	case "", "api_key":
+		if auth.APIKey == "" || auth.APISecret == "" {
+			return nil, fmt.Errorf("API Key和Secret不能为空，请在配置文件中设置")
+		}
+		logger.Info("Using API Key authentication")
+		return client.NewAPIKeyAuth(auth.APIKey, auth.APISecret, logger), nil
+	case "jwt":
+		if auth.Token == "" && !hasLogin {
+			return nil, fmt.Errorf("JWT认证需要设置token或username/password")
+		}
+		logger.Info("Using JWT authentication", zap.String("login_url", loginURL))
+		return client.NewJWTAuth(loginURL, auth.Username, auth.Password, logger).
+			SetRedactor(redactor).
+			SetToken(auth.Token), nil
+	case "session":
+		if auth.SessionCookie == "" && !hasLogin {
+			return nil, fmt.Errorf("会话认证需要设置session_cookie或username/password")
+		}
+		logger.Info("Using session cookie authentication", zap.String("login_url", loginURL))
+		sessionAuth := client.NewSessionAuth(loginURL, auth.Username, auth.Password, logger).SetRedactor(redactor)
+		if auth.SessionCookie != "" {
+			sessionAuth.SetCookie(auth.SessionCookie)
+		}
+		return sessionAuth, nil
+	default:
+		return nil, fmt.Errorf("不支持的认证方式: %s (可选 api_key, jwt, session)", auth.Type)
+	}
+}
+
+// newServiceTreeCrawler 根据配置创建服务树爬取器
+func newServiceTreeCrawler(cmdbClient *client.CMDBClient, config *Config, logger *zap.Logger) *crawler.ServiceTreeCrawler {
+	serviceCrawler := crawler.NewServiceTreeCrawler(cmdbClient, logger)
+	serviceCrawler.SetMaxDepth(config.Crawler.ServiceTree.MaxDepth).
+		SetPageSize(config.Crawler.ServiceTree.PageSize).
+		SetMaxWorkers(config.Crawler.Concurrency.MaxWorkers).
+		SetBatchSize(config.Crawler.ServiceTree.BatchSize).
+		SetIncludeStats(config.Crawler.ServiceTree.IncludeStatistics).
+		SetIncludeM2M(config.Crawler.ServiceTree.HasM2M).
+		SetRetryPolicy(cmdbClient.RetryPolicy()). // 与客户端共用重试策略，客户端已重试的错误不再重试
+		SetRequestInterval(0)                     // 请求频率由客户端限流器控制，见 clientRateLimit
+
+	return serviceCrawler
+}
+
+// openCheckpoint 根据--checkpoint/--resume参数打开检查点日志，未指定时返回nil
+func openCheckpoint(logger *zap.Logger) (*crawler.Checkpoint, error) {
+	if resumeFrom != "" {
+		logger.Info("从检查点日志恢复爬取", zap.String("journal", resumeFrom))
+		return crawler.OpenCheckpoint(resumeFrom, true, logger)
+	}
+
+	if checkpointTo != "" {
+		logger.Info("记录检查点日志", zap.String("journal", checkpointTo))
+		return crawler.OpenCheckpoint(checkpointTo, false, logger)
+	}
+
+	return nil, nil
+}
+
 // mergeFlags 合并命令行参数和配置文件
 func mergeFlags(config *Config, cmd *cobra.Command) {
 	// 目标视图
@@ -170,56 +438,151 @@ func mergeFlags(config *Config, cmd *cobra.Command) {
 		config.Crawler.Concurrency.MaxWorkers = maxWorkers
 	}
 
+	// 批量查询大小
+	if batchSize > 0 {
+		config.Crawler.ServiceTree.BatchSize = batchSize
+	}
+
+	// 限流
+	if rateLimit > 0 {
+		config.Crawler.Concurrency.RateLimit = rateLimit
+	}
+	if burst > 0 {
+		config.Crawler.Concurrency.Burst = burst
+	}
+
 	// 统计信息
 	if cmd.Flags().Changed("include-stats") {
 		config.Crawler.ServiceTree.IncludeStatistics = includeStats
 	}
 
+	// 多对多关系
+	if cmd.Flags().Changed("m2m") {
+		config.Crawler.ServiceTree.HasM2M = includeM2M
+	}
+
+	// CI类型模型
+	if cmd.Flags().Changed("include-schema") {
+		config.Crawler.ServiceTree.IncludeSchema = withSchema
+	}
+
 	// 美化输出
 	if cmd.Flags().Changed("pretty") {
 		config.Output.PrettyPrint = prettyPrint
 	}
+
+	// 追加快照
+	if cmd.Flags().Changed("append") {
+		config.Output.Append = appendOutput
+	}
+
+	// 图形按类型着色
+	if cmd.Flags().Changed("color-by-type") {
+		config.Output.ColorByType = colorByType
+	}
+
+	// CSV属性列
+	if csvAttrMode != "" {
+		config.Output.CSV.Attributes = csvAttrMode
+	}
+	if len(csvInclude) > 0 {
+		config.Output.CSV.Include = csvInclude
+	}
+	if len(csvExclude) > 0 {
+		config.Output.CSV.Exclude = csvExclude
+	}
+	if cmd.Flags().Changed("csv-flatten") {
+		config.Output.CSV.Flatten = csvFlatten
+	}
 }
 
-// exportResults 导出结果
-func exportResults(treeData []*models.ServiceTreeData, config *Config, logger *zap.Logger) error {
-	// 创建导出器
-	exporter := output.NewExporter(config.Output.Format, config.Output.PrettyPrint, logger)
+// exportResults 导出结果，streamed为true时节点已在爬取过程中写入，只需导出摘要
+// schema不为nil时随服务树导出，不能内嵌模型的格式单独导出到 *_schema.json
+func exportResults(treeData []*models.ServiceTreeData, schema *models.CITypeSchema, config *Config,
+	logger *zap.Logger, streamed bool) error {
 
-	// 生成输出文件路径
-	outputFile := config.Output.FilePath
-	if outputFile == "" {
-		filename := exporter.GenerateFileName("service_tree_data", true)
-		outputFile = filepath.Join("./output", filename)
+	exporter, err := newExporter(config, logger)
+	if err != nil {
+		return err
 	}
+	exporter.SetSchema(schema)
+	outputFile := outputFilePath(config, logger)
+	summaryFile := strings.Replace(outputFile, filepath.Ext(outputFile), "_summary"+filepath.Ext(outputFile), 1)
 
 	// 导出数据
 	if summaryOnly {
 		// 只导出摘要
-		summaryFile := strings.Replace(outputFile, filepath.Ext(outputFile), "_summary"+filepath.Ext(outputFile), 1)
 		if err := exporter.ExportSummary(treeData, summaryFile); err != nil {
 			return err
 		}
 		fmt.Printf("摘要信息已导出到: %s\n", summaryFile)
-	} else {
-		// 导出完整数据
+		return nil
+	}
+
+	// 导出完整数据
+	if !streamed {
 		if err := exporter.ExportServiceTrees(treeData, outputFile); err != nil {
 			return err
 		}
+	}
+	if exporter.SplitsCSVFiles() {
+		ext := filepath.Ext(outputFile)
+		fmt.Printf("数据已按%s拆分导出到: %s\n", config.Output.CSV.Attributes, strings.TrimSuffix(outputFile, ext)+"_*"+ext)
+	} else {
 		fmt.Printf("数据已导出到: %s\n", outputFile)
+	}
 
-		// 同时生成摘要文件
-		summaryFile := strings.Replace(outputFile, filepath.Ext(outputFile), "_summary"+filepath.Ext(outputFile), 1)
-		if err := exporter.ExportSummary(treeData, summaryFile); err != nil {
-			logger.Warn("生成摘要文件失败", zap.Error(err))
-		} else {
-			fmt.Printf("摘要信息已导出到: %s\n", summaryFile)
+	// 不能内嵌模型的格式单独导出模型文件
+	if schema != nil && !exporter.EmbedsSchema() {
+		schemaFile := exporter.SchemaFilePath(outputFile)
+		if err := exporter.ExportSchema(schema, schemaFile); err != nil {
+			return err
 		}
+		fmt.Printf("CI类型模型已导出到: %s\n", schemaFile)
+	}
+
+	// 同时生成摘要文件
+	if !exporter.SupportsSummary() {
+		return nil
+	}
+	if err := exporter.ExportSummary(treeData, summaryFile); err != nil {
+		logger.Warn("生成摘要文件失败", zap.Error(err))
+	} else {
+		fmt.Printf("摘要信息已导出到: %s\n", summaryFile)
 	}
 
 	return nil
 }
 
+// newExporter 根据配置创建导出器
+func newExporter(config *Config, logger *zap.Logger) (*output.Exporter, error) {
+	csvMode, err := output.ParseCSVAttributeMode(config.Output.CSV.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.NewExporter(config.Output.Format, config.Output.PrettyPrint, logger).
+		SetMaxDepth(config.Crawler.ServiceTree.MaxDepth).
+		SetColorByType(config.Output.ColorByType).
+		SetAppend(config.Output.Append).
+		SetCSVOptions(output.CSVOptions{
+			Attributes: csvMode,
+			Include:    config.Output.CSV.Include,
+			Exclude:    config.Output.CSV.Exclude,
+			Flatten:    config.Output.CSV.Flatten,
+		}), nil
+}
+
+// outputFilePath 返回输出文件路径，未配置时按格式生成带时间戳的文件名
+func outputFilePath(config *Config, logger *zap.Logger) string {
+	if config.Output.FilePath != "" {
+		return config.Output.FilePath
+	}
+	filename := output.NewExporter(config.Output.Format, config.Output.PrettyPrint, logger).
+		GenerateFileName("service_tree_data", true)
+	return filepath.Join("./output", filename)
+}
+
 // printSummary 打印统计摘要
 func printSummary(treeData []*models.ServiceTreeData, logger *zap.Logger) {
 	totalTrees := len(treeData)
@@ -238,6 +601,9 @@ func printSummary(treeData []*models.ServiceTreeData, logger *zap.Logger) {
 		fmt.Printf("\n服务树: %s (ID: %d)\n", tree.ViewName, tree.ViewID)
 		fmt.Printf("  根节点数: %d\n", len(tree.RootNodes))
 		fmt.Printf("  总节点数: %d\n", tree.TotalNodes)
+		if len(tree.SharedNodes) > 0 {
+			fmt.Printf("  多父节点CI数: %d\n", len(tree.SharedNodes))
+		}
 		fmt.Printf("  最大深度: %d\n", tree.MaxDepth)
 		fmt.Printf("  是否公开: %t\n", tree.Config.IsPublic)
 		fmt.Printf("  爬取时间: %s\n", tree.CrawledAt.Format("2006-01-02 15:04:05"))
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"cmdb-crawler/internal/crawler"
+	"cmdb-crawler/internal/models"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var ciTypeNames []string
+
+// crawlCIsCmd 全量CI爬取命令
+var crawlCIsCmd = &cobra.Command{
+	Use:   "crawl-cis",
+	Short: "爬取全部CI实例（不依赖服务树视图）",
+	Long: `枚举CMDB中的全部CI类型，按类型分页拉取所有CI及其全部属性
+
+服务树只包含视图中配置的CI类型，服务器、IP等未出现在任何视图中的CI不会被导出。
+该命令按CI类型并发爬取（并发数受 max_workers 限制），输出格式支持 json、yaml、csv、ndjson。
+CSV格式每个CI类型一个文件，如 cis.csv 会拆分为 cis_1_server.csv、cis_2_ip.csv 等，
+属性列同样受 --csv-include、--csv-exclude 和 --csv-flatten 控制。
+
+示例：
+  # 导出全部CI
+  cmdb-crawler crawl-cis --output ./data/cis.json
+
+  # 只导出服务器和IP（按类型名称或别名匹配）
+  cmdb-crawler crawl-cis --types server,ip --format csv --output ./data/cis.csv
+
+  # 每行一个CI，便于导入其他系统
+  cmdb-crawler crawl-cis --format ndjson --max-workers 4 --output ./data/cis.ndjson`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCrawlCIs(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(crawlCIsCmd)
+
+	crawlCIsCmd.Flags().StringSliceVar(&ciTypeNames, "types", []string{}, "只爬取这些CI类型（名称或别名，逗号分隔）")
+	crawlCIsCmd.Flags().StringVarP(&outputPath, "output", "o", "", "输出文件路径")
+	crawlCIsCmd.Flags().StringVarP(&outputFormat, "format", "f", "", "输出格式 (json, yaml, csv, ndjson)")
+	crawlCIsCmd.Flags().IntVar(&maxWorkers, "max-workers", 0, "同时爬取的CI类型数")
+	crawlCIsCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "所有并发请求合计的每秒请求数 (0表示使用配置文件)")
+	crawlCIsCmd.Flags().IntVar(&burst, "burst", 0, "限流允许的突发请求数")
+	crawlCIsCmd.Flags().BoolVar(&prettyPrint, "pretty", false, "是否美化输出格式")
+	crawlCIsCmd.Flags().DurationVar(&crawlTimeout, "crawl-timeout", 0, "整次爬取的最长运行时间 (0表示无限制)")
+	crawlCIsCmd.Flags().StringSliceVar(&csvInclude, "csv-include", []string{}, "CSV只输出这些属性列（逗号分隔，按给定顺序）")
+	crawlCIsCmd.Flags().StringSliceVar(&csvExclude, "csv-exclude", []string{}, "CSV不输出的属性列（逗号分隔）")
+	crawlCIsCmd.Flags().BoolVar(&csvFlatten, "csv-flatten", false, "CSV展开对象属性为子列，列表用|连接（默认编码为JSON）")
+}
+
+// runCrawlCIs 执行全量CI爬取
+func runCrawlCIs(cmd *cobra.Command) error {
+	logger := GetLogger()
+	config, err := GetConfig()
+	if err != nil {
+		return fmt.Errorf("配置无效: %w", err)
+	}
+
+	mergeFlags(config, cmd)
+
+	exporter, err := newExporter(config, logger)
+	if err != nil {
+		return fmt.Errorf("输出配置无效: %w", err)
+	}
+	if !exporter.SupportsInventory() {
+		return fmt.Errorf("全量CI导出不支持 %s 格式，可选 json、yaml、csv、ndjson", config.Output.Format)
+	}
+
+	outputFile := outputPath
+	if outputFile == "" {
+		outputFile = filepath.Join("./output", exporter.GenerateFileName("ci_inventory", true))
+	}
+
+	logger.Info("开始爬取全部CI",
+		zap.String("cmdb_url", config.CMDB.BaseURL),
+		zap.Strings("types", ciTypeNames),
+		zap.String("output_format", config.Output.Format),
+		zap.String("output_path", outputFile))
+
+	cmdbClient := newCMDBClient(config, logger)
+	ciCrawler := crawler.NewCICrawler(cmdbClient, logger).
+		SetPageSize(config.Crawler.ServiceTree.PageSize).
+		SetMaxWorkers(config.Crawler.Concurrency.MaxWorkers).
+		SetRetryPolicy(cmdbClient.RetryPolicy()). // 与客户端共用重试策略，客户端已重试的错误不再重试
+		SetRequestInterval(0).                    // 请求频率由客户端限流器控制，见 clientRateLimit
+		SetTypeFilter(ciTypeNames)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if crawlTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, crawlTimeout)
+		defer cancel()
+	}
+
+	inventory, err := ciCrawler.CrawlAllCIs(ctx)
+	interrupted := err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+	if err != nil && !interrupted {
+		logger.Error("爬取失败", zap.Error(err))
+		return fmt.Errorf("爬取CI失败: %w", err)
+	}
+
+	if interrupted {
+		logger.Warn("爬取被中断，导出已获取的部分数据",
+			zap.Int("completed_types", len(inventory.Types)),
+			zap.Error(err))
+		fmt.Printf("警告: 爬取被中断 (%v)，以下为部分结果\n", err)
+	}
+
+	if err := exporter.ExportCIInventory(inventory, outputFile); err != nil {
+		logger.Error("导出结果失败", zap.Error(err))
+		return fmt.Errorf("导出结果失败: %w", err)
+	}
+	fmt.Printf("数据已导出到: %s\n", outputFile)
+
+	printInventorySummary(inventory, logger)
+	logRateLimitStats(cmdbClient, logger)
+
+	if interrupted {
+		return fmt.Errorf("爬取被中断: %w", err)
+	}
+	return nil
+}
+
+// printInventorySummary 打印全量CI统计摘要
+func printInventorySummary(inventory *models.CIInventory, logger *zap.Logger) {
+	failed := 0
+
+	fmt.Println("\n=== 全量CI摘要 ===")
+	for _, typeInventory := range inventory.Types {
+		ciType := typeInventory.Type
+		if typeInventory.Error != "" {
+			failed++
+			fmt.Printf("  %s (%s, ID: %d): 失败 - %s\n", ciType.DisplayName(), ciType.Name, ciType.ID, typeInventory.Error)
+			continue
+		}
+		fmt.Printf("  %s (%s, ID: %d): %d\n", ciType.DisplayName(), ciType.Name, ciType.ID, len(typeInventory.CIs))
+	}
+
+	fmt.Printf("\nCI类型数: %d\n", len(inventory.Types))
+	fmt.Printf("CI总数: %d\n", inventory.TotalCIs)
+	if failed > 0 {
+		fmt.Printf("失败类型数: %d\n", failed)
+	}
+	fmt.Println("==================")
+
+	logger.Info("爬取完成",
+		zap.Int("type_count", len(inventory.Types)),
+		zap.Int("total_cis", inventory.TotalCIs),
+		zap.Int("failed_types", failed))
+}
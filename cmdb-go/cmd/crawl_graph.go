@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"cmdb-crawler/internal/crawler"
+	"cmdb-crawler/internal/models"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	seedQuery      string
+	graphDepth     int
+	graphMaxNodes  int
+	graphDirection string
+)
+
+// crawlGraphCmd CI关系图爬取命令
+var crawlGraphCmd = &cobra.Command{
+	Use:   "crawl-graph",
+	Short: "从种子CI出发爬取完整的CI关系图（用于影响分析）",
+	Long: `以 --query 搜索到的CI为种子，沿CI关系逐层广度优先遍历，得到包含多对多关系的完整关系图
+
+服务树只沿视图Topo层级向下展开，同一CI的多个父节点、跨视图的关系不会出现在树中。
+该命令通过 /ci_relations/<id>/second_cis 查找子CI、/ci_relations/<id>/first_cis 查找父CI，
+每条边带有从CI类型关系推断的关系类型和约束（一对多、一对一、多对多）。
+遍历深度受 --depth 限制，节点总数受 --max-nodes 限制，超出时结果标记为 truncated。
+输出格式支持 json、yaml、dot、graphml、gexf。
+
+示例：
+  # 查看某个应用向上向下两跳内的全部关联CI
+  cmdb-crawler crawl-graph --query "_type:(3),name:order-service" --depth 2 --output ./data/impact.json
+
+  # 只向下查找，导出到Gephi
+  cmdb-crawler crawl-graph --query "_type:(1)" --direction down --format gexf --output ./data/graph.gexf
+
+  # 生成按类型着色的DOT图
+  cmdb-crawler crawl-graph --query "_id:(42)" --format dot --color-by-type --output ./data/graph.dot`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCrawlGraph(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(crawlGraphCmd)
+
+	crawlGraphCmd.Flags().StringVarP(&seedQuery, "query", "q", "", "种子CI的搜索条件（与 /ci/s 的 q 参数相同）")
+	crawlGraphCmd.Flags().IntVar(&graphDepth, "depth", 0, "从种子出发的最大跳数 (-1表示无限制)")
+	crawlGraphCmd.Flags().IntVar(&graphMaxNodes, "max-nodes", 0, "最大节点数 (0表示无限制)")
+	crawlGraphCmd.Flags().StringVar(&graphDirection, "direction", "", "遍历方向 (down, up, both)")
+	crawlGraphCmd.Flags().StringVarP(&outputPath, "output", "o", "", "输出文件路径")
+	crawlGraphCmd.Flags().StringVarP(&outputFormat, "format", "f", "", "输出格式 (json, yaml, dot, graphml, gexf)")
+	crawlGraphCmd.Flags().IntVar(&maxWorkers, "max-workers", 0, "同一层内同时展开的CI数")
+	crawlGraphCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "所有并发请求合计的每秒请求数 (0表示使用配置文件)")
+	crawlGraphCmd.Flags().IntVar(&burst, "burst", 0, "限流允许的突发请求数")
+	crawlGraphCmd.Flags().BoolVar(&prettyPrint, "pretty", false, "是否美化输出格式")
+	crawlGraphCmd.Flags().BoolVar(&colorByType, "color-by-type", false, "图形导出时按CI类型着色")
+	crawlGraphCmd.Flags().DurationVar(&crawlTimeout, "crawl-timeout", 0, "整次爬取的最长运行时间 (0表示无限制)")
+	crawlGraphCmd.MarkFlagRequired("query")
+}
+
+// runCrawlGraph 执行CI关系图爬取
+func runCrawlGraph(cmd *cobra.Command) error {
+	logger := GetLogger()
+	config, err := GetConfig()
+	if err != nil {
+		return fmt.Errorf("配置无效: %w", err)
+	}
+
+	mergeFlags(config, cmd)
+	if cmd.Flags().Changed("depth") {
+		config.Crawler.Graph.MaxDepth = graphDepth
+	}
+	if cmd.Flags().Changed("max-nodes") {
+		config.Crawler.Graph.MaxNodes = graphMaxNodes
+	}
+	if graphDirection != "" {
+		config.Crawler.Graph.Direction = graphDirection
+	}
+
+	if err := crawler.ValidateGraphDirection(config.Crawler.Graph.Direction); err != nil {
+		return fmt.Errorf("遍历方向无效: %w", err)
+	}
+
+	exporter, err := newExporter(config, logger)
+	if err != nil {
+		return fmt.Errorf("输出配置无效: %w", err)
+	}
+	if !exporter.SupportsCIGraph() {
+		return fmt.Errorf("关系图导出不支持 %s 格式，可选 json、yaml、dot、graphml、gexf", config.Output.Format)
+	}
+
+	outputFile := outputPath
+	if outputFile == "" {
+		outputFile = filepath.Join("./output", exporter.GenerateFileName("ci_graph", true))
+	}
+
+	logger.Info("开始爬取CI关系图",
+		zap.String("cmdb_url", config.CMDB.BaseURL),
+		zap.String("seed_query", seedQuery),
+		zap.Int("max_depth", config.Crawler.Graph.MaxDepth),
+		zap.Int("max_nodes", config.Crawler.Graph.MaxNodes),
+		zap.String("direction", config.Crawler.Graph.Direction),
+		zap.String("output_format", config.Output.Format),
+		zap.String("output_path", outputFile))
+
+	cmdbClient := newCMDBClient(config, logger)
+	graphCrawler := crawler.NewGraphCrawler(cmdbClient, logger).
+		SetPageSize(config.Crawler.ServiceTree.PageSize).
+		SetMaxWorkers(config.Crawler.Concurrency.MaxWorkers).
+		SetRetryPolicy(cmdbClient.RetryPolicy()). // 与客户端共用重试策略，客户端已重试的错误不再重试
+		SetRequestInterval(0).                    // 请求频率由客户端限流器控制，见 clientRateLimit
+		SetMaxDepth(config.Crawler.Graph.MaxDepth).
+		SetMaxNodes(config.Crawler.Graph.MaxNodes).
+		SetDirection(config.Crawler.Graph.Direction)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if crawlTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, crawlTimeout)
+		defer cancel()
+	}
+
+	graph, err := graphCrawler.CrawlGraph(ctx, seedQuery)
+	interrupted := err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+	if err != nil && !interrupted {
+		logger.Error("爬取失败", zap.Error(err))
+		return fmt.Errorf("爬取CI关系图失败: %w", err)
+	}
+
+	if interrupted {
+		logger.Warn("爬取被中断，导出已获取的部分数据",
+			zap.Int("nodes", len(graph.Nodes)),
+			zap.Error(err))
+		fmt.Printf("警告: 爬取被中断 (%v)，以下为部分结果\n", err)
+	}
+
+	if err := exporter.ExportCIGraph(graph, outputFile); err != nil {
+		logger.Error("导出结果失败", zap.Error(err))
+		return fmt.Errorf("导出结果失败: %w", err)
+	}
+	fmt.Printf("数据已导出到: %s\n", outputFile)
+
+	printGraphSummary(graph, logger)
+	logRateLimitStats(cmdbClient, logger)
+
+	if interrupted {
+		return fmt.Errorf("爬取被中断: %w", err)
+	}
+	return nil
+}
+
+// printGraphSummary 打印关系图统计摘要
+func printGraphSummary(graph *models.CIGraph, logger *zap.Logger) {
+	typeCounts := make(map[string]int)
+	for _, node := range graph.Nodes {
+		typeCounts[node.TypeName]++
+	}
+	relationCounts := make(map[string]int)
+	for _, edge := range graph.Edges {
+		key := edge.RelationType
+		if key == "" {
+			key = "(未知)"
+		}
+		if edge.Constraint != "" {
+			key += " / " + models.ConstraintName(edge.Constraint)
+		}
+		relationCounts[key]++
+	}
+
+	fmt.Println("\n=== CI关系图摘要 ===")
+	fmt.Printf("种子CI数: %d\n", len(graph.Seeds))
+	fmt.Printf("节点数: %d\n", len(graph.Nodes))
+	fmt.Printf("边数: %d\n", len(graph.Edges))
+	if graph.Truncated {
+		fmt.Println("已达到节点数上限，结果不完整")
+	}
+
+	fmt.Println("\n按CI类型:")
+	for _, name := range sortedKeys(typeCounts) {
+		fmt.Printf("  %s: %d\n", name, typeCounts[name])
+	}
+	fmt.Println("\n按关系类型:")
+	for _, name := range sortedKeys(relationCounts) {
+		fmt.Printf("  %s: %d\n", name, relationCounts[name])
+	}
+	fmt.Println("====================")
+
+	logger.Info("爬取完成",
+		zap.Int("seeds", len(graph.Seeds)),
+		zap.Int("nodes", len(graph.Nodes)),
+		zap.Int("edges", len(graph.Edges)),
+		zap.Bool("truncated", graph.Truncated))
+}
+
+// sortedKeys 返回按字典序排序的键
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"cmdb-crawler/internal/models"
+	"cmdb-crawler/internal/output"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	diffFormat     string
+	diffOutputPath string
+	diffExitCode   bool
+)
+
+// errDiffHasChanges 指定 --exit-code 且快照存在变更，Execute 以退出码1结束且不输出错误信息
+var errDiffHasChanges = errors.New("快照存在变更")
+
+// diffCmd 快照对比命令
+var diffCmd = &cobra.Command{
+	Use:   "diff <旧快照> <新快照>",
+	Short: "对比两次爬取的服务树快照",
+	Long: `对比两次crawl导出的JSON/YAML文件，输出服务树的变更报告
+
+按视图名称和节点ID匹配，报告以下变更：
+- 新增/删除的节点
+- 父节点发生变化（移动）的节点
+- 重命名的节点
+- 属性发生变化的节点
+
+示例：
+  # 文本格式输出到终端
+  cmdb-crawler diff ./output/old.json ./output/new.json
+
+  # 输出Markdown报告
+  cmdb-crawler diff old.json new.yaml --format markdown --output ./output/diff.md
+
+  # 有变更时以退出码1结束，便于在脚本中使用
+  cmdb-crawler diff old.json new.json --exit-code`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := runDiff(args[0], args[1])
+		if errors.Is(err, errDiffHasChanges) {
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "报告格式 (text, json, markdown)")
+	diffCmd.Flags().StringVarP(&diffOutputPath, "output", "o", "", "报告输出路径 (默认输出到终端)")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "存在变更时以退出码1结束")
+}
+
+// runDiff 执行快照对比
+func runDiff(oldPath, newPath string) error {
+	logger := GetLogger()
+
+	oldExport, err := output.LoadServiceTrees(oldPath)
+	if err != nil {
+		return fmt.Errorf("读取旧快照失败: %w", err)
+	}
+
+	newExport, err := output.LoadServiceTrees(newPath)
+	if err != nil {
+		return fmt.Errorf("读取新快照失败: %w", err)
+	}
+
+	diff := models.DiffServiceTrees(oldExport.ServiceTrees, newExport.ServiceTrees)
+
+	logger.Info("快照对比完成",
+		zap.String("old", oldPath),
+		zap.String("new", newPath),
+		zap.Int("added", diff.Summary.Added),
+		zap.Int("removed", diff.Summary.Removed),
+		zap.Int("moved", diff.Summary.Moved),
+		zap.Int("renamed", diff.Summary.Renamed),
+		zap.Int("attributes_changed", diff.Summary.AttributesChanged))
+
+	var w io.Writer = os.Stdout
+	if diffOutputPath != "" {
+		file, err := os.Create(diffOutputPath)
+		if err != nil {
+			return fmt.Errorf("创建报告文件失败: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := output.WriteDiff(w, diff, diffFormat, true); err != nil {
+		return fmt.Errorf("输出变更报告失败: %w", err)
+	}
+
+	if diffOutputPath != "" {
+		fmt.Printf("变更报告已导出到: %s\n", diffOutputPath)
+	}
+
+	if diffExitCode && diff.HasChanges() {
+		return errDiffHasChanges
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+	"cmdb-crawler/internal/output"
+
+	"go.uber.org/zap"
+)
+
+// writeSnapshot 导出只有一个根节点的服务树快照
+func writeSnapshot(t *testing.T, name, nodeName string) string {
+	tree := &models.ServiceTreeData{
+		ViewName:  "product",
+		RootNodes: []*models.ServiceTreeNode{{ID: 1, Type: 1, Name: nodeName}},
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := output.NewExporter("json", false, zap.NewNop()).ExportServiceTrees([]*models.ServiceTreeData{tree}, path); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+	return path
+}
+
+// TestRunDiffExitCode 测试 --exit-code 在有变更时返回 errDiffHasChanges，没有变更时正常返回
+func TestRunDiffExitCode(t *testing.T) {
+	logger = zap.NewNop()
+	diffFormat, diffOutputPath, diffExitCode = "text", filepath.Join(t.TempDir(), "diff.txt"), true
+	t.Cleanup(func() { diffFormat, diffOutputPath, diffExitCode = "text", "", false })
+
+	oldPath := writeSnapshot(t, "old.json", "line")
+	if err := runDiff(oldPath, writeSnapshot(t, "same.json", "line")); err != nil {
+		t.Errorf("Expected no error for identical snapshots, got %v", err)
+	}
+	if err := runDiff(oldPath, writeSnapshot(t, "new.json", "line-renamed")); !errors.Is(err, errDiffHasChanges) {
+		t.Errorf("Expected errDiffHasChanges, got %v", err)
+	}
+
+	diffExitCode = false
+	if err := runDiff(oldPath, writeSnapshot(t, "new2.json", "line-renamed")); err != nil {
+		t.Errorf("Expected no error without --exit-code, got %v", err)
+	}
+}
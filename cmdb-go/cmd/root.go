@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"cmdb-crawler/internal/secrets"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -13,10 +16,13 @@ import (
 )
 
 var (
-	cfgFile  string
-	verbose  bool
-	logLevel string
-	logger   *zap.Logger
+	cfgFile       string
+	verbose       bool
+	logLevel      string
+	strictSecrets bool
+	logger        *zap.Logger
+	// redactor 配置中解析出的敏感值，从所有日志中脱敏
+	redactor = secrets.NewRedactor()
 )
 
 // rootCmd 根命令
@@ -43,7 +49,9 @@ var rootCmd = &cobra.Command{
 // Execute 执行根命令
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		if !errors.Is(err, errDiffHasChanges) {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 }
@@ -55,9 +63,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件路径 (默认为 ./config/config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "详细输出")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "日志级别 (debug, info, warn, error)")
+	rootCmd.PersistentFlags().BoolVar(&strictSecrets, "strict-secrets", false, "严格模式：配置文件中明文填写的凭证视为错误，必须使用 env:、file: 或 exec: 引用")
 
 	// Viper绑定
 	viper.BindPFlag("logging.level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("cmdb.auth.strict_secrets", rootCmd.PersistentFlags().Lookup("strict-secrets"))
 }
 
 // initConfig 初始化配置
@@ -101,23 +111,45 @@ func setDefaults() {
 	// CMDB配置默认值
 	viper.SetDefault("cmdb.base_url", "http://localhost:8080")
 	viper.SetDefault("cmdb.api_version", "v0.1")
-	viper.SetDefault("cmdb.auth.username", "admin")
-	viper.SetDefault("cmdb.auth.password", "admin")
+	viper.SetDefault("cmdb.auth.type", "api_key")
+	viper.SetDefault("cmdb.auth.login_path", "/api/v1/acl/login")
+	viper.SetDefault("cmdb.auth.strict_secrets", false)
 	viper.SetDefault("cmdb.request.timeout", "30s")
 	viper.SetDefault("cmdb.request.retry_count", 3)
 	viper.SetDefault("cmdb.request.retry_wait_time", "1s")
+	viper.SetDefault("cmdb.request.retry_max_wait_time", "30s")
+	viper.SetDefault("cmdb.request.retry_jitter", 0.5)
+	viper.SetDefault("cmdb.request.retry_max_elapsed_time", "2m")
 
 	// 爬取配置默认值
 	viper.SetDefault("crawler.service_tree.max_depth", -1)
 	viper.SetDefault("crawler.service_tree.page_size", 1000)
 	viper.SetDefault("crawler.service_tree.include_statistics", true)
+	viper.SetDefault("crawler.service_tree.include_schema", true)
+	viper.SetDefault("crawler.service_tree.has_m2m", false)
+	viper.SetDefault("crawler.service_tree.batch_size", 100)
+	viper.SetDefault("crawler.graph.max_depth", 3)
+	viper.SetDefault("crawler.graph.max_nodes", 10000)
+	viper.SetDefault("crawler.graph.direction", "both")
 	viper.SetDefault("crawler.concurrency.max_workers", 10)
 	viper.SetDefault("crawler.concurrency.request_interval", "100ms")
+	viper.SetDefault("crawler.concurrency.rate_limit", 0)
+	viper.SetDefault("crawler.concurrency.burst", 0)
 
 	// 输出配置默认值
 	viper.SetDefault("output.format", "json")
 	viper.SetDefault("output.file_path", "./output/service_tree_data.json")
 	viper.SetDefault("output.pretty_print", true)
+	viper.SetDefault("output.color_by_type", false)
+	viper.SetDefault("output.append", false)
+	viper.SetDefault("output.csv.attributes", "none")
+	viper.SetDefault("output.csv.include", []string{})
+	viper.SetDefault("output.csv.exclude", []string{})
+	viper.SetDefault("output.csv.flatten", false)
+
+	// 服务模式配置默认值
+	viper.SetDefault("server.listen", ":8080")
+	viper.SetDefault("server.refresh_interval", "1h")
 
 	// 日志配置默认值
 	viper.SetDefault("logging.level", "info")
@@ -160,7 +192,7 @@ func initLogger() {
 	}
 
 	var err error
-	logger, err = config.Build()
+	logger, err = config.Build(zap.WrapCore(redactor.WrapCore))
 	if err != nil {
 		fmt.Printf("初始化日志失败: %v\n", err)
 		os.Exit(1)
@@ -177,8 +209,17 @@ func GetLogger() *zap.Logger {
 
 // AuthConfig 认证配置
 type AuthConfig struct {
+	// Type 认证方式: api_key, jwt, session
+	Type      string `mapstructure:"type"`
 	APIKey    string `mapstructure:"api_key"`
 	APISecret string `mapstructure:"api_secret"`
+	// jwt/session认证的登录凭证，LoginPath相对于base_url
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+	LoginPath string `mapstructure:"login_path"`
+	// Token 已有的JWT，SessionCookie 已有的会话Cookie，配置后不需要登录
+	Token         string `mapstructure:"token"`
+	SessionCookie string `mapstructure:"session_cookie"`
 }
 
 // CMDBConfig CMDB配置
@@ -189,20 +230,74 @@ type CMDBConfig struct {
 	Request    RequestConfig `mapstructure:"request"`
 }
 
-// GetConfig 获取配置
-func GetConfig() *Config {
+// secretKeys 各认证方式使用的凭证配置项，支持 env:、file:、exec: 引用并需要从日志中脱敏
+var secretKeys = map[string][]string{
+	"api_key": {"cmdb.auth.api_key", "cmdb.auth.api_secret"},
+	"jwt":     {"cmdb.auth.password", "cmdb.auth.token"},
+	"session": {"cmdb.auth.password", "cmdb.auth.session_cookie"},
+}
+
+// resolveSecrets 解析 cmdb.auth.type 所选认证方式的凭证引用并登记到日志脱敏，其他认证方式的凭证不解析
+// 严格模式下配置文件中明文填写的凭证视为错误，通过 CMDB_CRAWLER_ 环境变量直接传入的值不受限制
+func resolveSecrets() (map[string]string, error) {
+	authType := viper.GetString("cmdb.auth.type")
+	if authType == "" {
+		authType = "api_key"
+	}
+
+	strict := viper.GetBool("cmdb.auth.strict_secrets")
+	resolved := make(map[string]string, len(secretKeys[authType]))
+	for _, key := range secretKeys[authType] {
+		value := viper.GetString(key)
+		if value == "" {
+			continue
+		}
+
+		if !secrets.IsReference(value) {
+			envName := "CMDB_CRAWLER_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+			if _, fromEnv := os.LookupEnv(envName); strict && !fromEnv {
+				return nil, fmt.Errorf("%s 为明文配置，严格模式下请使用 env:、file: 或 exec: 引用", key)
+			}
+		}
+
+		secret, err := secrets.Resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", key, err)
+		}
+		redactor.Add(secret)
+		resolved[key] = secret
+	}
+	return resolved, nil
+}
+
+// GetConfig 获取配置，凭证中的 env:、file:、exec: 引用在此解析
+func GetConfig() (*Config, error) {
+	credentials, err := resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		CMDB: CMDBConfig{
 			BaseURL:    viper.GetString("cmdb.base_url"),
 			APIVersion: viper.GetString("cmdb.api_version"),
 			Auth: AuthConfig{
-				APIKey:    viper.GetString("cmdb.auth.api_key"),
-				APISecret: viper.GetString("cmdb.auth.api_secret"),
+				Type:          viper.GetString("cmdb.auth.type"),
+				APIKey:        credentials["cmdb.auth.api_key"],
+				APISecret:     credentials["cmdb.auth.api_secret"],
+				Username:      viper.GetString("cmdb.auth.username"),
+				Password:      credentials["cmdb.auth.password"],
+				LoginPath:     viper.GetString("cmdb.auth.login_path"),
+				Token:         credentials["cmdb.auth.token"],
+				SessionCookie: credentials["cmdb.auth.session_cookie"],
 			},
 			Request: RequestConfig{
-				Timeout:       viper.GetDuration("cmdb.request.timeout"),
-				RetryCount:    viper.GetInt("cmdb.request.retry_count"),
-				RetryWaitTime: viper.GetDuration("cmdb.request.retry_wait_time"),
+				Timeout:             viper.GetDuration("cmdb.request.timeout"),
+				RetryCount:          viper.GetInt("cmdb.request.retry_count"),
+				RetryWaitTime:       viper.GetDuration("cmdb.request.retry_wait_time"),
+				RetryMaxWaitTime:    viper.GetDuration("cmdb.request.retry_max_wait_time"),
+				RetryJitter:         viper.GetFloat64("cmdb.request.retry_jitter"),
+				RetryMaxElapsedTime: viper.GetDuration("cmdb.request.retry_max_elapsed_time"),
 			},
 		},
 		Crawler: CrawlerConfig{
@@ -211,23 +306,45 @@ func GetConfig() *Config {
 				MaxDepth:          viper.GetInt("crawler.service_tree.max_depth"),
 				PageSize:          viper.GetInt("crawler.service_tree.page_size"),
 				IncludeStatistics: viper.GetBool("crawler.service_tree.include_statistics"),
+				IncludeSchema:     viper.GetBool("crawler.service_tree.include_schema"),
+				HasM2M:            viper.GetBool("crawler.service_tree.has_m2m"),
+				BatchSize:         viper.GetInt("crawler.service_tree.batch_size"),
+			},
+			Graph: GraphConfig{
+				MaxDepth:  viper.GetInt("crawler.graph.max_depth"),
+				MaxNodes:  viper.GetInt("crawler.graph.max_nodes"),
+				Direction: viper.GetString("crawler.graph.direction"),
 			},
 			Concurrency: ConcurrencyConfig{
 				MaxWorkers:      viper.GetInt("crawler.concurrency.max_workers"),
 				RequestInterval: viper.GetDuration("crawler.concurrency.request_interval"),
+				RateLimit:       viper.GetFloat64("crawler.concurrency.rate_limit"),
+				Burst:           viper.GetInt("crawler.concurrency.burst"),
 			},
 		},
 		Output: OutputConfig{
 			Format:      viper.GetString("output.format"),
 			FilePath:    viper.GetString("output.file_path"),
 			PrettyPrint: viper.GetBool("output.pretty_print"),
+			ColorByType: viper.GetBool("output.color_by_type"),
+			Append:      viper.GetBool("output.append"),
+			CSV: CSVConfig{
+				Attributes: viper.GetString("output.csv.attributes"),
+				Include:    viper.GetStringSlice("output.csv.include"),
+				Exclude:    viper.GetStringSlice("output.csv.exclude"),
+				Flatten:    viper.GetBool("output.csv.flatten"),
+			},
+		},
+		Server: ServerConfig{
+			Listen:          viper.GetString("server.listen"),
+			RefreshInterval: viper.GetDuration("server.refresh_interval"),
 		},
 		Logging: LoggingConfig{
 			Level:    viper.GetString("logging.level"),
 			Output:   viper.GetString("logging.output"),
 			FilePath: viper.GetString("logging.file_path"),
 		},
-	}
+	}, nil
 }
 
 // Config 配置结构
@@ -235,17 +352,22 @@ type Config struct {
 	CMDB    CMDBConfig    `mapstructure:"cmdb"`
 	Crawler CrawlerConfig `mapstructure:"crawler"`
 	Output  OutputConfig  `mapstructure:"output"`
+	Server  ServerConfig  `mapstructure:"server"`
 	Logging LoggingConfig `mapstructure:"logging"`
 }
 
 type RequestConfig struct {
-	Timeout       time.Duration `mapstructure:"timeout"`
-	RetryCount    int           `mapstructure:"retry_count"`
-	RetryWaitTime time.Duration `mapstructure:"retry_wait_time"`
+	Timeout             time.Duration `mapstructure:"timeout"`
+	RetryCount          int           `mapstructure:"retry_count"`
+	RetryWaitTime       time.Duration `mapstructure:"retry_wait_time"`
+	RetryMaxWaitTime    time.Duration `mapstructure:"retry_max_wait_time"`
+	RetryJitter         float64       `mapstructure:"retry_jitter"`
+	RetryMaxElapsedTime time.Duration `mapstructure:"retry_max_elapsed_time"`
 }
 
 type CrawlerConfig struct {
 	ServiceTree ServiceTreeConfig `mapstructure:"service_tree"`
+	Graph       GraphConfig       `mapstructure:"graph"`
 	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
 }
 
@@ -254,17 +376,45 @@ type ServiceTreeConfig struct {
 	MaxDepth          int      `mapstructure:"max_depth"`
 	PageSize          int      `mapstructure:"page_size"`
 	IncludeStatistics bool     `mapstructure:"include_statistics"`
+	IncludeSchema     bool     `mapstructure:"include_schema"`
+	HasM2M            bool     `mapstructure:"has_m2m"`
+	BatchSize         int      `mapstructure:"batch_size"`
+}
+
+type GraphConfig struct {
+	MaxDepth  int    `mapstructure:"max_depth"`
+	MaxNodes  int    `mapstructure:"max_nodes"`
+	Direction string `mapstructure:"direction"`
 }
 
 type ConcurrencyConfig struct {
-	MaxWorkers      int           `mapstructure:"max_workers"`
+	MaxWorkers int `mapstructure:"max_workers"`
+	// RequestInterval 每个协程的请求间隔，没有配置RateLimit时换算为合计速率 MaxWorkers/RequestInterval
 	RequestInterval time.Duration `mapstructure:"request_interval"`
+	// RateLimit 所有并发请求合计的每秒请求数，0表示不限速；Burst 允许的突发请求数，0表示取RateLimit
+	RateLimit float64 `mapstructure:"rate_limit"`
+	Burst     int     `mapstructure:"burst"`
 }
 
 type OutputConfig struct {
-	Format      string `mapstructure:"format"`
-	FilePath    string `mapstructure:"file_path"`
-	PrettyPrint bool   `mapstructure:"pretty_print"`
+	Format      string    `mapstructure:"format"`
+	FilePath    string    `mapstructure:"file_path"`
+	PrettyPrint bool      `mapstructure:"pretty_print"`
+	ColorByType bool      `mapstructure:"color_by_type"`
+	Append      bool      `mapstructure:"append"`
+	CSV         CSVConfig `mapstructure:"csv"`
+}
+
+type CSVConfig struct {
+	Attributes string   `mapstructure:"attributes"`
+	Include    []string `mapstructure:"include"`
+	Exclude    []string `mapstructure:"exclude"`
+	Flatten    bool     `mapstructure:"flatten"`
+}
+
+type ServerConfig struct {
+	Listen          string        `mapstructure:"listen"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
 }
 
 type LoggingConfig struct {
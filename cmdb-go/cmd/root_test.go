@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"cmdb-crawler/internal/secrets"
+
+	"github.com/spf13/viper"
+)
+
+// TestResolveSecretsJWT 测试jwt认证只解析token和密码，未使用的api_key引用即使无法解析也不报错
+func TestResolveSecretsJWT(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	t.Setenv("CMDB_TEST_JWT_TOKEN", "jwt-token-value")
+	viper.Set("cmdb.auth.type", "jwt")
+	viper.Set("cmdb.auth.strict_secrets", true)
+	viper.Set("cmdb.auth.token", "env:CMDB_TEST_JWT_TOKEN")
+	viper.Set("cmdb.auth.api_key", "env:CMDB_TEST_API_KEY_UNSET")
+	viper.Set("cmdb.auth.api_secret", "plain-api-secret")
+
+	resolved, err := resolveSecrets()
+	if err != nil {
+		t.Fatalf("resolveSecrets failed: %v", err)
+	}
+	if resolved["cmdb.auth.token"] != "jwt-token-value" {
+		t.Errorf("Expected token to be resolved, got %q", resolved["cmdb.auth.token"])
+	}
+	if _, ok := resolved["cmdb.auth.api_key"]; ok {
+		t.Error("Expected api_key to be ignored for jwt authentication")
+	}
+	if got := redactor.Redact("jwt-token-value"); got != secrets.Redacted {
+		t.Errorf("Expected resolved token to be redacted, got %q", got)
+	}
+
+	viper.Set("cmdb.auth.type", "api_key")
+	if _, err := resolveSecrets(); err == nil {
+		t.Error("Expected unset api_key reference to fail for api_key authentication")
+	}
+}
+
+// TestRateLimitFromRequestInterval 测试没有配置rate_limit时request_interval换算为合计速率
+func TestRateLimitFromRequestInterval(t *testing.T) {
+	tests := []struct {
+		concurrency ConcurrencyConfig
+		rps         float64
+		burst       int
+	}{
+		{ConcurrencyConfig{MaxWorkers: 10, RequestInterval: 100 * time.Millisecond}, 100, 10},
+		{ConcurrencyConfig{MaxWorkers: 10, RequestInterval: 100 * time.Millisecond, Burst: 3}, 100, 3},
+		{ConcurrencyConfig{MaxWorkers: 10, RequestInterval: 100 * time.Millisecond, RateLimit: 20, Burst: 5}, 20, 5},
+		{ConcurrencyConfig{MaxWorkers: 10}, 0, 0},
+	}
+	for _, tt := range tests {
+		if rps, burst := clientRateLimit(tt.concurrency); rps != tt.rps || burst != tt.burst {
+			t.Errorf("clientRateLimit(%+v) = %v, %d, want %v, %d", tt.concurrency, rps, burst, tt.rps, tt.burst)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cmdb-crawler/internal/client"
+	"cmdb-crawler/internal/crawler"
+	"cmdb-crawler/internal/server"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	serveListen   string
+	serveInterval time.Duration
+)
+
+// serveCmd HTTP服务命令
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "定时爬取并通过REST API提供服务树查询",
+	Long: `以HTTP服务方式运行，按固定间隔爬取服务树并在内存中保存最新结果
+
+提供以下只读接口（响应结构与JSON导出一致）：
+  GET /healthz                 服务状态和最近一次更新时间
+  GET /views                   所有服务树的摘要
+  GET /views/{name}/tree       指定服务树的完整数据
+  GET /nodes/{id}              节点在各服务树中的位置及其子树
+  GET /nodes/{id}/ancestors    节点的祖先链
+  GET /search?q=&view=&limit=  按节点名称或类型名称搜索
+
+示例：
+  # 监听8080端口，每30分钟刷新一次
+  cmdb-crawler serve --listen :8080 --interval 30m
+
+  # 只提供指定的服务树
+  cmdb-crawler serve --views "产品服务树"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "HTTP监听地址 (默认 :8080)")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 0, "爬取刷新间隔 (默认 1h)")
+	serveCmd.Flags().StringSliceVar(&targetViews, "views", []string{}, "指定要爬取的服务树视图名称（逗号分隔）")
+	serveCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "最大爬取深度 (-1表示无限制)")
+	serveCmd.Flags().IntVar(&maxWorkers, "max-workers", 0, "最大并发数")
+	serveCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "所有并发请求合计的每秒请求数 (0表示使用配置文件)")
+	serveCmd.Flags().IntVar(&burst, "burst", 0, "限流允许的突发请求数")
+	serveCmd.Flags().BoolVar(&includeStats, "include-stats", true, "是否包含统计信息")
+}
+
+// runServe 启动HTTP服务并定时爬取
+func runServe(cmd *cobra.Command) error {
+	logger := GetLogger()
+	config, err := GetConfig()
+	if err != nil {
+		return fmt.Errorf("配置无效: %w", err)
+	}
+
+	mergeFlags(config, cmd)
+	if serveListen != "" {
+		config.Server.Listen = serveListen
+	}
+	if serveInterval > 0 {
+		config.Server.RefreshInterval = serveInterval
+	}
+	if config.Server.RefreshInterval <= 0 {
+		return fmt.Errorf("刷新间隔必须大于0: %s", config.Server.RefreshInterval)
+	}
+
+	cmdbClient := newCMDBClient(config, logger)
+	serviceCrawler := newServiceTreeCrawler(cmdbClient, config, logger)
+	srv := server.NewServer(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:              config.Server.Listen,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("启动HTTP服务", zap.String("listen", config.Server.Listen))
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	go refreshLoop(ctx, cmdbClient, serviceCrawler, srv, config, logger)
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			return fmt.Errorf("HTTP服务启动失败: %w", err)
+		}
+	case <-ctx.Done():
+	}
+
+	logger.Info("正在关闭HTTP服务")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("关闭HTTP服务失败: %w", err)
+	}
+
+	return nil
+}
+
+// refreshLoop 立即爬取一次，之后按间隔定时刷新，直到上下文取消
+func refreshLoop(ctx context.Context, cmdbClient *client.CMDBClient, serviceCrawler *crawler.ServiceTreeCrawler,
+	srv *server.Server, config *Config, logger *zap.Logger) {
+
+	ticker := time.NewTicker(config.Server.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		logger.Info("开始刷新服务树数据", zap.Strings("target_views", config.Crawler.ServiceTree.TargetViews))
+
+		trees, err := serviceCrawler.CrawlSpecificViews(ctx, config.Crawler.ServiceTree.TargetViews)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			logger.Error("刷新服务树数据失败，继续使用上次结果", zap.Error(err))
+			srv.SetError(err)
+		default:
+			srv.Update(trees)
+			logger.Info("刷新服务树数据完成",
+				zap.Int("tree_count", len(trees)),
+				zap.Duration("duration", time.Since(start)))
+		}
+		logRateLimitStats(cmdbClient, logger)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package client
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/secrets"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// loginTimeout 登录请求超时
+	loginTimeout = 30 * time.Second
+	// tokenRefreshMargin JWT在过期前多久重新登录
+	tokenRefreshMargin = time.Minute
+)
+
+// Authenticator 请求认证方式
+type Authenticator interface {
+	// Name 认证方式名称
+	Name() string
+	// Authenticate 为请求添加认证信息，签名类认证将签名写入查询参数params
+	Authenticate(ctx context.Context, req *resty.Request, urlPath string, params map[string]string) error
+	// Refresh 请求返回401后刷新凭证，failed为返回401的请求，返回false表示凭证无法刷新
+	// 并发请求同时返回401时，failed携带的凭证已被其他请求刷新则不需要重复刷新
+	Refresh(ctx context.Context, failed *resty.Request) (bool, error)
+}
+
+// APIKeyAuth API Key签名认证，请求带上 _key 和对路径、参数签名得到的 _secret
+type APIKeyAuth struct {
+	apiKey    string
+	apiSecret string
+	logger    *zap.Logger
+}
+
+// NewAPIKeyAuth 创建API Key签名认证
+func NewAPIKeyAuth(apiKey, apiSecret string, logger *zap.Logger) *APIKeyAuth {
+	return &APIKeyAuth{
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		logger:    logger,
+	}
+}
+
+// Name 认证方式名称
+func (a *APIKeyAuth) Name() string {
+	return "api_key"
+}
+
+// Authenticate 添加 _key 和签名参数
+func (a *APIKeyAuth) Authenticate(ctx context.Context, req *resty.Request, urlPath string, params map[string]string) error {
+	if a.apiKey == "" || a.apiSecret == "" {
+		return fmt.Errorf("API key and secret are required")
+	}
+
+	params["_key"] = a.apiKey
+	params["_secret"] = a.buildSignature(urlPath, params)
+	return nil
+}
+
+// Refresh API Key没有可刷新的凭证
+func (a *APIKeyAuth) Refresh(ctx context.Context, failed *resty.Request) (bool, error) {
+	return false, nil
+}
+
+// buildSignature 构建API签名
+func (a *APIKeyAuth) buildSignature(urlPath string, params map[string]string) string {
+	// 1. 收集除_key和_secret外的所有参数
+	var keys []string
+	for k := range params {
+		if k != "_key" && k != "_secret" {
+			keys = append(keys, k)
+		}
+	}
+
+	// 2. 参数名排序
+	sort.Strings(keys)
+
+	// 3. 拼接参数值
+	var values []string
+	for _, k := range keys {
+		values = append(values, params[k])
+	}
+	paramValues := strings.Join(values, "")
+
+	// 4. 构建签名字符串：url_path + secret + 参数值
+	signStr := urlPath + a.apiSecret + paramValues
+
+	// 5. 计算SHA1
+	h := sha1.New()
+	h.Write([]byte(signStr))
+	signature := fmt.Sprintf("%x", h.Sum(nil))
+
+	// 签名字符串中的secret不写入日志
+	a.logger.Debug("API signature",
+		zap.String("url_path", urlPath),
+		zap.String("sign_string", urlPath+secrets.Redacted+paramValues),
+		zap.String("signature", signature))
+
+	return signature
+}
+
+// JWTAuth JWT认证，用户名密码登录后在 Access-Token 请求头中携带token
+// token过期前或请求返回401时重新登录
+type JWTAuth struct {
+	httpClient *resty.Client
+	loginURL   string
+	username   string
+	password   string
+	logger     *zap.Logger
+	redactor   *secrets.Redactor
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewJWTAuth 创建JWT认证，loginURL为登录接口的完整地址
+func NewJWTAuth(loginURL, username, password string, logger *zap.Logger) *JWTAuth {
+	return &JWTAuth{
+		httpClient: resty.New().SetTimeout(loginTimeout),
+		loginURL:   loginURL,
+		username:   username,
+		password:   password,
+		logger:     logger,
+	}
+}
+
+// SetToken 使用已有的token，没有配置用户名密码时token过期后无法刷新
+func (a *JWTAuth) SetToken(token string) *JWTAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = token
+	a.expiresAt = jwtExpiry(token)
+	return a
+}
+
+// SetRedactor 设置日志脱敏器，登录获取的token登记到其中
+func (a *JWTAuth) SetRedactor(redactor *secrets.Redactor) *JWTAuth {
+	a.redactor = redactor
+	return a
+}
+
+// Name 认证方式名称
+func (a *JWTAuth) Name() string {
+	return "jwt"
+}
+
+// Authenticate 添加 Access-Token 请求头，没有token或即将过期时先登录
+func (a *JWTAuth) Authenticate(ctx context.Context, req *resty.Request, urlPath string, params map[string]string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	expiring := !a.expiresAt.IsZero() && time.Until(a.expiresAt) < tokenRefreshMargin
+	if (a.token == "" || expiring) && a.canLogin() {
+		if err := a.login(ctx); err != nil {
+			return err
+		}
+	}
+	if a.token == "" {
+		return fmt.Errorf("JWT token is not set and no login credentials are configured")
+	}
+
+	req.SetHeader("Access-Token", a.token)
+	return nil
+}
+
+// Refresh 重新登录获取token
+func (a *JWTAuth) Refresh(ctx context.Context, failed *resty.Request) (bool, error) {
+	if !a.canLogin() {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if failed != nil && failed.Header.Get("Access-Token") != a.token {
+		return true, nil
+	}
+	if err := a.login(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// canLogin 是否配置了登录凭证
+func (a *JWTAuth) canLogin() bool {
+	return a.username != "" && a.password != ""
+}
+
+// login 登录并保存token，调用方需持有锁
+func (a *JWTAuth) login(ctx context.Context) error {
+	_, result, err := login(ctx, a.httpClient, a.loginURL, a.username, a.password)
+	if err != nil {
+		return err
+	}
+	if result.Token == "" {
+		return fmt.Errorf("login response contains no token")
+	}
+
+	a.token = result.Token
+	a.expiresAt = jwtExpiry(result.Token)
+	if a.redactor != nil {
+		a.redactor.Add(result.Token)
+	}
+	a.logger.Info("Obtained JWT token",
+		zap.String("username", a.username),
+		zap.Time("expires_at", a.expiresAt))
+	return nil
+}
+
+// SessionAuth 会话Cookie认证，用户名密码登录后携带服务端返回的会话Cookie
+type SessionAuth struct {
+	httpClient *resty.Client
+	loginURL   string
+	username   string
+	password   string
+	logger     *zap.Logger
+	redactor   *secrets.Redactor
+
+	mu      sync.Mutex
+	cookies []*http.Cookie
+}
+
+// NewSessionAuth 创建会话Cookie认证，loginURL为登录接口的完整地址
+func NewSessionAuth(loginURL, username, password string, logger *zap.Logger) *SessionAuth {
+	return &SessionAuth{
+		httpClient: resty.New().SetTimeout(loginTimeout),
+		loginURL:   loginURL,
+		username:   username,
+		password:   password,
+		logger:     logger,
+	}
+}
+
+// SetCookie 使用已有的会话Cookie，格式同Cookie请求头，如 "session=xxx"
+func (a *SessionAuth) SetCookie(cookie string) *SessionAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cookies = (&http.Request{Header: http.Header{"Cookie": {cookie}}}).Cookies()
+	return a
+}
+
+// SetRedactor 设置日志脱敏器，登录获取的会话Cookie登记到其中
+func (a *SessionAuth) SetRedactor(redactor *secrets.Redactor) *SessionAuth {
+	a.redactor = redactor
+	return a
+}
+
+// Name 认证方式名称
+func (a *SessionAuth) Name() string {
+	return "session"
+}
+
+// Authenticate 添加会话Cookie，没有Cookie时先登录
+func (a *SessionAuth) Authenticate(ctx context.Context, req *resty.Request, urlPath string, params map[string]string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cookies) == 0 && a.canLogin() {
+		if err := a.login(ctx); err != nil {
+			return err
+		}
+	}
+	if len(a.cookies) == 0 {
+		return fmt.Errorf("session cookie is not set and no login credentials are configured")
+	}
+
+	req.SetCookies(a.cookies)
+	return nil
+}
+
+// Refresh 重新登录获取会话Cookie
+func (a *SessionAuth) Refresh(ctx context.Context, failed *resty.Request) (bool, error) {
+	if !a.canLogin() {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if failed != nil && cookieHeader(failed.Cookies) != cookieHeader(a.cookies) {
+		return true, nil
+	}
+	if err := a.login(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// canLogin 是否配置了登录凭证
+func (a *SessionAuth) canLogin() bool {
+	return a.username != "" && a.password != ""
+}
+
+// login 登录并保存会话Cookie，调用方需持有锁
+func (a *SessionAuth) login(ctx context.Context) error {
+	resp, _, err := login(ctx, a.httpClient, a.loginURL, a.username, a.password)
+	if err != nil {
+		return err
+	}
+	if len(resp.Cookies()) == 0 {
+		return fmt.Errorf("login response contains no session cookie")
+	}
+
+	a.cookies = resp.Cookies()
+	if a.redactor != nil {
+		for _, cookie := range a.cookies {
+			a.redactor.Add(cookie.Value)
+		}
+	}
+	a.logger.Info("Obtained session cookie", zap.String("username", a.username))
+	return nil
+}
+
+// cookieHeader 将Cookie列表格式化为Cookie请求头的值
+func cookieHeader(cookies []*http.Cookie) string {
+	parts := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		parts[i] = cookie.Name + "=" + cookie.Value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// loginResponse /login API响应
+type loginResponse struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+}
+
+// login 使用用户名密码登录
+func login(ctx context.Context, httpClient *resty.Client, loginURL, username, password string) (*resty.Response, *loginResponse, error) {
+	var result loginResponse
+	resp, err := httpClient.R().
+		SetContext(ctx).
+		SetBody(map[string]string{
+			"username": username,
+			"password": password,
+		}).
+		SetResult(&result).
+		Post(loginURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("login request failed: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, nil, fmt.Errorf("login failed: %w", newAPIError(resp))
+	}
+	return resp, &result, nil
+}
+
+// jwtExpiry 读取JWT中的exp声明（不校验签名），无法解析时返回零值
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(claims.Exp), 0)
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cmdb-crawler/internal/secrets"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+// authServer 测试用CMDB服务器，/api/v1/acl/login 登录，/api/v0.1/ci_types 校验凭证
+type authServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	logins int
+	// valid 当前有效的token或会话Cookie值，登录时轮换
+	valid string
+}
+
+// newAuthServer 创建测试服务器
+func newAuthServer(t *testing.T) *authServer {
+	s := &authServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/acl/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["password"] != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			s.logins++
+			s.valid = fmt.Sprintf("credential-%d", s.logins)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: s.valid})
+			json.NewEncoder(w).Encode(map[string]string{"token": s.valid, "username": body["username"]})
+		case "/api/v0.1/ci_types":
+			got := r.Header.Get("Access-Token")
+			if cookie, err := r.Cookie("session"); err == nil {
+				got = cookie.Value
+			}
+			if got == "" || got != s.valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"numfound": 0, "ci_types": []interface{}{}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// expire 使当前凭证失效
+func (s *authServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = "expired"
+}
+
+// loginCount 返回登录次数
+func (s *authServer) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// TestAPIKeyAuthSignature 测试API Key签名
+func TestAPIKeyAuthSignature(t *testing.T) {
+	auth := NewAPIKeyAuth("key", "secret", zap.NewNop())
+	params := map[string]string{"q": "_type:(1)", "page": "1", "count": "10"}
+
+	if err := auth.Authenticate(context.Background(), resty.New().R(), "/api/v0.1/ci/s", params); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	// 参数值按参数名排序拼接: count, page, q
+	want := fmt.Sprintf("%x", sha1.Sum([]byte("/api/v0.1/ci/s"+"secret"+"10"+"1"+"_type:(1)")))
+	if params["_key"] != "key" || params["_secret"] != want {
+		t.Errorf("Expected _key=key and _secret=%s, got %v", want, params)
+	}
+
+	if err := NewAPIKeyAuth("", "", zap.NewNop()).Authenticate(context.Background(), resty.New().R(), "/", map[string]string{}); err == nil {
+		t.Error("Expected error without API credentials")
+	}
+}
+
+// TestJWTAuth 测试JWT认证按需登录，token失效后重新登录并重试请求
+func TestJWTAuth(t *testing.T) {
+	server := newAuthServer(t)
+	redactor := secrets.NewRedactor()
+	cmdbClient := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewJWTAuth(server.URL+"/api/v1/acl/login", "crawler", "pass", zap.NewNop()).SetRedactor(redactor))
+
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("GetCITypes failed: %v", err)
+	}
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("GetCITypes failed: %v", err)
+	}
+	if got := server.loginCount(); got != 1 {
+		t.Errorf("Expected token to be reused after 1 login, got %d logins", got)
+	}
+	if got := redactor.Redact("token credential-1"); got != "token "+secrets.Redacted {
+		t.Errorf("Expected obtained token to be redacted, got %q", got)
+	}
+
+	server.expire()
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed after re-login, got %v", err)
+	}
+	if got := server.loginCount(); got != 2 {
+		t.Errorf("Expected 2 logins after token expired, got %d", got)
+	}
+
+	// 并发请求同时收到401时只重新登录一次
+	server.expire()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+				t.Errorf("Concurrent request failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := server.loginCount(); got != 3 {
+		t.Errorf("Expected a single re-login for concurrent requests, got %d logins", got)
+	}
+}
+
+// TestJWTAuthStaticToken 测试直接指定token，即将过期的token在有登录凭证时提前刷新
+func TestJWTAuthStaticToken(t *testing.T) {
+	server := newAuthServer(t)
+	loginURL := server.URL + "/api/v1/acl/login"
+
+	// 没有登录凭证时token失效直接返回401
+	server.expire()
+	cmdbClient := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewJWTAuth(loginURL, "", "", zap.NewNop()).SetToken("stale"))
+	if _, err := cmdbClient.GetCITypes(context.Background()); err == nil {
+		t.Error("Expected 401 error with a stale token and no login credentials")
+	}
+	if server.loginCount() != 0 {
+		t.Error("Expected no login without credentials")
+	}
+
+	expiring := testJWT(time.Now().Add(10 * time.Second))
+	auth := NewJWTAuth(loginURL, "crawler", "pass", zap.NewNop()).SetToken(expiring)
+	req := resty.New().R()
+	if err := auth.Authenticate(context.Background(), req, "/", map[string]string{}); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got := req.Header.Get("Access-Token"); got != "credential-1" {
+		t.Errorf("Expected expiring token to be replaced by a new login, got %q", got)
+	}
+}
+
+// TestSessionAuth 测试会话Cookie认证
+func TestSessionAuth(t *testing.T) {
+	server := newAuthServer(t)
+	redactor := secrets.NewRedactor()
+	cmdbClient := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewSessionAuth(server.URL+"/api/v1/acl/login", "crawler", "pass", zap.NewNop()).SetRedactor(redactor))
+
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("GetCITypes failed: %v", err)
+	}
+	server.expire()
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed after session re-login, got %v", err)
+	}
+	if got := server.loginCount(); got != 2 {
+		t.Errorf("Expected 2 logins, got %d", got)
+	}
+	if got := redactor.Redact("session=credential-1; session=credential-2"); got != "session="+secrets.Redacted+"; session="+secrets.Redacted {
+		t.Errorf("Expected obtained session cookies to be redacted, got %q", got)
+	}
+
+	// 直接指定的会话Cookie
+	cmdbClient = NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewSessionAuth("", "", "", zap.NewNop()).SetCookie("session=credential-2"))
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Errorf("Expected configured session cookie to be accepted, got %v", err)
+	}
+}
+
+// testJWT 构建只含exp声明的JWT，签名部分不做校验
+func testJWT(exp time.Time) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return encode(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." +
+		encode(map[string]int64{"exp": exp.Unix()}) + ".signature"
+}
@@ -1,15 +1,16 @@
 package client
 
 import (
-	"crypto/sha1"
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"cmdb-crawler/internal/models"
+	"cmdb-crawler/internal/secrets"
 
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
@@ -21,9 +22,32 @@ type CMDBClient struct {
 	baseURL    string
 	apiVersion string
 	logger     *zap.Logger
-	// API Key认证
-	apiKey    string
-	apiSecret string
+	// auth 请求认证方式
+	auth Authenticator
+	// limiter 所有请求共享的限流器
+	limiter *RateLimiter
+	// retry 请求失败后的重试策略
+	retry RetryPolicy
+}
+
+// restyLogger 将resty的日志转发到zap
+type restyLogger struct {
+	logger *zap.SugaredLogger
+}
+
+// Errorf 输出错误日志
+func (l *restyLogger) Errorf(format string, v ...interface{}) {
+	l.logger.Errorf(format, v...)
+}
+
+// Warnf 输出警告日志
+func (l *restyLogger) Warnf(format string, v ...interface{}) {
+	l.logger.Warnf(format, v...)
+}
+
+// Debugf 输出调试日志
+func (l *restyLogger) Debugf(format string, v ...interface{}) {
+	l.logger.Debugf(format, v...)
 }
 
 // NewCMDBClient 创建CMDB客户端
@@ -39,91 +63,74 @@ func NewCMDBClient(baseURL, apiVersion string, logger *zap.Logger) *CMDBClient {
 
 	// 启用Cookie支持
 	client.SetCookieJar(nil)
+	// resty自身的日志（如重试警告中的请求URL）同样经过zap输出和脱敏
+	client.SetLogger(&restyLogger{logger: logger.WithOptions(zap.AddCallerSkip(1)).Sugar()})
 
 	return &CMDBClient{
 		client:     client,
 		baseURL:    strings.TrimRight(baseURL, "/"),
 		apiVersion: apiVersion,
 		logger:     logger,
+		limiter:    NewRateLimiter(0, 0),
+		retry:      DefaultRetryPolicy(),
 	}
 }
 
 // SetAPICredentials 设置API Key认证
 func (c *CMDBClient) SetAPICredentials(apiKey, apiSecret string) *CMDBClient {
-	c.apiKey = apiKey
-	c.apiSecret = apiSecret
-	c.logger.Info("Set API key authentication", zap.String("api_key", apiKey))
+	c.auth = NewAPIKeyAuth(apiKey, apiSecret, c.logger)
+	c.logger.Info("Set API key authentication", zap.String("api_key", secrets.Mask(apiKey)))
 	return c
 }
 
-// buildSignature 构建API签名
-func (c *CMDBClient) buildSignature(urlPath string, params map[string]string) string {
-	// 1. 收集除_key和_secret外的所有参数
-	var keys []string
-	for k := range params {
-		if k != "_key" && k != "_secret" {
-			keys = append(keys, k)
-		}
-	}
-
-	// 2. 参数名排序
-	sort.Strings(keys)
-
-	// 3. 拼接参数值
-	var values []string
-	for _, k := range keys {
-		values = append(values, params[k])
-	}
-	paramValues := strings.Join(values, "")
-
-	// 4. 构建签名字符串：url_path + secret + 参数值
-	signStr := urlPath + c.apiSecret + paramValues
-
-	// 5. 计算SHA1
-	h := sha1.New()
-	h.Write([]byte(signStr))
-	signature := fmt.Sprintf("%x", h.Sum(nil))
-
-	c.logger.Debug("API signature",
-		zap.String("url_path", urlPath),
-		zap.String("sign_string", signStr),
-		zap.String("signature", signature))
-
-	return signature
+// SetAuthenticator 设置请求认证方式
+func (c *CMDBClient) SetAuthenticator(auth Authenticator) *CMDBClient {
+	c.auth = auth
+	c.logger.Info("Set authentication", zap.String("auth_type", auth.Name()))
+	return c
 }
 
-// addAPIAuth 为请求添加API认证参数
-func (c *CMDBClient) addAPIAuth(urlPath string, params map[string]string) map[string]string {
-	if c.apiKey == "" || c.apiSecret == "" {
-		c.logger.Error("API credentials not set")
-		return params
-	}
-
-	if params == nil {
-		params = make(map[string]string)
-	}
+// SetTimeout 设置请求超时
+func (c *CMDBClient) SetTimeout(timeout time.Duration) *CMDBClient {
+	c.client.SetTimeout(timeout)
+	return c
+}
 
-	// 添加API Key
-	params["_key"] = c.apiKey
+// SetRetry 设置最大重试次数和第一次重试前的等待时间，其余沿用当前重试策略
+func (c *CMDBClient) SetRetry(count int, waitTime time.Duration) *CMDBClient {
+	c.retry.MaxRetries = count
+	c.retry.InitialInterval = waitTime
+	return c
+}
 
-	// 计算并添加签名
-	params["_secret"] = c.buildSignature(urlPath, params)
+// SetRetryPolicy 设置请求重试策略
+func (c *CMDBClient) SetRetryPolicy(policy RetryPolicy) *CMDBClient {
+	c.retry = policy
+	return c
+}
 
-	return params
+// RetryPolicy 返回请求重试策略
+func (c *CMDBClient) RetryPolicy() RetryPolicy {
+	return c.retry
 }
 
-// SetTimeout 设置请求超时
-func (c *CMDBClient) SetTimeout(timeout time.Duration) *CMDBClient {
-	c.client.SetTimeout(timeout)
+// SetRateLimit 设置每秒请求数和突发请求数，rps<=0表示不限速（仍会在服务端限流时退避）
+func (c *CMDBClient) SetRateLimit(rps float64, burst int) *CMDBClient {
+	c.limiter = NewRateLimiter(rps, burst)
 	return c
 }
 
-// SetRetry 设置重试配置
-func (c *CMDBClient) SetRetry(count int, waitTime time.Duration) *CMDBClient {
-	c.client.SetRetryCount(count).SetRetryWaitTime(waitTime)
+// SetRateLimiter 使用指定的限流器，可以在多个客户端之间共享
+func (c *CMDBClient) SetRateLimiter(limiter *RateLimiter) *CMDBClient {
+	c.limiter = limiter
 	return c
 }
 
+// RateLimitStats 返回限流等待统计
+func (c *CMDBClient) RateLimitStats() RateLimitStats {
+	return c.limiter.Stats()
+}
+
 // buildURL 构建完整的API URL
 func (c *CMDBClient) buildURL(endpoint string) string {
 	if strings.HasPrefix(endpoint, "/") {
@@ -143,22 +150,15 @@ func (c *CMDBClient) getURLPath(fullURL string) string {
 }
 
 // GetRelationViews 获取服务树视图列表
-func (c *CMDBClient) GetRelationViews() (*models.RelationViewResponse, error) {
+func (c *CMDBClient) GetRelationViews(ctx context.Context) (*models.RelationViewResponse, error) {
 	c.logger.Info("Fetching relation views")
 
 	fullURL := c.buildURL("preference/relation/view")
-	urlPath := c.getURLPath(fullURL)
-	c.logger.Debug("Request URL", zap.String("url", fullURL), zap.String("path", urlPath))
+	c.logger.Debug("Request URL", zap.String("url", fullURL))
 
 	var response models.RelationViewResponse
 
-	// 构建查询参数并添加API认证
-	params := c.addAPIAuth(urlPath, nil)
-
-	resp, err := c.client.R().
-		SetQueryParams(params).
-		SetResult(&response).
-		Get(fullURL)
+	resp, err := c.send(ctx, fullURL, nil, &response)
 
 	if err != nil {
 		c.logger.Error("Failed to get relation views", zap.Error(err))
@@ -173,7 +173,7 @@ func (c *CMDBClient) GetRelationViews() (*models.RelationViewResponse, error) {
 		c.logger.Error("API returned non-200 status",
 			zap.Int("status", resp.StatusCode()),
 			zap.String("body", string(resp.Body())))
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return nil, newAPIError(resp)
 	}
 
 	c.logger.Info("Successfully fetched relation views",
@@ -182,20 +182,26 @@ func (c *CMDBClient) GetRelationViews() (*models.RelationViewResponse, error) {
 	return &response, nil
 }
 
-// SearchCI 搜索CI实例
-func (c *CMDBClient) SearchCI(query string, count int, useIDFilter bool) (*models.CISearchResponse, error) {
+// SearchCI 搜索CI实例（仅返回第一页）
+func (c *CMDBClient) SearchCI(ctx context.Context, query string, count int, useIDFilter bool) (*models.CISearchResponse, error) {
+	return c.SearchCIPage(ctx, query, 1, count, useIDFilter)
+}
+
+// SearchCIPage 按页搜索CI实例，page从1开始
+func (c *CMDBClient) SearchCIPage(ctx context.Context, query string, page, count int, useIDFilter bool) (*models.CISearchResponse, error) {
 	c.logger.Info("Searching CI instances",
 		zap.String("query", query),
+		zap.Int("page", page),
 		zap.Int("count", count))
 
 	var response models.CISearchResponse
 
 	fullURL := c.buildURL("ci/s")
-	urlPath := c.getURLPath(fullURL)
 
 	// 构建查询参数
 	params := map[string]string{
 		"q":     query,
+		"page":  strconv.Itoa(page),
 		"count": strconv.Itoa(count),
 	}
 
@@ -203,13 +209,7 @@ func (c *CMDBClient) SearchCI(query string, count int, useIDFilter bool) (*model
 		params["use_id_filter"] = "1"
 	}
 
-	// 添加API认证
-	params = c.addAPIAuth(urlPath, params)
-
-	resp, err := c.client.R().
-		SetQueryParams(params).
-		SetResult(&response).
-		Get(fullURL)
+	resp, err := c.send(ctx, fullURL, params, &response)
 
 	if err != nil {
 		c.logger.Error("Failed to search CI instances", zap.Error(err))
@@ -220,7 +220,7 @@ func (c *CMDBClient) SearchCI(query string, count int, useIDFilter bool) (*model
 		c.logger.Error("API returned non-200 status",
 			zap.Int("status", resp.StatusCode()),
 			zap.String("body", string(resp.Body())))
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return nil, newAPIError(resp)
 	}
 
 	c.logger.Info("Successfully searched CI instances",
@@ -230,14 +230,34 @@ func (c *CMDBClient) SearchCI(query string, count int, useIDFilter bool) (*model
 	return &response, nil
 }
 
+// SearchAllCI 自动翻页搜索CI实例，直到取回NumFound条记录
+func (c *CMDBClient) SearchAllCI(ctx context.Context, query string, pageSize int, useIDFilter bool) (*models.CISearchResponse, error) {
+	result, numFound, pages, err := c.paginate(ctx, pageSize, func(page int) ([]models.CIInstance, int, error) {
+		resp, err := c.SearchCIPage(ctx, query, page, pageSize, useIDFilter)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Result, resp.NumFound, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CISearchResponse{
+		Result:   result,
+		NumFound: numFound,
+		Total:    len(result),
+		Page:     pages,
+	}, nil
+}
+
 // SearchCIRelation 搜索CI关系
-func (c *CMDBClient) SearchCIRelation(queryParams map[string]interface{}) (*models.CIRelationSearchResponse, error) {
+func (c *CMDBClient) SearchCIRelation(ctx context.Context, queryParams map[string]interface{}) (*models.CIRelationSearchResponse, error) {
 	c.logger.Info("Searching CI relations", zap.Any("params", queryParams))
 
 	var response models.CIRelationSearchResponse
 
 	fullURL := c.buildURL("ci_relations/s")
-	urlPath := c.getURLPath(fullURL)
 
 	// 转换参数
 	params := make(map[string]string)
@@ -260,13 +280,7 @@ func (c *CMDBClient) SearchCIRelation(queryParams map[string]interface{}) (*mode
 		}
 	}
 
-	// 添加API认证
-	params = c.addAPIAuth(urlPath, params)
-
-	resp, err := c.client.R().
-		SetQueryParams(params).
-		SetResult(&response).
-		Get(fullURL)
+	resp, err := c.send(ctx, fullURL, params, &response)
 
 	if err != nil {
 		c.logger.Error("Failed to search CI relations", zap.Error(err))
@@ -277,7 +291,7 @@ func (c *CMDBClient) SearchCIRelation(queryParams map[string]interface{}) (*mode
 		c.logger.Error("API returned non-200 status",
 			zap.Int("status", resp.StatusCode()),
 			zap.String("body", string(resp.Body())))
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return nil, newAPIError(resp)
 	}
 
 	c.logger.Info("Successfully searched CI relations",
@@ -287,14 +301,95 @@ func (c *CMDBClient) SearchCIRelation(queryParams map[string]interface{}) (*mode
 	return &response, nil
 }
 
+// SearchAllCIRelations 自动翻页搜索CI关系，queryParams中的page和count会被覆盖
+func (c *CMDBClient) SearchAllCIRelations(ctx context.Context, queryParams map[string]interface{}, pageSize int) (*models.CIRelationSearchResponse, error) {
+	var last *models.CIRelationSearchResponse
+
+	result, numFound, pages, err := c.paginate(ctx, pageSize, func(page int) ([]models.CIInstance, int, error) {
+		params := make(map[string]interface{}, len(queryParams)+2)
+		for k, v := range queryParams {
+			params[k] = v
+		}
+		params["page"] = page
+		params["count"] = pageSize
+
+		resp, err := c.SearchCIRelation(ctx, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		last = resp
+		return resp.Result, resp.NumFound, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CIRelationSearchResponse{
+		Result:   result,
+		NumFound: numFound,
+		Total:    len(result),
+		Page:     pages,
+	}
+	if last != nil {
+		response.Counter = last.Counter
+		response.Facet = last.Facet
+	}
+
+	return response, nil
+}
+
+// paginate 循环调用fetch拉取所有分页，返回合并结果、NumFound和实际请求的页数
+func (c *CMDBClient) paginate(ctx context.Context, pageSize int, fetch func(page int) ([]models.CIInstance, int, error)) ([]models.CIInstance, int, int, error) {
+	if pageSize <= 0 {
+		return nil, 0, 0, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+
+	var all []models.CIInstance
+	numFound := 0
+	page := 0
+
+	for {
+		// 翻页之间检查上下文，取消后不再发起新请求
+		if err := ctx.Err(); err != nil {
+			return nil, 0, page, err
+		}
+
+		page++
+		result, found, err := fetch(page)
+		if err != nil {
+			return nil, 0, page, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+
+		numFound = found
+		all = append(all, result...)
+
+		// 没有更多数据、已取满或最后一页不足pageSize时结束
+		if len(result) == 0 || len(all) >= numFound || len(result) < pageSize {
+			break
+		}
+
+		c.logger.Debug("Fetching next page",
+			zap.Int("page", page+1),
+			zap.Int("fetched", len(all)),
+			zap.Int("num_found", numFound))
+	}
+
+	if len(all) < numFound {
+		c.logger.Warn("Pagination ended before all records were fetched",
+			zap.Int("fetched", len(all)),
+			zap.Int("num_found", numFound))
+	}
+
+	return all, numFound, page, nil
+}
+
 // GetCIRelationStatistics 获取CI关系统计
-func (c *CMDBClient) GetCIRelationStatistics(queryParams map[string]interface{}) (models.StatisticsResponse, error) {
+func (c *CMDBClient) GetCIRelationStatistics(ctx context.Context, queryParams map[string]interface{}) (models.StatisticsResponse, error) {
 	c.logger.Info("Getting CI relation statistics", zap.Any("params", queryParams))
 
 	var response models.StatisticsResponse
 
 	fullURL := c.buildURL("ci_relations/statistics")
-	urlPath := c.getURLPath(fullURL)
 
 	// 转换参数
 	params := make(map[string]string)
@@ -317,13 +412,7 @@ func (c *CMDBClient) GetCIRelationStatistics(queryParams map[string]interface{})
 		}
 	}
 
-	// 添加API认证
-	params = c.addAPIAuth(urlPath, params)
-
-	resp, err := c.client.R().
-		SetQueryParams(params).
-		SetResult(&response).
-		Get(fullURL)
+	resp, err := c.send(ctx, fullURL, params, &response)
 
 	if err != nil {
 		c.logger.Error("Failed to get CI relation statistics", zap.Error(err))
@@ -334,7 +423,7 @@ func (c *CMDBClient) GetCIRelationStatistics(queryParams map[string]interface{})
 		c.logger.Error("API returned non-200 status",
 			zap.Int("status", resp.StatusCode()),
 			zap.String("body", string(resp.Body())))
-		return models.StatisticsResponse{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return models.StatisticsResponse{}, newAPIError(resp)
 	}
 
 	c.logger.Info("Successfully got CI relation statistics",
@@ -343,6 +432,363 @@ func (c *CMDBClient) GetCIRelationStatistics(queryParams map[string]interface{})
 	return response, nil
 }
 
+// SearchCIRelationFull 一次查询多个根节点下level层以内的关系树，typeIDs[i]为第i+1层的CI类型
+// 返回的节点不含CI属性，用于批量获取父子节点的对应关系；descendantIDs与ci_relations/s的descendant_ids相同，
+// 服务端按其过滤子节点
+func (c *CMDBClient) SearchCIRelationFull(ctx context.Context, rootIDs []int, level int, typeIDs []int,
+	descendantIDs []int, hasM2M bool) ([]models.CIRelationTreeNode, error) {
+	c.logger.Debug("Searching full CI relation tree",
+		zap.Int("root_count", len(rootIDs)),
+		zap.Int("level", level),
+		zap.Ints("type_ids", typeIDs))
+
+	rootStrs := make([]string, len(rootIDs))
+	for i, id := range rootIDs {
+		rootStrs[i] = strconv.Itoa(id)
+	}
+	typeStrs := make([]string, len(typeIDs))
+	for i, id := range typeIDs {
+		typeStrs[i] = strconv.Itoa(id)
+	}
+
+	params := map[string]string{
+		"root_ids": strings.Join(rootStrs, ","),
+		"level":    strconv.Itoa(level),
+		"type_ids": strings.Join(typeStrs, ","),
+	}
+	if len(descendantIDs) > 0 {
+		descendantStrs := make([]string, len(descendantIDs))
+		for i, id := range descendantIDs {
+			descendantStrs[i] = strconv.Itoa(id)
+		}
+		params["descendant_ids"] = strings.Join(descendantStrs, ",")
+	}
+	if hasM2M {
+		params["has_m2m"] = "1"
+	}
+
+	var response []models.CIRelationTreeNode
+	if err := c.get(ctx, "ci_relations/search/full", params, &response); err != nil {
+		c.logger.Error("Failed to search full CI relation tree", zap.Error(err))
+		return nil, fmt.Errorf("failed to search full CI relation tree: %w", err)
+	}
+
+	return response, nil
+}
+
+// send 发送带认证信息的GET请求，凭证失效(401)且可以刷新时刷新后重试一次
+func (c *CMDBClient) send(ctx context.Context, fullURL string, params map[string]string, result interface{}) (*resty.Response, error) {
+	resp, err := c.doWithRetry(ctx, fullURL, params, result)
+	if err != nil || resp.StatusCode() != http.StatusUnauthorized || c.auth == nil {
+		return resp, err
+	}
+
+	refreshed, err := c.auth.Refresh(ctx, resp.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh %s credentials: %w", c.auth.Name(), err)
+	}
+	if !refreshed {
+		return resp, nil
+	}
+
+	c.logger.Info("Credentials refreshed, retrying request",
+		zap.String("auth_type", c.auth.Name()),
+		zap.String("url", fullURL))
+	return c.doWithRetry(ctx, fullURL, params, result)
+}
+
+// doWithRetry 经过限流器发送请求，按重试策略重试失败的请求
+// 429/503通知限流器退避，重试前的等待由限流器控制；502/504和网络错误按策略指数退避
+func (c *CMDBClient) doWithRetry(ctx context.Context, fullURL string, params map[string]string, result interface{}) (*resty.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.doGet(ctx, fullURL, params, result)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode()
+		}
+
+		var delay time.Duration
+		switch {
+		case err == nil && isThrottled(status):
+			delay = c.limiter.Throttle(retryAfter(resp))
+		case err == nil:
+			c.limiter.Success()
+		}
+
+		if attempt > c.retry.MaxRetries || !c.retry.shouldRetry(status, err) {
+			return resp, err
+		}
+
+		// 限流时由下一次 limiter.Wait 等待，其余情况在这里等待
+		var sleep time.Duration
+		if delay == 0 {
+			delay = c.retry.Backoff(attempt)
+			sleep = delay
+		}
+		if c.retry.MaxElapsedTime > 0 && time.Since(start)+delay > c.retry.MaxElapsedTime {
+			c.logger.Warn("Retry time exhausted, giving up",
+				zap.String("url", fullURL),
+				zap.Int("attempt", attempt),
+				zap.Duration("elapsed", time.Since(start)))
+			return resp, err
+		}
+
+		fields := []zap.Field{
+			zap.String("url", fullURL),
+			zap.Int("attempt", attempt),
+			zap.Int("max_retries", c.retry.MaxRetries),
+			zap.Duration("backoff", delay),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		} else {
+			fields = append(fields, zap.Int("status", status))
+		}
+		c.logger.Warn("Request failed, retrying", fields...)
+
+		if err := SleepContext(ctx, sleep); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doGet 为请求添加认证信息后发送，params不会被修改
+func (c *CMDBClient) doGet(ctx context.Context, fullURL string, params map[string]string, result interface{}) (*resty.Response, error) {
+	query := make(map[string]string, len(params)+2)
+	for k, v := range params {
+		query[k] = v
+	}
+
+	req := c.client.R().
+		SetContext(ctx).
+		SetResult(result)
+
+	if c.auth == nil {
+		c.logger.Error("API credentials not set")
+	} else if err := c.auth.Authenticate(ctx, req, c.getURLPath(fullURL), query); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	return req.SetQueryParams(query).Get(fullURL)
+}
+
+// get 发送带认证信息的GET请求并将JSON响应解析到result
+func (c *CMDBClient) get(ctx context.Context, endpoint string, params map[string]string, result interface{}) error {
+	fullURL := c.buildURL(endpoint)
+
+	resp, err := c.send(ctx, fullURL, params, result)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() != 200 {
+		c.logger.Error("API returned non-200 status",
+			zap.String("endpoint", endpoint),
+			zap.Int("status", resp.StatusCode()),
+			zap.String("body", string(resp.Body())))
+		return newAPIError(resp)
+	}
+
+	return nil
+}
+
+// GetCITypes 获取所有CI类型
+func (c *CMDBClient) GetCITypes(ctx context.Context) (*models.CITypeListResponse, error) {
+	c.logger.Info("Fetching CI types")
+
+	var response models.CITypeListResponse
+	if err := c.get(ctx, "ci_types", nil, &response); err != nil {
+		c.logger.Error("Failed to get CI types", zap.Error(err))
+		return nil, fmt.Errorf("failed to get CI types: %w", err)
+	}
+
+	c.logger.Info("Successfully fetched CI types",
+		zap.Int("type_count", len(response.CITypes)))
+
+	return &response, nil
+}
+
+// GetCITypeAttributes 获取CI类型的属性定义（包含继承的属性）
+func (c *CMDBClient) GetCITypeAttributes(ctx context.Context, typeID int) (*models.CITypeAttributesResponse, error) {
+	c.logger.Debug("Fetching CI type attributes", zap.Int("type_id", typeID))
+
+	var response models.CITypeAttributesResponse
+	endpoint := fmt.Sprintf("ci_types/%d/attributes", typeID)
+	if err := c.get(ctx, endpoint, nil, &response); err != nil {
+		c.logger.Error("Failed to get CI type attributes", zap.Int("type_id", typeID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get attributes for CI type %d: %w", typeID, err)
+	}
+
+	return &response, nil
+}
+
+// GetCITypeRelations 获取所有CI类型之间的关系
+func (c *CMDBClient) GetCITypeRelations(ctx context.Context) (*models.CITypeRelationListResponse, error) {
+	c.logger.Info("Fetching CI type relations")
+
+	var response models.CITypeRelationListResponse
+	if err := c.get(ctx, "ci_type_relations", nil, &response); err != nil {
+		c.logger.Error("Failed to get CI type relations", zap.Error(err))
+		return nil, fmt.Errorf("failed to get CI type relations: %w", err)
+	}
+
+	c.logger.Info("Successfully fetched CI type relations",
+		zap.Int("relation_count", len(response.Relations)))
+
+	return &response, nil
+}
+
+// GetSecondCIs 按页获取CI的子CI（second_cis），relationType为关系类型名称，为空时不过滤
+func (c *CMDBClient) GetSecondCIs(ctx context.Context, ciID int, relationType string, page, count int) (*models.SecondCIsResponse, error) {
+	c.logger.Debug("Fetching second CIs",
+		zap.Int("ci_id", ciID),
+		zap.String("relation_type", relationType),
+		zap.Int("page", page))
+
+	params := map[string]string{
+		"page":  strconv.Itoa(page),
+		"count": strconv.Itoa(count),
+	}
+	if relationType != "" {
+		params["relation_type"] = relationType
+	}
+
+	var response models.SecondCIsResponse
+	endpoint := fmt.Sprintf("ci_relations/%d/second_cis", ciID)
+	if err := c.get(ctx, endpoint, params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get second CIs of %d: %w", ciID, err)
+	}
+
+	return &response, nil
+}
+
+// GetAllSecondCIs 自动翻页获取CI的全部子CI
+func (c *CMDBClient) GetAllSecondCIs(ctx context.Context, ciID int, relationType string, pageSize int) ([]models.CIInstance, error) {
+	result, _, _, err := c.paginate(ctx, pageSize, func(page int) ([]models.CIInstance, int, error) {
+		resp, err := c.GetSecondCIs(ctx, ciID, relationType, page, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.SecondCIs, resp.NumFound, nil
+	})
+	return result, err
+}
+
+// GetFirstCIs 按页获取CI的父CI（first_cis）
+func (c *CMDBClient) GetFirstCIs(ctx context.Context, ciID int, page, count int) (*models.FirstCIsResponse, error) {
+	c.logger.Debug("Fetching first CIs",
+		zap.Int("ci_id", ciID),
+		zap.Int("page", page))
+
+	params := map[string]string{
+		"page":  strconv.Itoa(page),
+		"count": strconv.Itoa(count),
+	}
+
+	var response models.FirstCIsResponse
+	endpoint := fmt.Sprintf("ci_relations/%d/first_cis", ciID)
+	if err := c.get(ctx, endpoint, params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get first CIs of %d: %w", ciID, err)
+	}
+
+	return &response, nil
+}
+
+// GetAllFirstCIs 自动翻页获取CI的全部父CI
+func (c *CMDBClient) GetAllFirstCIs(ctx context.Context, ciID int, pageSize int) ([]models.CIInstance, error) {
+	result, _, _, err := c.paginate(ctx, pageSize, func(page int) ([]models.CIInstance, int, error) {
+		resp, err := c.GetFirstCIs(ctx, ciID, page, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.FirstCIs, resp.NumFound, nil
+	})
+	return result, err
+}
+
+// historyTimeLayout 历史记录API的时间格式
+const historyTimeLayout = "2006-01-02 15:04:05"
+
+// GetAttributeHistory 获取指定时间之后的CI属性变更记录，page从1开始
+func (c *CMDBClient) GetAttributeHistory(ctx context.Context, since time.Time, page, pageSize int) (*models.HistoryRecordResponse, error) {
+	return c.getHistory(ctx, "history/records/attribute", since, page, pageSize)
+}
+
+// GetRelationHistory 获取指定时间之后的CI关系变更记录，page从1开始
+func (c *CMDBClient) GetRelationHistory(ctx context.Context, since time.Time, page, pageSize int) (*models.HistoryRecordResponse, error) {
+	return c.getHistory(ctx, "history/records/relation", since, page, pageSize)
+}
+
+// getHistory 查询变更历史记录
+func (c *CMDBClient) getHistory(ctx context.Context, endpoint string, since time.Time, page, pageSize int) (*models.HistoryRecordResponse, error) {
+	c.logger.Info("Fetching change history",
+		zap.String("endpoint", endpoint),
+		zap.Time("since", since),
+		zap.Int("page", page))
+
+	var response models.HistoryRecordResponse
+
+	params := map[string]string{
+		"start":     since.Local().Format(historyTimeLayout),
+		"page":      strconv.Itoa(page),
+		"page_size": strconv.Itoa(pageSize),
+	}
+
+	if err := c.get(ctx, endpoint, params, &response); err != nil {
+		c.logger.Error("Failed to fetch change history", zap.String("endpoint", endpoint), zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch %s: %w", endpoint, err)
+	}
+
+	return &response, nil
+}
+
+// GetChangedCIIDs 汇总指定时间之后属性或关系发生变更的CI ID
+func (c *CMDBClient) GetChangedCIIDs(ctx context.Context, since time.Time, pageSize int) (map[int]bool, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+
+	changed := make(map[int]bool)
+	fetchers := []func(context.Context, time.Time, int, int) (*models.HistoryRecordResponse, error){
+		c.GetAttributeHistory,
+		c.GetRelationHistory,
+	}
+
+	for _, fetch := range fetchers {
+		for page := 1; ; page++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			resp, err := fetch(ctx, since, page, pageSize)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, id := range resp.ChangedCIIDs() {
+				changed[id] = true
+			}
+
+			// page_size作用于原始历史行，CMDB再按record_id合并，返回的记录数可能少于page_size；
+			// total是当前页的原始行数，不足page_size时才是最后一页
+			if resp.Total < pageSize {
+				break
+			}
+		}
+	}
+
+	c.logger.Info("Collected changed CIs",
+		zap.Time("since", since),
+		zap.Int("changed_count", len(changed)))
+
+	return changed, nil
+}
+
 // BuildCITypeQuery 构建CI类型查询字符串
 func (c *CMDBClient) BuildCITypeQuery(typeIDs []int) string {
 	if len(typeIDs) == 0 {
@@ -413,10 +859,10 @@ func (c *CMDBClient) BuildTreeKey(segments []TreeKeySegment) string {
 	return strings.Join(parts, "@^@")
 }
 
-// ValidateResponse 验证API响应
+// ValidateResponse 验证API响应，非2xx响应返回 *APIError
 func (c *CMDBClient) ValidateResponse(resp *resty.Response) error {
 	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode(), string(resp.Body()))
+		return newAPIError(resp)
 	}
 	return nil
 }
@@ -1,6 +1,12 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"go.uber.org/zap"
@@ -63,3 +69,111 @@ func TestBuildCITypeQuery_KKKK_View(t *testing.T) {
 
 	t.Logf("KKKK视图查询修复验证: %s", result)
 }
+
+// newPagedServer 创建按page/count分页返回total条CI的测试服务器
+func newPagedServer(t *testing.T, total int, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		if page < 1 || count < 1 {
+			t.Errorf("unexpected paging params: page=%q count=%q",
+				r.URL.Query().Get("page"), r.URL.Query().Get("count"))
+		}
+
+		result := make([]map[string]interface{}, 0, count)
+		for id := (page-1)*count + 1; id <= page*count && id <= total; id++ {
+			result = append(result, map[string]interface{}{"_id": id, "_type": 73, "name": "ci-" + strconv.Itoa(id)})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":   result,
+			"numfound": total,
+			"total":    len(result),
+			"page":     page,
+		})
+	}))
+}
+
+// TestSearchAllCI 测试CI搜索自动翻页
+func TestSearchAllCI(t *testing.T) {
+	requests := 0
+	server := newPagedServer(t, 25, &requests)
+	defer server.Close()
+
+	client := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop())
+	client.SetAPICredentials("key", "secret")
+
+	resp, err := client.SearchAllCI(context.Background(), "_type:(73)", 10, false)
+	if err != nil {
+		t.Fatalf("SearchAllCI failed: %v", err)
+	}
+
+	if len(resp.Result) != 25 {
+		t.Fatalf("expected 25 results, got %d", len(resp.Result))
+	}
+	if resp.NumFound != 25 {
+		t.Errorf("expected numfound 25, got %d", resp.NumFound)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 page requests, got %d", requests)
+	}
+	if resp.Result[24].ID != 25 {
+		t.Errorf("expected last CI ID 25, got %d", resp.Result[24].ID)
+	}
+}
+
+// TestSearchAllCIRelations 测试CI关系搜索自动翻页
+func TestSearchAllCIRelations(t *testing.T) {
+	requests := 0
+	server := newPagedServer(t, 20, &requests)
+	defer server.Close()
+
+	client := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop())
+	client.SetAPICredentials("key", "secret")
+
+	params := map[string]interface{}{
+		"q":       "_type:(2)",
+		"root_id": 1,
+		"level":   1,
+	}
+
+	resp, err := client.SearchAllCIRelations(context.Background(), params, 10)
+	if err != nil {
+		t.Fatalf("SearchAllCIRelations failed: %v", err)
+	}
+
+	if len(resp.Result) != 20 {
+		t.Errorf("expected 20 results, got %d", len(resp.Result))
+	}
+	// 恰好整页时需要依赖numfound结束，不应多请求空页
+	if requests != 2 {
+		t.Errorf("expected 2 page requests, got %d", requests)
+	}
+	if _, exists := params["page"]; exists {
+		t.Error("caller params should not be modified")
+	}
+}
+
+// TestSearchAllCICancelled 测试上下文取消后停止翻页
+func TestSearchAllCICancelled(t *testing.T) {
+	requests := 0
+	server := newPagedServer(t, 25, &requests)
+	defer server.Close()
+
+	client := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop())
+	client.SetAPICredentials("key", "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.SearchAllCI(ctx, "_type:(73)", 10, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests after cancellation, got %d", requests)
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// maxErrorBodyLength 无法解析错误信息时保留的响应体最大长度
+const maxErrorBodyLength = 512
+
+// 可通过 errors.Is 判断的API错误类别
+var (
+	// ErrUnauthorized 凭证无效或已过期 (401)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden 没有访问资源的权限 (403)
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound 资源不存在 (404)
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited 请求被服务端限流 (429)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServerError 服务端错误 (5xx)
+	ErrServerError = errors.New("server error")
+)
+
+// APIError CMDB API返回的非2xx响应
+type APIError struct {
+	// StatusCode HTTP状态码
+	StatusCode int
+	// Endpoint 请求的URL路径，如 /api/v0.1/ci/s
+	Endpoint string
+	// Message 从响应体解析出的CMDB错误信息，无法解析时为截断后的响应体
+	Message string
+}
+
+// newAPIError 根据响应创建APIError
+func newAPIError(resp *resty.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode(),
+		Message:    parseErrorMessage(resp.Body()),
+	}
+	if resp.RawResponse != nil && resp.RawResponse.Request != nil {
+		apiErr.Endpoint = resp.RawResponse.Request.URL.Path
+	}
+	return apiErr
+}
+
+// Error 错误描述
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API returned status %d", e.StatusCode)
+	if e.Endpoint != "" {
+		msg += " for " + e.Endpoint
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is 按状态码匹配错误类别
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// parseErrorMessage 解析CMDB错误响应体 {"message": "..."}，不是JSON时返回截断的原始内容
+func parseErrorMessage(body []byte) string {
+	var payload struct {
+		Message string `json:"message"`
+		Msg     string `json:"msg"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		for _, msg := range []string{payload.Message, payload.Msg, payload.Error} {
+			if msg != "" {
+				return msg
+			}
+		}
+	}
+
+	text := strings.TrimSpace(string(body))
+	if len(text) > maxErrorBodyLength {
+		text = text[:maxErrorBodyLength] + "..."
+	}
+	return text
+}
+
+// IsRetryable 判断错误是否为临时错误：与重试策略一致，即429、502、503、504响应或网络错误
+// 其他5xx响应、上下文取消或超时不属于临时错误
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatuses[apiErr.StatusCode]
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
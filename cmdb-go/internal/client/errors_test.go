@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestAPIError 测试非2xx响应返回带状态码、端点和错误信息的APIError
+func TestAPIError(t *testing.T) {
+	status := http.StatusOK
+	body := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	cmdbClient := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewAPIKeyAuth("key", "secret", zap.NewNop()))
+
+	tests := []struct {
+		status  int
+		body    string
+		target  error
+		message string
+	}{
+		{http.StatusUnauthorized, `{"message": "invalid api key"}`, ErrUnauthorized, "invalid api key"},
+		{http.StatusForbidden, `{"message": "no permission"}`, ErrForbidden, "no permission"},
+		{http.StatusNotFound, `<html>not found</html>`, ErrNotFound, "<html>not found</html>"},
+		{http.StatusInternalServerError, `{"msg": "db error"}`, ErrServerError, "db error"},
+	}
+
+	for _, tt := range tests {
+		status, body = tt.status, tt.body
+
+		_, err := cmdbClient.GetCITypes(context.Background())
+		if !errors.Is(err, tt.target) {
+			t.Errorf("status %d: expected %v, got %v", tt.status, tt.target, err)
+			continue
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("status %d: expected *APIError, got %T", tt.status, err)
+			continue
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Endpoint != "/api/v0.1/ci_types" || apiErr.Message != tt.message {
+			t.Errorf("status %d: unexpected APIError %+v", tt.status, apiErr)
+		}
+		if IsRetryable(err) {
+			t.Errorf("status %d: expected error not to be retryable", tt.status)
+		}
+	}
+}
+
+// TestIsRetryable 测试临时错误判断
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(&APIError{StatusCode: http.StatusTooManyRequests}) || !IsRetryable(&APIError{StatusCode: http.StatusBadGateway}) {
+		t.Error("Expected 429 and 502 to be retryable")
+	}
+	if IsRetryable(&APIError{StatusCode: http.StatusInternalServerError}) {
+		t.Error("Expected 500 not to be retryable, matching the retry policy")
+	}
+	if IsRetryable(&APIError{StatusCode: http.StatusBadRequest}) || IsRetryable(context.Canceled) || IsRetryable(nil) {
+		t.Error("Expected 400, context cancellation and nil not to be retryable")
+	}
+
+	// 连接被拒绝等网络错误可以重试
+	_, err := NewCMDBClient("http://127.0.0.1:1", "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewAPIKeyAuth("key", "secret", zap.NewNop())).
+		SetRetry(0, 0).
+		GetCITypes(context.Background())
+	if err == nil || !IsRetryable(err) {
+		t.Errorf("Expected network error to be retryable, got %v", err)
+	}
+}
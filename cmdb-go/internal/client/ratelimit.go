@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// defaultMinBackoff 限流响应没有 Retry-After 时的初始退避时间
+	defaultMinBackoff = time.Second
+	// defaultMaxBackoff 退避时间上限，Retry-After 超过上限时按上限等待
+	defaultMaxBackoff = time.Minute
+	// minRateFactor 被限流后速率最多降低到配置速率的比例
+	minRateFactor = 1.0 / 16
+	// rateRecoverFactor 每个成功请求恢复的速率占配置速率的比例
+	rateRecoverFactor = 1.0 / 10
+)
+
+// RateLimitStats 限流统计
+type RateLimitStats struct {
+	// Requests 经过限流器的请求数
+	Requests int64 `json:"requests"`
+	// Delayed 需要等待才能发出的请求数
+	Delayed int64 `json:"delayed"`
+	// Throttled 服务端返回429/503的次数
+	Throttled int64 `json:"throttled"`
+	// TotalWait 所有请求的累计等待时间，MaxWait 单个请求的最长等待时间
+	TotalWait time.Duration `json:"total_wait"`
+	MaxWait   time.Duration `json:"max_wait"`
+	// CurrentRate 当前生效的每秒请求数，被限流后降低，0表示不限速
+	CurrentRate float64 `json:"current_rate"`
+}
+
+// RateLimiter 令牌桶限流器，所有并发请求共享
+// 服务端返回429/503时暂停发送请求（优先使用 Retry-After，否则指数退避）并将速率减半，之后每个成功请求逐步恢复速率
+type RateLimiter struct {
+	mu sync.Mutex
+	// rate 配置的每秒请求数，0表示不限速，只在被限流时暂停
+	rate    float64
+	burst   int
+	current float64
+	tokens  float64
+	// last 上次补充令牌的时间，暂停期间位于将来
+	last        time.Time
+	pausedUntil time.Time
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	backoff     time.Duration
+	stats       RateLimitStats
+}
+
+// NewRateLimiter 创建限流器，rps<=0表示不限速，burst<=0时取rps向上取整
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if rps < 0 {
+		rps = 0
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rps)))
+	}
+
+	return &RateLimiter{
+		rate:       rps,
+		burst:      burst,
+		current:    rps,
+		tokens:     float64(burst),
+		last:       time.Now(),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// SetBackoff 设置没有 Retry-After 时的退避范围
+func (l *RateLimiter) SetBackoff(min, max time.Duration) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.minBackoff = min
+	l.maxBackoff = max
+	return l
+}
+
+// Wait 等待直到可以发送下一个请求，上下文已取消时不占用令牌，等待期间取消时归还令牌
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	if err := SleepContext(ctx, delay); err != nil {
+		l.release()
+		return err
+	}
+	return nil
+}
+
+// release 归还一个预占后未使用的令牌
+func (l *RateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current > 0 {
+		l.tokens = math.Min(float64(l.burst), l.tokens+1)
+	}
+}
+
+// reserve 预占一个令牌并返回需要等待的时间
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var delay time.Duration
+	if l.current > 0 {
+		if now.After(l.last) {
+			l.tokens = math.Min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*l.current)
+			l.last = now
+		}
+		// 令牌不足时欠账，后续请求依次排在更晚的时间
+		l.tokens--
+		delay = l.last.Sub(now)
+		if l.tokens < 0 {
+			delay += time.Duration(-l.tokens / l.current * float64(time.Second))
+		}
+	}
+	if pause := l.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+
+	l.stats.Requests++
+	if delay > 0 {
+		l.stats.Delayed++
+		l.stats.TotalWait += delay
+		if delay > l.stats.MaxWait {
+			l.stats.MaxWait = delay
+		}
+	}
+	return delay
+}
+
+// Throttle 记录一次服务端限流并暂停发送请求，返回暂停时长
+// retryAfter>0时按服务端要求等待，否则从 minBackoff 开始指数退避
+func (l *RateLimiter) Throttle(retryAfter time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stats.Throttled++
+
+	pause := retryAfter
+	if pause <= 0 {
+		l.backoff = time.Duration(math.Max(float64(l.minBackoff), float64(l.backoff*2)))
+		pause = l.backoff
+	}
+	if pause > l.maxBackoff {
+		pause = l.maxBackoff
+	}
+
+	now := time.Now()
+	if until := now.Add(pause); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+
+	if l.rate > 0 {
+		l.current = math.Max(l.current/2, l.rate*minRateFactor)
+		// 暂停结束后从空桶开始按降低后的速率发送
+		l.tokens = 0
+		if l.pausedUntil.After(l.last) {
+			l.last = l.pausedUntil
+		}
+	}
+	return pause
+}
+
+// Success 记录一次未被限流的响应，重置退避并逐步恢复速率
+func (l *RateLimiter) Success() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.backoff = 0
+	if l.rate > 0 && l.current < l.rate {
+		l.current = math.Min(l.rate, l.current+l.rate*rateRecoverFactor)
+	}
+}
+
+// Stats 返回限流统计
+func (l *RateLimiter) Stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.CurrentRate = l.current
+	return stats
+}
+
+// isThrottled 响应是否表示服务端限流或过载
+func isThrottled(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfter 解析 Retry-After 响应头（秒数或HTTP日期），没有或无法解析时返回0
+func retryAfter(resp *resty.Response) time.Duration {
+	value := strings.TrimSpace(resp.Header().Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
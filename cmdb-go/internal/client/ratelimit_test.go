@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+// TestRateLimiterBoundsConcurrentRequests 测试多个协程共享令牌桶时总速率受限
+func TestRateLimiterBoundsConcurrentRequests(t *testing.T) {
+	limiter := NewRateLimiter(100, 5)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if err := limiter.Wait(context.Background()); err != nil {
+					t.Errorf("Wait failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 20个请求，突发5个，其余15个按100rps发出至少需要150ms
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Expected requests to be spread over at least 140ms, took %s", elapsed)
+	}
+
+	stats := limiter.Stats()
+	if stats.Requests != 20 || stats.Delayed != 15 {
+		t.Errorf("Expected 20 requests with 15 delayed, got %+v", stats)
+	}
+	if stats.MaxWait <= 0 || stats.TotalWait < stats.MaxWait {
+		t.Errorf("Expected wait time to be recorded, got %+v", stats)
+	}
+
+	// 取消的上下文立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Throttle(time.Minute)
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected Wait to fail with a cancelled context")
+	}
+}
+
+// TestRateLimiterCancelKeepsBudget 测试取消的等待不消耗令牌
+func TestRateLimiterCancelKeepsBudget(t *testing.T) {
+	limiter := NewRateLimiter(10, 1)
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(cancelled); err == nil {
+		t.Error("Expected Wait to fail with a cancelled context")
+	}
+	if got := limiter.Stats().Requests; got != 1 {
+		t.Errorf("Expected cancelled context not to reserve a token, got %d requests", got)
+	}
+
+	// 等待期间超时的请求归还令牌，下一个请求仍在100ms后发出，而不是200ms
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(timeout); err == nil {
+		t.Error("Expected Wait to time out")
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 170*time.Millisecond {
+		t.Errorf("Expected the timed out token to be returned, next request took %s", elapsed)
+	}
+}
+
+// TestRateLimiterAdaptiveBackoff 测试限流后退避、降速和恢复
+func TestRateLimiterAdaptiveBackoff(t *testing.T) {
+	limiter := NewRateLimiter(100, 10).SetBackoff(10*time.Millisecond, 40*time.Millisecond)
+
+	if got := limiter.Throttle(0); got != 10*time.Millisecond {
+		t.Errorf("Expected first backoff of 10ms, got %s", got)
+	}
+	if got := limiter.Throttle(0); got != 20*time.Millisecond {
+		t.Errorf("Expected backoff to double to 20ms, got %s", got)
+	}
+	if got := limiter.Throttle(0); got != 40*time.Millisecond {
+		t.Errorf("Expected backoff of 40ms, got %s", got)
+	}
+	if got := limiter.Throttle(0); got != 40*time.Millisecond {
+		t.Errorf("Expected backoff to be capped at 40ms, got %s", got)
+	}
+	if got := limiter.Throttle(30 * time.Millisecond); got != 30*time.Millisecond {
+		t.Errorf("Expected Retry-After to be honoured, got %s", got)
+	}
+	if got := limiter.Stats().CurrentRate; got != 100*minRateFactor {
+		t.Errorf("Expected rate to drop to %v, got %v", 100*minRateFactor, got)
+	}
+
+	limiter.Success()
+	if got := limiter.Stats().CurrentRate; got != 100*minRateFactor+100*rateRecoverFactor {
+		t.Errorf("Expected rate to recover after success, got %v", got)
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Success()
+	}
+	if got := limiter.Stats().CurrentRate; got != 100 {
+		t.Errorf("Expected rate to recover to configured rate, got %v", got)
+	}
+	if got := limiter.Throttle(0); got != 10*time.Millisecond {
+		t.Errorf("Expected backoff to reset after success, got %s", got)
+	}
+}
+
+// TestClientBacksOffOnThrottle 测试客户端收到429/503后退避并重试
+func TestClientBacksOffOnThrottle(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"numfound": 0, "ci_types": []interface{}{}})
+		}
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(0, 0).SetBackoff(20*time.Millisecond, time.Second)
+	cmdbClient := NewCMDBClient(server.URL, "api/v0.1", zap.NewNop()).
+		SetAuthenticator(NewAPIKeyAuth("key", "secret", zap.NewNop())).
+		SetRateLimiter(limiter)
+
+	start := time.Now()
+	if _, err := cmdbClient.GetCITypes(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed after backing off, got %v", err)
+	}
+	// 两次退避: 20ms + 40ms
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected at least 60ms of backoff, took %s", elapsed)
+	}
+
+	stats := cmdbClient.RateLimitStats()
+	if calls != 3 || stats.Throttled != 2 || stats.Delayed != 2 {
+		t.Errorf("Expected 3 calls with 2 throttled and delayed, got %d calls, %+v", calls, stats)
+	}
+}
+
+// TestRetryAfter 测试解析 Retry-After 响应头
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"3", 3 * time.Second, 3 * time.Second},
+		{"-1", 0, 0},
+		{"invalid", 0, 0},
+		{time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, tt := range tests {
+		resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+		if tt.value != "" {
+			resp.RawResponse.Header.Set("Retry-After", tt.value)
+		}
+		if got := retryAfter(resp); got < tt.min || got > tt.max {
+			t.Errorf("retryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 请求重试策略，只用于查询类的GET请求，重复发送不会产生副作用
+// 服务端返回429、502、503、504或发生网络错误时按指数退避重试，429/503的等待由限流器按 Retry-After 控制
+type RetryPolicy struct {
+	// MaxRetries 最大重试次数，0表示不重试
+	MaxRetries int
+	// InitialInterval 第一次重试前的等待时间，之后每次乘以 Multiplier，最长 MaxInterval
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter 等待时间的随机抖动比例(0-1)，避免并发请求同时重试
+	Jitter float64
+	// MaxElapsedTime 从第一次请求开始的最长重试时间，0表示不限制
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryPolicy 默认重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:      3,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxElapsedTime:  2 * time.Minute,
+	}
+}
+
+// retryableStatuses 需要重试的响应状态码
+var retryableStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// shouldRetry 判断请求结果是否需要重试：可重试的状态码或网络错误，上下文取消不重试
+func (p RetryPolicy) shouldRetry(status int, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+	return retryableStatuses[status]
+}
+
+// Retries 按该策略客户端是否已经自动重试过返回err的请求
+func (p RetryPolicy) Retries(err error) bool {
+	return p.MaxRetries > 0 && IsRetryable(err)
+}
+
+// SleepContext 等待指定时间，上下文取消时立即返回，d<=0时只检查上下文
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Backoff 第attempt次重试(从1开始)前的等待时间
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval) * math.Pow(math.Max(p.Multiplier, 1), float64(attempt-1))
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		interval *= 1 - jitter + 2*jitter*rand.Float64()
+	}
+	return time.Duration(interval)
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newRetryServer 创建依次返回statuses中状态码的测试服务器，用完后返回200
+func newRetryServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			json.NewEncoder(w).Encode(map[string]string{"message": http.StatusText(statuses[n-1])})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"numfound": 0, "ci_types": []interface{}{}})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newRetryClient 创建使用指定重试策略的客户端，限流退避缩短为毫秒级
+func newRetryClient(baseURL string, policy RetryPolicy, logger *zap.Logger) *CMDBClient {
+	return NewCMDBClient(baseURL, "api/v0.1", logger).
+		SetAuthenticator(NewAPIKeyAuth("key", "secret", zap.NewNop())).
+		SetRateLimiter(NewRateLimiter(0, 0).SetBackoff(5*time.Millisecond, 50*time.Millisecond)).
+		SetRetryPolicy(policy)
+}
+
+// fastPolicy 毫秒级间隔、无抖动的重试策略
+func fastPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:      maxRetries,
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     100 * time.Millisecond,
+		Multiplier:      2,
+	}
+}
+
+// TestClientRetriesTransientStatuses 测试502/503/504/429重试成功，每次重试记录日志
+func TestClientRetriesTransientStatuses(t *testing.T) {
+	statuses := []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests}
+	server, calls := newRetryServer(t, statuses...)
+	core, logs := observer.New(zap.WarnLevel)
+
+	start := time.Now()
+	if _, err := newRetryClient(server.URL, fastPolicy(4), zap.New(core)).GetCITypes(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed after retries, got %v", err)
+	}
+	if *calls != 5 {
+		t.Errorf("Expected 5 requests, got %d", *calls)
+	}
+	// 502: 10ms，503: 限流器5ms，504: 40ms，429: 限流器5ms（中间的成功响应重置了限流退避）
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected at least 60ms of backoff, took %s", elapsed)
+	}
+
+	entries := logs.FilterMessage("Request failed, retrying").All()
+	if len(entries) != len(statuses) {
+		t.Fatalf("Expected %d retry logs, got %d", len(statuses), len(entries))
+	}
+	for i, entry := range entries {
+		fields := entry.ContextMap()
+		if fields["attempt"] != int64(i+1) || fields["status"] != int64(statuses[i]) {
+			t.Errorf("Unexpected retry log fields %v", fields)
+		}
+	}
+}
+
+// TestClientDoesNotRetryOtherErrors 测试500和4xx不重试
+func TestClientDoesNotRetryOtherErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusInternalServerError, ErrServerError},
+		{http.StatusNotFound, ErrNotFound},
+	}
+	for _, tt := range tests {
+		server, calls := newRetryServer(t, tt.status)
+		_, err := newRetryClient(server.URL, fastPolicy(3), zap.NewNop()).GetCITypes(context.Background())
+		if !errors.Is(err, tt.target) {
+			t.Errorf("status %d: expected %v, got %v", tt.status, tt.target, err)
+		}
+		if *calls != 1 {
+			t.Errorf("status %d: expected no retries, got %d requests", tt.status, *calls)
+		}
+	}
+}
+
+// TestClientRetryLimits 测试重试次数和最长重试时间耗尽后返回最后一次的错误
+func TestClientRetryLimits(t *testing.T) {
+	always := []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+
+	server, calls := newRetryServer(t, always...)
+	_, err := newRetryClient(server.URL, fastPolicy(2), zap.NewNop()).GetCITypes(context.Background())
+	if !errors.Is(err, ErrServerError) || *calls != 3 {
+		t.Errorf("Expected ErrServerError after 3 requests, got %v after %d", err, *calls)
+	}
+
+	// 第二次重试需要再等20ms，超过30ms的最长重试时间
+	policy := fastPolicy(4)
+	policy.MaxElapsedTime = 30 * time.Millisecond
+	server, calls = newRetryServer(t, always...)
+	_, err = newRetryClient(server.URL, policy, zap.NewNop()).GetCITypes(context.Background())
+	if !errors.Is(err, ErrServerError) || *calls != 2 {
+		t.Errorf("Expected ErrServerError after 2 requests, got %v after %d", err, *calls)
+	}
+}
+
+// TestClientRetryStopsOnCancel 测试等待重试期间取消上下文立即返回
+func TestClientRetryStopsOnCancel(t *testing.T) {
+	server, calls := newRetryServer(t, http.StatusBadGateway, http.StatusBadGateway)
+	policy := fastPolicy(3)
+	policy.InitialInterval = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newRetryClient(server.URL, policy, zap.NewNop()).GetCITypes(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || *calls != 1 {
+		t.Errorf("Expected to stop waiting on cancel, took %s and %d requests", elapsed, *calls)
+	}
+}
+
+// TestClientRetriesNetworkErrors 测试连接失败时重试
+func TestClientRetriesNetworkErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	core, logs := observer.New(zap.WarnLevel)
+	_, err = newRetryClient("http://"+addr, fastPolicy(2), zap.New(core)).GetCITypes(context.Background())
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("Expected network error, got %v", err)
+	}
+	if got := logs.FilterMessage("Request failed, retrying").Len(); got != 2 {
+		t.Errorf("Expected 2 retries, got %d", got)
+	}
+}
+
+// TestRetryPolicyBackoff 测试指数退避的上限和抖动范围
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := policy.Backoff(attempt + 1); got != want*time.Millisecond {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt+1, got, want*time.Millisecond)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 100*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("Expected jittered backoff within [100ms, 300ms], got %s", got)
+		}
+	}
+}
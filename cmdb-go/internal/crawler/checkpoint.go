@@ -0,0 +1,264 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// CheckpointEntryKind 检查点记录类型
+type CheckpointEntryKind string
+
+const (
+	// CheckpointView 已完成的服务树视图
+	CheckpointView CheckpointEntryKind = "view"
+	// CheckpointSubtree 已完成的根节点子树
+	CheckpointSubtree CheckpointEntryKind = "subtree"
+)
+
+// CheckpointEntry 检查点日志中的一条记录
+type CheckpointEntry struct {
+	Kind       CheckpointEntryKind     `json:"kind"`
+	ViewName   string                  `json:"view_name"`
+	TreeKey    string                  `json:"tree_key,omitempty"`
+	Tree       *models.ServiceTreeData `json:"tree,omitempty"`
+	Node       *models.ServiceTreeNode `json:"node,omitempty"`
+	RecordedAt time.Time               `json:"recorded_at"`
+}
+
+// Checkpoint 爬取检查点
+// 以JSON Lines格式追加记录已完成的视图和子树，中断后可从日志恢复
+type Checkpoint struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	logger   *zap.Logger
+	views    map[string]*models.ServiceTreeData
+	subtrees map[string]map[string]*models.ServiceTreeNode
+}
+
+// OpenCheckpoint 打开检查点日志
+// resume为true时加载已有记录并在文件末尾继续追加，否则清空文件重新开始
+func OpenCheckpoint(path string, resume bool, logger *zap.Logger) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		path:     path,
+		logger:   logger,
+		views:    make(map[string]*models.ServiceTreeData),
+		subtrees: make(map[string]map[string]*models.ServiceTreeNode),
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := cp.load(); err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint journal: %w", err)
+	}
+	cp.file = file
+
+	// 上次中断时最后一行可能没有换行，先补齐避免新记录拼接到残缺行上
+	if resume && !endsWithNewline(path) {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to repair checkpoint journal: %w", err)
+		}
+	}
+
+	logger.Info("Opened checkpoint journal",
+		zap.String("path", path),
+		zap.Bool("resume", resume),
+		zap.Int("completed_views", len(cp.views)),
+		zap.Int("completed_subtrees", cp.subtreeCount()))
+
+	return cp, nil
+}
+
+// load 读取已有的检查点日志
+func (cp *Checkpoint) load() error {
+	file, err := os.Open(cp.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cp.logger.Warn("Checkpoint journal not found, starting fresh", zap.String("path", cp.path))
+			return nil
+		}
+		return fmt.Errorf("failed to open checkpoint journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry CheckpointEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// 进程中断时最后一行可能只写了一半，跳过即可
+			cp.logger.Warn("Skipping corrupt checkpoint entry",
+				zap.Int("line", lineNo),
+				zap.Error(err))
+			continue
+		}
+
+		cp.apply(&entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read checkpoint journal: %w", err)
+	}
+
+	return nil
+}
+
+// apply 将一条记录应用到内存状态
+func (cp *Checkpoint) apply(entry *CheckpointEntry) {
+	switch entry.Kind {
+	case CheckpointView:
+		if entry.Tree == nil {
+			return
+		}
+		cp.views[entry.ViewName] = entry.Tree
+		// 视图已完成，其子树记录不再需要
+		delete(cp.subtrees, entry.ViewName)
+	case CheckpointSubtree:
+		if entry.Node == nil || entry.TreeKey == "" {
+			return
+		}
+		if cp.subtrees[entry.ViewName] == nil {
+			cp.subtrees[entry.ViewName] = make(map[string]*models.ServiceTreeNode)
+		}
+		cp.subtrees[entry.ViewName][entry.TreeKey] = entry.Node
+	}
+}
+
+// CompletedView 获取已完成的视图数据
+func (cp *Checkpoint) CompletedView(viewName string) (*models.ServiceTreeData, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	tree, ok := cp.views[viewName]
+	return tree, ok
+}
+
+// CompletedSubtree 获取视图中已完成的子树
+func (cp *Checkpoint) CompletedSubtree(viewName, treeKey string) (*models.ServiceTreeNode, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	node, ok := cp.subtrees[viewName][treeKey]
+	return node, ok
+}
+
+// RecordView 记录已完成的视图
+func (cp *Checkpoint) RecordView(tree *models.ServiceTreeData) error {
+	return cp.record(&CheckpointEntry{
+		Kind:       CheckpointView,
+		ViewName:   tree.ViewName,
+		Tree:       tree,
+		RecordedAt: time.Now(),
+	})
+}
+
+// RecordSubtree 记录视图中已完成的子树
+func (cp *Checkpoint) RecordSubtree(viewName, treeKey string, node *models.ServiceTreeNode) error {
+	return cp.record(&CheckpointEntry{
+		Kind:       CheckpointSubtree,
+		ViewName:   viewName,
+		TreeKey:    treeKey,
+		Node:       node,
+		RecordedAt: time.Now(),
+	})
+}
+
+// record 追加一条记录到日志文件
+func (cp *Checkpoint) record(entry *CheckpointEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.file == nil {
+		return fmt.Errorf("checkpoint journal is closed")
+	}
+
+	if _, err := cp.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write checkpoint entry: %w", err)
+	}
+
+	cp.apply(entry)
+	return nil
+}
+
+// Path 返回检查点日志路径
+func (cp *Checkpoint) Path() string {
+	return cp.path
+}
+
+// Close 关闭检查点日志
+func (cp *Checkpoint) Close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.file == nil {
+		return nil
+	}
+
+	err := cp.file.Close()
+	cp.file = nil
+	return err
+}
+
+// endsWithNewline 判断文件是否为空或以换行结尾
+func endsWithNewline(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return true
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return true
+	}
+	return last[0] == '\n'
+}
+
+// subtreeCount 统计已完成的子树数量
+func (cp *Checkpoint) subtreeCount() int {
+	count := 0
+	for _, nodes := range cp.subtrees {
+		count += len(nodes)
+	}
+	return count
+}
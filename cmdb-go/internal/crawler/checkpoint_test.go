@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// TestCheckpointResume 测试检查点记录后可恢复
+func TestCheckpointResume(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "crawl.journal")
+
+	cp, err := OpenCheckpoint(path, false, logger)
+	if err != nil {
+		t.Fatalf("Failed to open checkpoint: %v", err)
+	}
+
+	subtree := &models.ServiceTreeNode{ID: 1, Type: 73, Name: "root-1"}
+	subtree.AddChild(&models.ServiceTreeNode{ID: 2, Type: 2, Name: "child-2"})
+
+	if err := cp.RecordSubtree("view-a", "1%73%", subtree); err != nil {
+		t.Fatalf("Failed to record subtree: %v", err)
+	}
+	if err := cp.RecordView(&models.ServiceTreeData{ViewName: "view-b", TotalNodes: 3}); err != nil {
+		t.Fatalf("Failed to record view: %v", err)
+	}
+	cp.Close()
+
+	// 模拟中断时写了一半的记录
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	f.WriteString(`{"kind":"subtree","view_name":"view-a","tree_key":"9%73%","node":{"id":`)
+	f.Close()
+
+	resumed, err := OpenCheckpoint(path, true, logger)
+	if err != nil {
+		t.Fatalf("Failed to resume checkpoint: %v", err)
+	}
+	node, ok := resumed.CompletedSubtree("view-a", "1%73%")
+	if !ok {
+		t.Fatal("Expected subtree to be restored")
+	}
+	if len(node.Children) != 1 || node.Children[0].ID != 2 {
+		t.Errorf("Expected restored subtree to keep its children, got %+v", node.Children)
+	}
+
+	if _, ok := resumed.CompletedSubtree("view-a", "9%73%"); ok {
+		t.Error("Expected corrupt entry to be skipped")
+	}
+
+	tree, ok := resumed.CompletedView("view-b")
+	if !ok || tree.TotalNodes != 3 {
+		t.Errorf("Expected view-b to be restored with 3 nodes, got %+v", tree)
+	}
+
+	// 在残缺行之后继续追加的记录应能再次恢复
+	if err := resumed.RecordView(&models.ServiceTreeData{ViewName: "view-c"}); err != nil {
+		t.Fatalf("Failed to record view after resume: %v", err)
+	}
+	resumed.Close()
+
+	again, err := OpenCheckpoint(path, true, logger)
+	if err != nil {
+		t.Fatalf("Failed to resume checkpoint again: %v", err)
+	}
+	defer again.Close()
+
+	if _, ok := again.CompletedView("view-c"); !ok {
+		t.Error("Expected view-c recorded after a corrupt line to be restored")
+	}
+}
+
+// TestCheckpointViewSupersedesSubtrees 测试视图完成后其子树记录被清除
+func TestCheckpointViewSupersedesSubtrees(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.journal")
+
+	cp, err := OpenCheckpoint(path, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to open checkpoint: %v", err)
+	}
+	defer cp.Close()
+
+	cp.RecordSubtree("view-a", "1%73%", &models.ServiceTreeNode{ID: 1, Type: 73})
+	cp.RecordView(&models.ServiceTreeData{ViewName: "view-a"})
+
+	if _, ok := cp.CompletedSubtree("view-a", "1%73%"); ok {
+		t.Error("Expected subtree entries to be dropped once the view completed")
+	}
+	if _, ok := cp.CompletedView("view-a"); !ok {
+		t.Error("Expected view-a to be completed")
+	}
+}
@@ -0,0 +1,227 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/client"
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// CICrawler 全量CI爬取器，不依赖服务树视图，按CI类型分页拉取所有CI
+type CICrawler struct {
+	client          *client.CMDBClient
+	logger          *zap.Logger
+	pageSize        int
+	maxWorkers      int
+	requestInterval time.Duration
+	// retry 爬取器层面的重试策略，只重试客户端自身不重试的临时错误
+	retry client.RetryPolicy
+	// 只爬取这些类型（按名称或别名匹配），为空时爬取全部类型
+	typeFilter []string
+}
+
+// NewCICrawler 创建全量CI爬取器
+func NewCICrawler(client *client.CMDBClient, logger *zap.Logger) *CICrawler {
+	return &CICrawler{
+		client:          client,
+		logger:          logger,
+		pageSize:        1000,
+		maxWorkers:      10,
+		requestInterval: 100 * time.Millisecond,
+		retry:           defaultRetryPolicy(),
+	}
+}
+
+// SetPageSize 设置分页大小
+func (c *CICrawler) SetPageSize(size int) *CICrawler {
+	c.pageSize = size
+	return c
+}
+
+// SetMaxWorkers 设置同时爬取的类型数
+func (c *CICrawler) SetMaxWorkers(workers int) *CICrawler {
+	c.maxWorkers = workers
+	return c
+}
+
+// SetRetryPolicy 设置爬取器层面的重试策略，客户端已按同一策略重试的错误不会再次重试
+func (c *CICrawler) SetRetryPolicy(policy client.RetryPolicy) *CICrawler {
+	c.retry = policy
+	return c
+}
+
+// SetRequestInterval 设置每个协程的请求间隔，客户端已启用限流时设为0，避免两者叠加降低速率
+func (c *CICrawler) SetRequestInterval(interval time.Duration) *CICrawler {
+	c.requestInterval = interval
+	return c
+}
+
+// SetTypeFilter 设置只爬取的CI类型名称或别名
+func (c *CICrawler) SetTypeFilter(types []string) *CICrawler {
+	c.typeFilter = types
+	return c
+}
+
+// CrawlAllCIs 枚举所有CI类型并拉取每个类型下的全部CI及其属性，每个类型一个并发任务
+// 单个类型失败时记录错误并继续，认证失败时中止；上下文被取消时返回已完成的类型和上下文错误
+func (c *CICrawler) CrawlAllCIs(ctx context.Context) (*models.CIInventory, error) {
+	c.logger.Info("Starting to crawl all CIs")
+
+	typesResp, err := c.client.GetCITypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI types: %w", err)
+	}
+
+	types := c.filterTypes(typesResp.CITypes)
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].ID < types[j].ID
+	})
+
+	inventory := &models.CIInventory{
+		Types:     make([]*models.CITypeInventory, len(types)),
+		CrawledAt: time.Now(),
+	}
+
+	// 认证失败时取消其余类型的爬取
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, c.maxWorkers)
+
+	for i, ciType := range types {
+		wg.Add(1)
+		go func(i int, ciType models.CITypeDefinition) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+
+			inventory.Types[i] = c.crawlType(ctx, ciType, abort)
+		}(i, ciType)
+	}
+
+	wg.Wait()
+
+	if cause := context.Cause(ctx); isAbortError(cause) {
+		c.logger.Error("CI crawl aborted", zap.Error(cause))
+		return nil, cause
+	}
+
+	// 去掉因取消而未开始的类型
+	completed := inventory.Types[:0]
+	failed := 0
+	for _, typeInventory := range inventory.Types {
+		if typeInventory == nil {
+			continue
+		}
+		if typeInventory.Error != "" {
+			failed++
+		}
+		completed = append(completed, typeInventory)
+	}
+	inventory.Types = completed
+	inventory.CountCIs()
+
+	if err := ctx.Err(); err != nil {
+		c.logger.Warn("CI crawl cancelled",
+			zap.Int("completed_types", len(inventory.Types)),
+			zap.Int("total_cis", inventory.TotalCIs),
+			zap.Error(err))
+		return inventory, err
+	}
+
+	if failed > 0 {
+		c.logger.Warn("Some CI types failed to crawl", zap.Int("error_count", failed))
+	}
+
+	c.logger.Info("Completed crawling all CIs",
+		zap.Int("type_count", len(inventory.Types)),
+		zap.Int("total_cis", inventory.TotalCIs))
+
+	return inventory, nil
+}
+
+// crawlType 分页拉取单个类型的全部CI，临时错误重试，需要中止的错误通过abort取消整次爬取
+func (c *CICrawler) crawlType(ctx context.Context, ciType models.CITypeDefinition, abort context.CancelCauseFunc) *models.CITypeInventory {
+	typeInventory := &models.CITypeInventory{Type: ciType, CIs: []models.CIRecord{}}
+
+	if err := client.SleepContext(ctx, c.requestInterval); err != nil {
+		typeInventory.Error = err.Error()
+		return typeInventory
+	}
+
+	query := c.client.BuildCITypeQuery([]int{ciType.ID})
+	var resp *models.CISearchResponse
+	err := withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "crawl CI type", func() (err error) {
+		resp, err = c.client.SearchAllCI(ctx, query, c.pageSize, false)
+		return err
+	})
+	if err != nil {
+		if isAbortError(err) {
+			abort(err)
+		}
+		if ctx.Err() == nil {
+			c.logger.Error("Failed to crawl CI type",
+				zap.Int("type_id", ciType.ID),
+				zap.String("type_name", ciType.Name),
+				zap.Error(err))
+		}
+		typeInventory.Error = err.Error()
+		return typeInventory
+	}
+
+	typeName := ciType.DisplayName()
+	for _, ci := range resp.Result {
+		typeInventory.CIs = append(typeInventory.CIs, models.NewCIRecord(ci, typeName))
+	}
+	typeInventory.NumFound = resp.NumFound
+
+	c.logger.Debug("Crawled CI type",
+		zap.Int("type_id", ciType.ID),
+		zap.String("type_name", ciType.Name),
+		zap.Int("ci_count", len(typeInventory.CIs)))
+
+	return typeInventory
+}
+
+// filterTypes 按名称或别名过滤CI类型，并提示未找到的类型
+func (c *CICrawler) filterTypes(types []models.CITypeDefinition) []models.CITypeDefinition {
+	if len(c.typeFilter) == 0 {
+		return types
+	}
+
+	wanted := make(map[string]bool, len(c.typeFilter))
+	for _, name := range c.typeFilter {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	found := make(map[string]bool)
+	var filtered []models.CITypeDefinition
+	for _, ciType := range types {
+		for _, key := range []string{strings.ToLower(ciType.Name), strings.ToLower(ciType.Alias)} {
+			if wanted[key] {
+				found[key] = true
+				filtered = append(filtered, ciType)
+				break
+			}
+		}
+	}
+
+	for _, name := range c.typeFilter {
+		if !found[strings.ToLower(name)] {
+			c.logger.Warn("Target CI type not found", zap.String("type_name", name))
+		}
+	}
+	return filtered
+}
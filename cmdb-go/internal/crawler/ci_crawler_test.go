@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestCrawlAllCIs 测试按类型分页爬取全部CI，包括未出现在服务树视图中的类型
+func TestCrawlAllCIs(t *testing.T) {
+	f := newFakeCMDB(t)
+	f.addCIType(1, "product_line", "产品线")
+	f.addCIType(4, "server", "服务器")
+	f.addCIType(5, "ip", "IP")
+	f.addCI(1, 1, "line-a", 0)
+	for id := 10; id < 15; id++ {
+		f.addCI(id, 4, "server", 0).Attrs["cpu"] = float64(id)
+	}
+	f.addCI(20, 5, "10.0.0.1", 0)
+
+	ciCrawler := NewCICrawler(f.newClient(), zap.NewNop()).
+		SetRequestInterval(0).
+		SetPageSize(2).
+		SetMaxWorkers(2)
+
+	inventory, err := ciCrawler.CrawlAllCIs(context.Background())
+	if err != nil {
+		t.Fatalf("CrawlAllCIs failed: %v", err)
+	}
+
+	if len(inventory.Types) != 3 || inventory.TotalCIs != 7 {
+		t.Fatalf("Expected 3 types and 7 CIs, got %d types and %d CIs", len(inventory.Types), inventory.TotalCIs)
+	}
+	servers := inventory.Types[1]
+	if servers.Type.Name != "server" || len(servers.CIs) != 5 || servers.NumFound != 5 {
+		t.Fatalf("Unexpected server inventory: %+v", servers)
+	}
+	if servers.CIs[0].TypeName != "服务器" || servers.CIs[0].Attributes["cpu"] != float64(10) {
+		t.Errorf("Expected type name and attributes to be kept, got %+v", servers.CIs[0])
+	}
+	// 5台服务器按每页2条分3页，其余类型各1页
+	if got := f.requestCount("ci/s"); got != 5 {
+		t.Errorf("Expected 5 search requests, got %d", got)
+	}
+}
+
+// TestCrawlAllCIsTypeFilter 测试按名称或别名过滤CI类型
+func TestCrawlAllCIsTypeFilter(t *testing.T) {
+	f := newFakeCMDB(t)
+	f.addCIType(4, "server", "服务器")
+	f.addCIType(5, "ip", "IP")
+	f.addCI(10, 4, "server", 0)
+	f.addCI(20, 5, "10.0.0.1", 0)
+
+	inventory, err := NewCICrawler(f.newClient(), zap.NewNop()).
+		SetRequestInterval(0).
+		SetTypeFilter([]string{"IP", "missing"}).
+		CrawlAllCIs(context.Background())
+	if err != nil {
+		t.Fatalf("CrawlAllCIs failed: %v", err)
+	}
+
+	if len(inventory.Types) != 1 || inventory.Types[0].Type.ID != 5 || inventory.TotalCIs != 1 {
+		t.Errorf("Expected only the ip type, got %+v", inventory.Types)
+	}
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cmdb-crawler/internal/client"
+
+	"go.uber.org/zap"
+)
+
+// transientRetries 爬取器默认的临时错误（429、502、503、504、网络错误）最大重试次数
+const transientRetries = 2
+
+// transientRetryWait 爬取器默认的临时错误第一次重试前的等待时间，之后每次加倍
+var transientRetryWait = time.Second
+
+// defaultRetryPolicy 爬取器默认的重试策略，只在客户端自身不重试时生效
+func defaultRetryPolicy() client.RetryPolicy {
+	return client.RetryPolicy{
+		MaxRetries:      transientRetries,
+		InitialInterval: transientRetryWait,
+		Multiplier:      2,
+	}
+}
+
+// errorAction 请求失败后爬取器的处理方式
+type errorAction int
+
+const (
+	// actionSkip 跳过失败的视图、子树或CI类型，继续爬取其余部分
+	actionSkip errorAction = iota
+	// actionRetry 临时错误，等待后重试，重试耗尽后按 actionSkip 处理
+	actionRetry
+	// actionAbort 后续请求同样会失败（如凭证无效）或上下文已取消，中止整次爬取
+	actionAbort
+)
+
+// classifyError 根据错误类型决定重试、跳过还是中止
+// 401中止；429、502、503、504和网络错误重试；403、404、其他5xx及其他错误跳过
+func classifyError(err error) errorAction {
+	switch {
+	case isContextError(err), errors.Is(err, client.ErrUnauthorized):
+		return actionAbort
+	case client.IsRetryable(err):
+		return actionRetry
+	default:
+		return actionSkip
+	}
+}
+
+// isAbortError 判断错误是否需要中止整次爬取（上下文取消除外，由调用方单独处理部分结果）
+func isAbortError(err error) bool {
+	return err != nil && !isContextError(err) && classifyError(err) == actionAbort
+}
+
+// withRetry 执行fn，临时错误按policy指数退避重试，其他错误直接返回
+// clientPolicy为客户端的重试策略，客户端已经重试过的错误不再重试，避免两层重试叠加放大请求数
+func withRetry(ctx context.Context, logger *zap.Logger, policy, clientPolicy client.RetryPolicy, operation string, fn func() error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > policy.MaxRetries || classifyError(err) != actionRetry || clientPolicy.Retries(err) {
+			return err
+		}
+
+		wait := policy.Backoff(attempt)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+wait > policy.MaxElapsedTime {
+			return err
+		}
+		logger.Warn("Transient error, retrying",
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+			zap.Error(err))
+		if waitErr := client.SleepContext(ctx, wait); waitErr != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cmdb-crawler/internal/client"
+
+	"go.uber.org/zap"
+)
+
+// fastRetry 测试期间缩短临时错误的重试间隔
+func fastRetry(t *testing.T) {
+	wait := transientRetryWait
+	transientRetryWait = time.Millisecond
+	t.Cleanup(func() { transientRetryWait = wait })
+}
+
+// TestClassifyError 测试按错误类型决定重试、跳过或中止
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want errorAction
+	}{
+		{&client.APIError{StatusCode: http.StatusUnauthorized}, actionAbort},
+		{fmt.Errorf("wrapped: %w", &client.APIError{StatusCode: http.StatusUnauthorized}), actionAbort},
+		{context.Canceled, actionAbort},
+		{&client.APIError{StatusCode: http.StatusTooManyRequests}, actionRetry},
+		{&client.APIError{StatusCode: http.StatusBadGateway}, actionRetry},
+		{&client.APIError{StatusCode: http.StatusInternalServerError}, actionSkip},
+		{&client.APIError{StatusCode: http.StatusForbidden}, actionSkip},
+		{&client.APIError{StatusCode: http.StatusNotFound}, actionSkip},
+		{errors.New("decode error"), actionSkip},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestCrawlRetriesTransientErrors 测试5xx错误重试后爬取完整的树
+func TestCrawlRetriesTransientErrors(t *testing.T) {
+	fastRetry(t)
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+	f.fail("ci_relations/search/full", http.StatusBadGateway, 2)
+
+	trees, err := f.newCrawler().CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("CrawlAllServiceTrees failed: %v", err)
+	}
+	if len(trees) != 1 || trees[0].TotalNodes != 6 {
+		t.Fatalf("Expected complete tree with 6 nodes after retries, got %+v", trees)
+	}
+	// 第1层失败2次后成功，第2层1次
+	if got := f.requestCount("ci_relations/search/full"); got != 4 {
+		t.Errorf("Expected 4 relation requests, got %d", got)
+	}
+}
+
+// TestCrawlRetryBudget 测试重试耗尽后的总请求数：客户端已重试的错误爬取器不再重试，重试次数为0时不重试
+func TestCrawlRetryBudget(t *testing.T) {
+	fastRetry(t)
+	tests := []struct {
+		name          string
+		status        int
+		clientRetries int
+		crawlerPolicy *client.RetryPolicy
+		want          int
+	}{
+		{"client retries", http.StatusBadGateway, 2, nil, 3},
+		{"crawler retries", http.StatusBadGateway, 0, nil, 3},
+		{"retries disabled", http.StatusBadGateway, 0, &client.RetryPolicy{}, 1},
+		{"internal server error", http.StatusInternalServerError, 0, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeCMDB(t)
+			seedSimpleTree(f)
+			f.fail("ci_relations/search/full", tt.status, -1)
+
+			cmdbClient := f.newClient().SetRetryPolicy(client.RetryPolicy{MaxRetries: tt.clientRetries, InitialInterval: time.Millisecond})
+			serviceCrawler := NewServiceTreeCrawler(cmdbClient, zap.NewNop()).SetRequestInterval(0)
+			if tt.crawlerPolicy != nil {
+				serviceCrawler.SetRetryPolicy(*tt.crawlerPolicy)
+			}
+
+			trees, err := serviceCrawler.CrawlAllServiceTrees(context.Background())
+			if err != nil {
+				t.Fatalf("Expected failed subtrees to be skipped, got %v", err)
+			}
+			if len(trees) != 1 || trees[0].TotalNodes != 2 {
+				t.Fatalf("Expected tree with only root nodes, got %+v", trees)
+			}
+			if got := f.requestCount("ci_relations/search/full"); got != tt.want {
+				t.Errorf("Expected %d relation requests once retries are exhausted, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestCrawlSkipsForbiddenSubtrees 测试无权限的查询不重试，跳过失败的子树并保留其余结果
+func TestCrawlSkipsForbiddenSubtrees(t *testing.T) {
+	fastRetry(t)
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+	f.fail("ci_relations/search/full", http.StatusForbidden, -1)
+
+	trees, err := f.newCrawler().CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Expected forbidden subtrees to be skipped, got %v", err)
+	}
+	if len(trees) != 1 || trees[0].TotalNodes != 2 {
+		t.Fatalf("Expected tree with only root nodes, got %+v", trees)
+	}
+	if got := f.requestCount("ci_relations/search/full"); got != 1 {
+		t.Errorf("Expected forbidden request not to be retried, got %d requests", got)
+	}
+}
+
+// TestCrawlAbortsOnUnauthorized 测试认证失败时中止整次爬取
+func TestCrawlAbortsOnUnauthorized(t *testing.T) {
+	fastRetry(t)
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+	f.addView("second", simpleView())
+	f.fail("ci/s", http.StatusUnauthorized, -1)
+
+	_, err := f.newCrawler().CrawlAllServiceTrees(context.Background())
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.Endpoint != "/api/v0.1/ci/s" || apiErr.Message != "Unauthorized" {
+		t.Errorf("Expected APIError with endpoint and message, got %+v", apiErr)
+	}
+	if got := f.requestCount("ci/s"); got != 1 {
+		t.Errorf("Expected crawl to stop after the first unauthorized request, got %d requests", got)
+	}
+}
+
+// TestCrawlAllCIsErrorHandling 测试全量CI爬取时无权限的类型被跳过，认证失败时中止
+func TestCrawlAllCIsErrorHandling(t *testing.T) {
+	fastRetry(t)
+	f := newFakeCMDB(t)
+	f.addCIType(4, "server", "服务器")
+	f.addCIType(5, "ip", "IP")
+	f.addCI(10, 4, "server", 0)
+	f.addCI(20, 5, "10.0.0.1", 0)
+
+	ciCrawler := NewCICrawler(f.newClient(), zap.NewNop()).
+		SetRequestInterval(0).
+		SetMaxWorkers(1)
+
+	f.fail("ci/s", http.StatusForbidden, 1)
+	inventory, err := ciCrawler.CrawlAllCIs(context.Background())
+	if err != nil {
+		t.Fatalf("Expected forbidden type to be skipped, got %v", err)
+	}
+	failed := 0
+	for _, typeInventory := range inventory.Types {
+		if typeInventory.Error != "" {
+			failed++
+		}
+	}
+	if len(inventory.Types) != 2 || failed != 1 || inventory.TotalCIs != 1 {
+		t.Errorf("Expected one type to fail and the other to succeed, got %d failed and %d CIs", failed, inventory.TotalCIs)
+	}
+
+	f.fail("ci/s", http.StatusUnauthorized, -1)
+	if _, err := ciCrawler.CrawlAllCIs(context.Background()); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
@@ -0,0 +1,511 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"cmdb-crawler/internal/client"
+
+	"go.uber.org/zap"
+)
+
+// fakeCI 测试用CI实例
+type fakeCI struct {
+	ID    int
+	Type  int
+	Name  string
+	Attrs map[string]interface{}
+}
+
+// fakeCMDB 内存中的CMDB测试服务器，实现爬取器用到的API子集
+type fakeCMDB struct {
+	t        *testing.T
+	mu       sync.Mutex
+	server   *httptest.Server
+	views    map[string]interface{}
+	cis      map[int]*fakeCI
+	children map[int][]int
+	// CI关系的关系类型，键为 [父CI, 子CI]，同一对CI之间可以有多种关系
+	relationTypes map[[2]int][]string
+	// 无权限的CI，查询带descendant_ids时服务端按权限过滤掉
+	restricted map[int]bool
+	// 发生变更的CI，每次变更修改两个属性，在历史中占两行
+	changed  []int
+	requests map[string]int
+	// 每个端点收到的查询参数
+	queries map[string][]url.Values
+	// CI类型模型
+	ciTypes       []map[string]interface{}
+	typeAttrs     map[int][]map[string]interface{}
+	typeRelations []map[string]interface{}
+	// 按端点注入的错误响应
+	failures map[string]*fakeFailure
+}
+
+// fakeFailure 注入的错误响应，remaining为剩余次数，小于0表示一直失败
+type fakeFailure struct {
+	status    int
+	remaining int
+}
+
+// newFakeCMDB 创建测试服务器
+func newFakeCMDB(t *testing.T) *fakeCMDB {
+	f := &fakeCMDB{
+		t:             t,
+		views:         make(map[string]interface{}),
+		cis:           make(map[int]*fakeCI),
+		children:      make(map[int][]int),
+		relationTypes: make(map[[2]int][]string),
+		restricted:    make(map[int]bool),
+		requests:      make(map[string]int),
+		queries:       make(map[string][]url.Values),
+		typeAttrs:     make(map[int][]map[string]interface{}),
+		failures:      make(map[string]*fakeFailure),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// addView 添加服务树视图
+func (f *fakeCMDB) addView(name string, view map[string]interface{}) {
+	f.views[name] = view
+}
+
+// addCI 添加CI，parentID为0表示没有父节点
+func (f *fakeCMDB) addCI(id, typeID int, name string, parentID int) *fakeCI {
+	ci := &fakeCI{ID: id, Type: typeID, Name: name, Attrs: map[string]interface{}{}}
+	f.cis[id] = ci
+	if parentID > 0 {
+		f.children[parentID] = append(f.children[parentID], id)
+	}
+	return ci
+}
+
+// addRelation 添加CI关系，用于构造一个CI有多个父节点的多对多关系，同一对CI重复添加时记录多种关系类型
+func (f *fakeCMDB) addRelation(parentID, childID int, relationType string) {
+	pair := [2]int{parentID, childID}
+	if _, exists := f.relationTypes[pair]; !exists {
+		f.children[parentID] = append(f.children[parentID], childID)
+	}
+	f.relationTypes[pair] = append(f.relationTypes[pair], relationType)
+}
+
+// fail 使指定端点接下来times次请求返回status错误，times小于0表示一直失败
+func (f *fakeCMDB) fail(endpoint string, status, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[endpoint] = &fakeFailure{status: status, remaining: times}
+}
+
+// requestCount 返回指定端点的请求次数
+func (f *fakeCMDB) requestCount(endpoint string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[endpoint]
+}
+
+// queryValues 返回指定端点收到的某个查询参数的所有非空取值
+func (f *fakeCMDB) queryValues(endpoint, key string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var values []string
+	for _, query := range f.queries[endpoint] {
+		if value := query.Get(key); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// newClient 创建指向测试服务器的客户端
+func (f *fakeCMDB) newClient() *client.CMDBClient {
+	cmdbClient := client.NewCMDBClient(f.server.URL, "api/v0.1", zap.NewNop())
+	cmdbClient.SetAPICredentials("key", "secret")
+	// 关闭客户端重试，临时错误由爬取器的 withRetry 重试
+	cmdbClient.SetRetry(0, 0)
+	return cmdbClient
+}
+
+// newCrawler 创建指向测试服务器的爬取器
+func (f *fakeCMDB) newCrawler() *ServiceTreeCrawler {
+	return NewServiceTreeCrawler(f.newClient(), zap.NewNop()).
+		SetRequestInterval(0).
+		SetPageSize(100)
+}
+
+// handle 分发请求
+func (f *fakeCMDB) handle(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/api/v0.1/")
+
+	query := r.URL.Query()
+
+	f.mu.Lock()
+	f.requests[endpoint]++
+	f.queries[endpoint] = append(f.queries[endpoint], query)
+	failure := f.failures[endpoint]
+	status := 0
+	if failure != nil && failure.remaining != 0 {
+		failure.remaining--
+		status = failure.status
+	}
+	f.mu.Unlock()
+
+	if status != 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"message": http.StatusText(status)})
+		return
+	}
+
+	var body interface{}
+
+	switch endpoint {
+	case "preference/relation/view":
+		body = f.relationViews()
+	case "ci/s":
+		types, ids := parseQuery(query.Get("q"))
+		body = f.search(types, ids, query)
+	case "ci_relations/s":
+		parents := parseIntList(query.Get("root_id"))
+		ids := []int{}
+		filter := query.Get("descendant_ids") != ""
+		for _, parent := range parents {
+			for _, child := range f.children[parent] {
+				if !filter || !f.restricted[child] {
+					ids = append(ids, child)
+				}
+			}
+		}
+		types, _ := parseQuery(query.Get("q"))
+		body = f.search(types, ids, query)
+	case "ci_relations/search/full":
+		level, _ := strconv.Atoi(query.Get("level"))
+		typeIDs := parseIntList(query.Get("type_ids"))
+		nodes := []interface{}{}
+		for _, id := range parseIntList(query.Get("root_ids")) {
+			nodes = append(nodes, f.relationTree(id, typeIDs, 1, level, query.Get("descendant_ids") != ""))
+		}
+		body = nodes
+	case "ci_relations/statistics":
+		level, _ := strconv.Atoi(query.Get("level"))
+		types := make(map[int]bool)
+		for _, id := range parseIntList(query.Get("type_ids")) {
+			types[id] = true
+		}
+		stats := map[string]interface{}{}
+		detail := map[string]interface{}{}
+		for _, id := range parseIntList(query.Get("root_ids")) {
+			counts := make(map[string]int)
+			total := 0
+			for _, descendant := range f.descendantsAt(id, level) {
+				if ci := f.cis[descendant]; types[ci.Type] {
+					counts[strconv.Itoa(ci.Type)]++
+					total++
+				}
+			}
+			stats[strconv.Itoa(id)] = total
+			detail[strconv.Itoa(id)] = counts
+		}
+		stats["detail"] = detail
+		body = stats
+	case "history/records/attribute":
+		// 与CMDB一致：page/page_size作用于属性变更的原始行，当前页的行再按record_id合并为记录，
+		// total为当前页的原始行数，记录数可能少于page_size
+		page, _ := strconv.Atoi(query.Get("page"))
+		pageSize, _ := strconv.Atoi(query.Get("page_size"))
+		records := []interface{}{}
+		total := 0
+		for i := (page - 1) * pageSize; i >= 0 && i < page*pageSize && i < 2*len(f.changed); i++ {
+			total++
+			change := map[string]interface{}{"attr_name": []string{"name", "owner"}[i%2], "operate_type": "update"}
+			if n := len(records); n > 0 && records[n-1].(map[string]interface{})["record_id"] == i/2 {
+				record := records[n-1].(map[string]interface{})
+				record["changes"] = append(record["changes"].([]interface{}), change)
+				continue
+			}
+			records = append(records, map[string]interface{}{"record_id": i / 2, "ci_id": f.changed[i/2], "changes": []interface{}{change}})
+		}
+		body = map[string]interface{}{"records": records, "total": total}
+	case "history/records/relation":
+		body = map[string]interface{}{"records": []interface{}{}, "total": 0}
+	case "ci_types":
+		body = map[string]interface{}{"numfound": len(f.ciTypes), "ci_types": f.ciTypes}
+	case "ci_type_relations":
+		body = map[string]interface{}{"relations": f.typeRelations, "type2attributes": map[string]interface{}{}}
+	default:
+		if ciID, direction, ok := parseCIRelationsEndpoint(endpoint); ok {
+			body = f.relatedCIs(ciID, direction, query)
+			break
+		}
+		typeID, ok := parseTypeAttributesEndpoint(endpoint)
+		if !ok {
+			f.t.Errorf("unexpected request: %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		body = map[string]interface{}{"attributes": f.typeAttrs[typeID], "type_id": typeID}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// addCIType 添加CI类型及其属性名
+func (f *fakeCMDB) addCIType(id int, name, alias string, attrs ...string) {
+	f.ciTypes = append(f.ciTypes, map[string]interface{}{"id": id, "name": name, "alias": alias, "enabled": true})
+	for i, attr := range attrs {
+		f.typeAttrs[id] = append(f.typeAttrs[id], map[string]interface{}{
+			"id": id*100 + i, "name": attr, "alias": attr, "value_type": "2", "order": i,
+		})
+	}
+}
+
+// addTypeRelation 添加CI类型关系
+func (f *fakeCMDB) addTypeRelation(parentID, childID, relationTypeID int, relationType, constraint string) {
+	f.typeRelations = append(f.typeRelations, map[string]interface{}{
+		"id":               len(f.typeRelations) + 1,
+		"parent_id":        parentID,
+		"child_id":         childID,
+		"relation_type_id": relationTypeID,
+		"constraint":       constraint,
+		"relation_type":    map[string]interface{}{"id": relationTypeID, "name": relationType},
+	})
+}
+
+// relationViews 构建视图列表响应
+func (f *fakeCMDB) relationViews() map[string]interface{} {
+	name2id := [][]interface{}{}
+	id := 1
+	for name := range f.views {
+		name2id = append(name2id, []interface{}{name, id})
+		id++
+	}
+	id2type := map[string]interface{}{}
+	for _, ciType := range f.ciTypes {
+		id2type[strconv.Itoa(ciType["id"].(int))] = ciType
+	}
+	return map[string]interface{}{
+		"views":   f.views,
+		"id2type": id2type,
+		"name2id": name2id,
+	}
+}
+
+// search 按类型过滤CI并分页返回，candidates为nil时在全部CI中查找
+func (f *fakeCMDB) search(types map[int]bool, candidates []int, query map[string][]string) map[string]interface{} {
+	var matched []*fakeCI
+	if candidates == nil {
+		for id := 1; id <= f.maxID(); id++ {
+			if ci, ok := f.cis[id]; ok && types[ci.Type] {
+				matched = append(matched, ci)
+			}
+		}
+	} else {
+		for _, id := range candidates {
+			if ci, ok := f.cis[id]; ok && types[ci.Type] {
+				matched = append(matched, ci)
+			}
+		}
+	}
+
+	result, page := paginateCIs(matched, query)
+	return map[string]interface{}{
+		"result":   result,
+		"numfound": len(matched),
+		"total":    len(result),
+		"page":     page,
+	}
+}
+
+// relatedCIs 返回CI的子CI（second_cis，可按relation_type过滤）或父CI（first_cis）
+func (f *fakeCMDB) relatedCIs(ciID int, direction string, query map[string][]string) map[string]interface{} {
+	var matched []*fakeCI
+	if direction == "second_cis" {
+		relationType := first(query["relation_type"])
+		for _, child := range f.children[ciID] {
+			if relationType == "" || slices.Contains(f.relationTypes[[2]int{ciID, child}], relationType) {
+				matched = append(matched, f.cis[child])
+			}
+		}
+	} else {
+		for id := 1; id <= f.maxID(); id++ {
+			for _, child := range f.children[id] {
+				if child == ciID {
+					matched = append(matched, f.cis[id])
+				}
+			}
+		}
+	}
+
+	result, page := paginateCIs(matched, query)
+	return map[string]interface{}{
+		direction:  result,
+		"numfound": len(matched),
+		"total":    len(result),
+		"page":     page,
+	}
+}
+
+// paginateCIs 按page和count参数截取一页CI，返回该页CI和页码
+func paginateCIs(matched []*fakeCI, query map[string][]string) ([]map[string]interface{}, int) {
+	page, _ := strconv.Atoi(first(query["page"]))
+	count, _ := strconv.Atoi(first(query["count"]))
+	if page < 1 {
+		page = 1
+	}
+	if count < 1 {
+		count = len(matched)
+	}
+
+	result := []map[string]interface{}{}
+	for i := (page - 1) * count; i < page*count && i < len(matched); i++ {
+		ci := matched[i]
+		item := map[string]interface{}{"_id": ci.ID, "_type": ci.Type, "name": ci.Name}
+		for k, v := range ci.Attrs {
+			item[k] = v
+		}
+		result = append(result, item)
+	}
+	return result, page
+}
+
+// relationTree 按 search/full 的格式返回CI下level层以内的关系树，typeIDs[i]为第i+1层的类型，
+// filter为true时过滤无权限的CI；子节点按与ci_relations/s相反的顺序返回
+func (f *fakeCMDB) relationTree(id int, typeIDs []int, lv, level int, filter bool) map[string]interface{} {
+	ci := f.cis[id]
+	children := []interface{}{}
+	if lv < level {
+		for i := len(f.children[id]) - 1; i >= 0; i-- {
+			child := f.children[id][i]
+			if lv < len(typeIDs) && f.cis[child].Type == typeIDs[lv] && (!filter || !f.restricted[child]) {
+				children = append(children, f.relationTree(child, typeIDs, lv+1, level, filter))
+			}
+		}
+	}
+	return map[string]interface{}{
+		"id":          ci.ID,
+		"type_id":     ci.Type,
+		"isLeaf":      len(children) == 0,
+		"title":       ci.Name,
+		"uniqueValue": ci.Name,
+		"children":    children,
+	}
+}
+
+// descendantsAt 返回距CI恰好level层的后代，多条路径到达的CI各计一次
+func (f *fakeCMDB) descendantsAt(id, level int) []int {
+	if level <= 0 {
+		return []int{id}
+	}
+	var result []int
+	for _, child := range f.children[id] {
+		result = append(result, f.descendantsAt(child, level-1)...)
+	}
+	return result
+}
+
+// maxID 返回最大的CI ID
+func (f *fakeCMDB) maxID() int {
+	max := 0
+	for id := range f.cis {
+		if id > max {
+			max = id
+		}
+	}
+	return max
+}
+
+// parseQuery 解析 _type:(1;2),_id:(3;4) 查询，没有 _id 条件时返回的ids为nil
+func parseQuery(q string) (map[int]bool, []int) {
+	types := make(map[int]bool)
+	var ids []int
+	for _, clause := range strings.Split(q, ",") {
+		name, value, ok := strings.Cut(clause, ":(")
+		if !ok {
+			continue
+		}
+		if name == "_id" && ids == nil {
+			ids = []int{}
+		}
+		for _, part := range strings.Split(strings.TrimSuffix(value, ")"), ";") {
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "_type":
+				types[id] = true
+			case "_id":
+				ids = append(ids, id)
+			}
+		}
+	}
+	return types, ids
+}
+
+// parseTypeAttributesEndpoint 解析 ci_types/<id>/attributes 端点中的类型ID
+func parseTypeAttributesEndpoint(endpoint string) (int, bool) {
+	if !strings.HasPrefix(endpoint, "ci_types/") || !strings.HasSuffix(endpoint, "/attributes") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(endpoint, "ci_types/"), "/attributes"))
+	return id, err == nil
+}
+
+// parseCIRelationsEndpoint 解析 ci_relations/<id>/second_cis 和 ci_relations/<id>/first_cis 端点
+func parseCIRelationsEndpoint(endpoint string) (int, string, bool) {
+	parts := strings.Split(endpoint, "/")
+	if len(parts) != 3 || parts[0] != "ci_relations" || (parts[2] != "second_cis" && parts[2] != "first_cis") {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(parts[1])
+	return id, parts[2], err == nil
+}
+
+// parseIntList 解析逗号分隔的整数列表
+func parseIntList(value string) []int {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// first 返回第一个值
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// simpleView 构建三层测试视图：产品线(1) > 产品(2) > 环境(3)
+func simpleView() map[string]interface{} {
+	return map[string]interface{}{
+		"topo":         [][]int{{1}, {2}, {3}},
+		"topo_flatten": []int{1, 2, 3},
+		"leaf":         []int{3},
+	}
+}
+
+// seedSimpleTree 填充两棵产品线子树
+func seedSimpleTree(f *fakeCMDB) {
+	f.addView("product", simpleView())
+	f.addCI(1, 1, "line-a", 0)
+	f.addCI(2, 1, "line-b", 0)
+	f.addCI(10, 2, "product-a1", 1)
+	f.addCI(11, 2, "product-b1", 2)
+	f.addCI(100, 3, "env-a1", 10)
+	f.addCI(101, 3, "env-b1", 11)
+}
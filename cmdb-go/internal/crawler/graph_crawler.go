@@ -0,0 +1,515 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/client"
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// GraphCrawler CI关系图爬取器，从种子CI出发沿 second_cis/first_cis 广度优先遍历，
+// 不受服务树视图Topo层级限制，多对多关系和多个父节点都会保留为独立的边
+type GraphCrawler struct {
+	client          *client.CMDBClient
+	logger          *zap.Logger
+	pageSize        int
+	maxWorkers      int
+	requestInterval time.Duration
+	// retry 爬取器层面的重试策略，只重试客户端自身不重试的临时错误
+	retry client.RetryPolicy
+	// 最大跳数，-1表示无限制
+	maxDepth int
+	// 最大节点数，0表示无限制
+	maxNodes  int
+	direction string
+}
+
+// NewGraphCrawler 创建关系图爬取器
+func NewGraphCrawler(client *client.CMDBClient, logger *zap.Logger) *GraphCrawler {
+	return &GraphCrawler{
+		client:          client,
+		logger:          logger,
+		pageSize:        1000,
+		maxWorkers:      10,
+		requestInterval: 100 * time.Millisecond,
+		retry:           defaultRetryPolicy(),
+		maxDepth:        3,
+		maxNodes:        10000,
+		direction:       models.GraphDirectionBoth,
+	}
+}
+
+// SetPageSize 设置分页大小
+func (c *GraphCrawler) SetPageSize(size int) *GraphCrawler {
+	c.pageSize = size
+	return c
+}
+
+// SetMaxWorkers 设置同一层内同时展开的节点数
+func (c *GraphCrawler) SetMaxWorkers(workers int) *GraphCrawler {
+	c.maxWorkers = workers
+	return c
+}
+
+// SetRetryPolicy 设置爬取器层面的重试策略，客户端已按同一策略重试的错误不会再次重试
+func (c *GraphCrawler) SetRetryPolicy(policy client.RetryPolicy) *GraphCrawler {
+	c.retry = policy
+	return c
+}
+
+// SetRequestInterval 设置每个协程的请求间隔，客户端已启用限流时设为0，避免两者叠加降低速率
+func (c *GraphCrawler) SetRequestInterval(interval time.Duration) *GraphCrawler {
+	c.requestInterval = interval
+	return c
+}
+
+// SetMaxDepth 设置从种子出发的最大跳数，-1表示无限制
+func (c *GraphCrawler) SetMaxDepth(depth int) *GraphCrawler {
+	c.maxDepth = depth
+	return c
+}
+
+// SetMaxNodes 设置最大节点数，0表示无限制
+func (c *GraphCrawler) SetMaxNodes(nodes int) *GraphCrawler {
+	c.maxNodes = nodes
+	return c
+}
+
+// SetDirection 设置遍历方向：down、up 或 both
+func (c *GraphCrawler) SetDirection(direction string) *GraphCrawler {
+	c.direction = direction
+	return c
+}
+
+// ValidateGraphDirection 检查遍历方向是否合法
+func ValidateGraphDirection(direction string) error {
+	switch direction {
+	case models.GraphDirectionDown, models.GraphDirectionUp, models.GraphDirectionBoth:
+		return nil
+	default:
+		return fmt.Errorf("invalid graph direction %q, expected down, up or both", direction)
+	}
+}
+
+// graphLink 展开节点时发现的相邻CI及连接它们的边
+type graphLink struct {
+	ci   models.CIInstance
+	edge models.CIGraphEdge
+}
+
+// typePair 父子CI类型对
+type typePair struct {
+	parent int
+	child  int
+}
+
+// relationIndex 按父子类型索引的类型关系，用于推断CI关系的关系类型和约束
+type relationIndex struct {
+	pairs map[typePair][]models.CITypeRelation
+	// 每个父类型下出现的关系类型名称
+	childNames map[int][]string
+}
+
+// newRelationIndex 构建类型关系索引
+func newRelationIndex(relations []models.CITypeRelation) *relationIndex {
+	index := &relationIndex{
+		pairs:      make(map[typePair][]models.CITypeRelation),
+		childNames: make(map[int][]string),
+	}
+	seen := make(map[int]map[string]bool)
+	for _, relation := range relations {
+		pair := typePair{parent: relation.ParentID, child: relation.ChildID}
+		index.pairs[pair] = append(index.pairs[pair], relation)
+
+		name := relationTypeName(relation)
+		if seen[relation.ParentID] == nil {
+			seen[relation.ParentID] = make(map[string]bool)
+		}
+		if name != "" && !seen[relation.ParentID][name] {
+			seen[relation.ParentID][name] = true
+			index.childNames[relation.ParentID] = append(index.childNames[relation.ParentID], name)
+		}
+	}
+	return index
+}
+
+// lookup 查找父子类型之间唯一的类型关系，不存在或存在多个时返回false
+func (r *relationIndex) lookup(parent, child int) (models.CITypeRelation, bool) {
+	relations := r.pairs[typePair{parent: parent, child: child}]
+	if len(relations) != 1 {
+		return models.CITypeRelation{}, false
+	}
+	return relations[0], true
+}
+
+// find 按关系类型名称查找父子类型之间的类型关系
+func (r *relationIndex) find(parent, child int, name string) (models.CITypeRelation, bool) {
+	for _, relation := range r.pairs[typePair{parent: parent, child: child}] {
+		if relationTypeName(relation) == name {
+			return relation, true
+		}
+	}
+	return models.CITypeRelation{}, false
+}
+
+// ambiguous 判断父类型是否与某个子类型之间存在多种关系，此时只能按关系类型分别查询子CI
+func (r *relationIndex) ambiguous(parent int) bool {
+	for pair, relations := range r.pairs {
+		if pair.parent == parent && len(relations) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// relationTypeName 返回类型关系的关系类型名称
+func relationTypeName(relation models.CITypeRelation) string {
+	if relation.RelationType == nil {
+		return ""
+	}
+	return relation.RelationType.Name
+}
+
+// newGraphEdge 构建边，relation为nil时边没有关系类型
+func newGraphEdge(source, target int, relation *models.CITypeRelation) models.CIGraphEdge {
+	edge := models.CIGraphEdge{Source: source, Target: target}
+	if relation != nil {
+		edge.RelationType = relationTypeName(*relation)
+		edge.Constraint = relation.Constraint
+	}
+	return edge
+}
+
+// edgeKey 边的唯一键，同一对CI之间不同关系类型的边分别保留
+type edgeKey struct {
+	source       int
+	target       int
+	relationType string
+}
+
+// edgeSet 去重后的边集合
+type edgeSet struct {
+	edges map[edgeKey]*models.CIGraphEdge
+	// typed 每对CI之间已有关系类型的边数
+	typed map[[2]int]int
+}
+
+// newEdgeSet 创建边集合
+func newEdgeSet() *edgeSet {
+	return &edgeSet{
+		edges: make(map[edgeKey]*models.CIGraphEdge),
+		typed: make(map[[2]int]int),
+	}
+}
+
+// add 添加边，返回新增的边，已存在时返回nil
+// 向上发现的边可能无法确定关系类型，这种边只在同一对CI之间没有其他边时保留，之后发现的有类型的边会补全它而不是另加一条
+func (s *edgeSet) add(edge models.CIGraphEdge) *models.CIGraphEdge {
+	pair := [2]int{edge.Source, edge.Target}
+	untypedKey := edgeKey{source: edge.Source, target: edge.Target}
+
+	if edge.RelationType == "" {
+		if s.typed[pair] > 0 || s.edges[untypedKey] != nil {
+			return nil
+		}
+		s.edges[untypedKey] = &edge
+		return &edge
+	}
+
+	key := edgeKey{source: edge.Source, target: edge.Target, relationType: edge.RelationType}
+	if _, exists := s.edges[key]; exists {
+		return nil
+	}
+	s.typed[pair]++
+
+	if existing, ok := s.edges[untypedKey]; ok {
+		delete(s.edges, untypedKey)
+		existing.RelationType = edge.RelationType
+		existing.Constraint = edge.Constraint
+		s.edges[key] = existing
+		return nil
+	}
+
+	s.edges[key] = &edge
+	return &edge
+}
+
+// CrawlGraph 以seedQuery搜索到的CI为种子，逐层广度优先展开关系图
+// 每层内节点并发展开（并发数受 maxWorkers 限制），层与层之间同步，保证节点深度为最短跳数；
+// 达到 maxDepth 或 maxNodes 时停止。单个节点展开失败时记录错误并继续；
+// 上下文被取消时返回已获取的部分图和上下文错误
+func (c *GraphCrawler) CrawlGraph(ctx context.Context, seedQuery string) (*models.CIGraph, error) {
+	if err := ValidateGraphDirection(c.direction); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Starting to crawl CI relation graph",
+		zap.String("seed_query", seedQuery),
+		zap.String("direction", c.direction),
+		zap.Int("max_depth", c.maxDepth),
+		zap.Int("max_nodes", c.maxNodes))
+
+	typesResp, err := c.client.GetCITypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI types: %w", err)
+	}
+	typeNames := make(map[int]string, len(typesResp.CITypes))
+	for _, ciType := range typesResp.CITypes {
+		typeNames[ciType.ID] = ciType.DisplayName()
+	}
+
+	relationsResp, err := c.client.GetCITypeRelations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI type relations: %w", err)
+	}
+	index := newRelationIndex(relationsResp.Relations)
+
+	seedsResp, err := c.client.SearchAllCI(ctx, seedQuery, c.pageSize, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search seed CIs: %w", err)
+	}
+
+	graph := &models.CIGraph{
+		SeedQuery: seedQuery,
+		Seeds:     []int{},
+		Direction: c.direction,
+		MaxDepth:  c.maxDepth,
+		Nodes:     []*models.CIGraphNode{},
+		Edges:     []*models.CIGraphEdge{},
+		CrawledAt: time.Now(),
+	}
+	nodes := make(map[int]*models.CIGraphNode)
+	edges := newEdgeSet()
+
+	addNode := func(ci models.CIInstance, depth int) *models.CIGraphNode {
+		if c.maxNodes > 0 && len(graph.Nodes) >= c.maxNodes {
+			graph.Truncated = true
+			return nil
+		}
+		typeName := typeNames[ci.Type]
+		if typeName == "" {
+			typeName = ci.TypeName
+		}
+		node := &models.CIGraphNode{
+			ID:         ci.ID,
+			Type:       ci.Type,
+			TypeName:   typeName,
+			Name:       ci.GetDisplayName(),
+			Depth:      depth,
+			Attributes: ci.Attrs,
+		}
+		nodes[ci.ID] = node
+		graph.Nodes = append(graph.Nodes, node)
+		return node
+	}
+
+	var frontier []*models.CIGraphNode
+	for _, ci := range seedsResp.Result {
+		if _, exists := nodes[ci.ID]; exists {
+			continue
+		}
+		node := addNode(ci, 0)
+		if node == nil {
+			break
+		}
+		graph.Seeds = append(graph.Seeds, node.ID)
+		frontier = append(frontier, node)
+	}
+
+	if len(graph.Seeds) == 0 {
+		c.logger.Warn("No seed CIs matched query", zap.String("seed_query", seedQuery))
+	}
+
+	// 认证失败时取消其余节点的展开
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
+	failed := 0
+	for depth := 0; len(frontier) > 0 && (c.maxDepth < 0 || depth < c.maxDepth); depth++ {
+		links, levelFailed := c.expandLevel(ctx, abort, frontier, index)
+		failed += levelFailed
+
+		// 按frontier顺序合并，保证结果与并发顺序无关
+		var next []*models.CIGraphNode
+		for _, nodeLinks := range links {
+			for _, link := range nodeLinks {
+				if _, exists := nodes[link.ci.ID]; !exists {
+					node := addNode(link.ci, depth+1)
+					if node == nil {
+						continue
+					}
+					next = append(next, node)
+				}
+
+				if edge := edges.add(link.edge); edge != nil {
+					graph.Edges = append(graph.Edges, edge)
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		c.logger.Debug("Expanded graph level",
+			zap.Int("depth", depth),
+			zap.Int("frontier", len(frontier)),
+			zap.Int("new_nodes", len(next)),
+			zap.Int("total_nodes", len(graph.Nodes)))
+
+		frontier = next
+	}
+
+	if cause := context.Cause(ctx); isAbortError(cause) {
+		c.logger.Error("CI graph crawl aborted", zap.Error(cause))
+		return nil, cause
+	}
+
+	if err := ctx.Err(); err != nil {
+		c.logger.Warn("CI graph crawl cancelled",
+			zap.Int("nodes", len(graph.Nodes)),
+			zap.Int("edges", len(graph.Edges)),
+			zap.Error(err))
+		return graph, err
+	}
+
+	if failed > 0 {
+		c.logger.Warn("Some CIs failed to expand", zap.Int("error_count", failed))
+	}
+	if graph.Truncated {
+		c.logger.Warn("CI graph truncated at max nodes", zap.Int("max_nodes", c.maxNodes))
+	}
+
+	c.logger.Info("Completed crawling CI relation graph",
+		zap.Int("seeds", len(graph.Seeds)),
+		zap.Int("nodes", len(graph.Nodes)),
+		zap.Int("edges", len(graph.Edges)))
+
+	return graph, nil
+}
+
+// expandLevel 并发展开同一层的节点，返回与frontier一一对应的相邻CI和失败数
+// 临时错误重试，需要中止的错误通过abort取消整次爬取
+func (c *GraphCrawler) expandLevel(ctx context.Context, abort context.CancelCauseFunc, frontier []*models.CIGraphNode,
+	index *relationIndex) ([][]graphLink, int) {
+	links := make([][]graphLink, len(frontier))
+	errs := make([]error, len(frontier))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, c.maxWorkers)
+
+	for i, node := range frontier {
+		wg.Add(1)
+		go func(i int, node *models.CIGraphNode) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+
+			errs[i] = withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "expand CI", func() (err error) {
+				links[i], err = c.expandNode(ctx, node, index)
+				return err
+			})
+			if isAbortError(errs[i]) {
+				abort(errs[i])
+			}
+		}(i, node)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if ctx.Err() == nil {
+			c.logger.Error("Failed to expand CI",
+				zap.Int("ci_id", frontier[i].ID),
+				zap.Error(err))
+		}
+	}
+	return links, failed
+}
+
+// expandNode 按遍历方向获取节点的子CI和父CI
+func (c *GraphCrawler) expandNode(ctx context.Context, node *models.CIGraphNode, index *relationIndex) ([]graphLink, error) {
+	var links []graphLink
+
+	if c.direction != models.GraphDirectionUp {
+		children, err := c.expandDown(ctx, node, index)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, children...)
+	}
+
+	if c.direction != models.GraphDirectionDown {
+		if err := client.SleepContext(ctx, c.requestInterval); err != nil {
+			return links, err
+		}
+		parents, err := c.client.GetAllFirstCIs(ctx, node.ID, c.pageSize)
+		if err != nil {
+			return links, err
+		}
+		for _, parent := range parents {
+			var relation *models.CITypeRelation
+			if r, ok := index.lookup(parent.Type, node.Type); ok {
+				relation = &r
+			}
+			links = append(links, graphLink{ci: parent, edge: newGraphEdge(parent.ID, node.ID, relation)})
+		}
+	}
+
+	return links, nil
+}
+
+// expandDown 获取节点的子CI；父类型与同一子类型之间有多种关系时按关系类型分别查询以确定边的类型
+func (c *GraphCrawler) expandDown(ctx context.Context, node *models.CIGraphNode, index *relationIndex) ([]graphLink, error) {
+	var links []graphLink
+
+	if !index.ambiguous(node.Type) {
+		if err := client.SleepContext(ctx, c.requestInterval); err != nil {
+			return nil, err
+		}
+		children, err := c.client.GetAllSecondCIs(ctx, node.ID, "", c.pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			var relation *models.CITypeRelation
+			if r, ok := index.lookup(node.Type, child.Type); ok {
+				relation = &r
+			}
+			links = append(links, graphLink{ci: child, edge: newGraphEdge(node.ID, child.ID, relation)})
+		}
+		return links, nil
+	}
+
+	for _, name := range index.childNames[node.Type] {
+		if err := client.SleepContext(ctx, c.requestInterval); err != nil {
+			return nil, err
+		}
+		children, err := c.client.GetAllSecondCIs(ctx, node.ID, name, c.pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			edge := models.CIGraphEdge{Source: node.ID, Target: child.ID, RelationType: name}
+			if relation, ok := index.find(node.Type, child.Type, name); ok {
+				edge.Constraint = relation.Constraint
+			}
+			links = append(links, graphLink{ci: child, edge: edge})
+		}
+	}
+	return links, nil
+}
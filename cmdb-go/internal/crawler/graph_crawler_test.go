@@ -0,0 +1,202 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// seedGraph 构建关系图测试数据：
+// 产品(1) -contain-> 应用(10, 11)；应用 -deploy/connect-> 服务器(100, 101)；服务器 -run-> 数据库(1000)
+// 服务器100同时部署了两个应用，应用与服务器之间有两种关系类型
+func seedGraph(f *fakeCMDB) {
+	f.addCIType(1, "product", "产品")
+	f.addCIType(2, "app", "应用")
+	f.addCIType(3, "server", "服务器")
+	f.addCIType(4, "db", "数据库")
+	f.addTypeRelation(1, 2, 1, "contain", models.ConstraintOneToMany)
+	f.addTypeRelation(2, 3, 2, "deploy", models.ConstraintManyToMany)
+	f.addTypeRelation(2, 3, 3, "connect", models.ConstraintOneToMany)
+	f.addTypeRelation(3, 4, 4, "run", models.ConstraintOneToOne)
+
+	f.addCI(1, 1, "shop", 0)
+	f.addCI(10, 2, "order-service", 0)
+	f.addCI(11, 2, "pay-service", 0)
+	f.addCI(100, 3, "server-1", 0)
+	f.addCI(101, 3, "server-2", 0)
+	f.addCI(1000, 4, "mysql", 0)
+	f.addRelation(1, 10, "contain")
+	f.addRelation(1, 11, "contain")
+	f.addRelation(10, 100, "deploy")
+	f.addRelation(11, 100, "deploy")
+	f.addRelation(10, 101, "connect")
+	f.addRelation(100, 1000, "run")
+}
+
+// newGraphCrawler 创建指向测试服务器的关系图爬取器
+func (f *fakeCMDB) newGraphCrawler() *GraphCrawler {
+	return NewGraphCrawler(f.newClient(), zap.NewNop()).
+		SetRequestInterval(0).
+		SetPageSize(100)
+}
+
+// edgeMap 按 [父CI, 子CI] 索引边
+func edgeMap(graph *models.CIGraph) map[[2]int]*models.CIGraphEdge {
+	edges := make(map[[2]int]*models.CIGraphEdge, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		edges[[2]int{edge.Source, edge.Target}] = edge
+	}
+	return edges
+}
+
+// TestCrawlGraphDown 测试向下遍历的深度限制、多父节点去重和边的关系类型
+func TestCrawlGraphDown(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedGraph(f)
+
+	graph, err := f.newGraphCrawler().
+		SetDirection(models.GraphDirectionDown).
+		SetMaxDepth(2).
+		CrawlGraph(context.Background(), "_type:(1)")
+	if err != nil {
+		t.Fatalf("CrawlGraph failed: %v", err)
+	}
+
+	if len(graph.Seeds) != 1 || graph.Seeds[0] != 1 {
+		t.Fatalf("Expected seed 1, got %v", graph.Seeds)
+	}
+	// 数据库距种子3跳，超出深度限制
+	if len(graph.Nodes) != 5 {
+		t.Fatalf("Expected 5 nodes within 2 hops, got %d", len(graph.Nodes))
+	}
+	depths := make(map[int]int)
+	for _, node := range graph.Nodes {
+		depths[node.ID] = node.Depth
+	}
+	if depths[100] != 2 || depths[10] != 1 {
+		t.Errorf("Unexpected node depths: %v", depths)
+	}
+	if _, ok := depths[1000]; ok {
+		t.Error("Expected node beyond max depth to be excluded")
+	}
+
+	edges := edgeMap(graph)
+	if len(graph.Edges) != 5 {
+		t.Fatalf("Expected 5 edges, got %d", len(graph.Edges))
+	}
+	expected := map[[2]int]models.CIGraphEdge{
+		{1, 10}:   {RelationType: "contain", Constraint: models.ConstraintOneToMany},
+		{10, 100}: {RelationType: "deploy", Constraint: models.ConstraintManyToMany},
+		{11, 100}: {RelationType: "deploy", Constraint: models.ConstraintManyToMany},
+		{10, 101}: {RelationType: "connect", Constraint: models.ConstraintOneToMany},
+	}
+	for key, want := range expected {
+		edge, ok := edges[key]
+		if !ok {
+			t.Errorf("Expected edge %v", key)
+			continue
+		}
+		if edge.RelationType != want.RelationType || edge.Constraint != want.Constraint {
+			t.Errorf("Edge %v: expected %s/%s, got %s/%s", key, want.RelationType, want.Constraint, edge.RelationType, edge.Constraint)
+		}
+	}
+
+	// 应用与服务器之间有两种关系类型，按关系类型分别查询
+	if got := f.requestCount("ci_relations/10/second_cis"); got != 2 {
+		t.Errorf("Expected 2 filtered second_cis requests for app 10, got %d", got)
+	}
+	if got := f.requestCount("ci_relations/1/first_cis"); got != 0 {
+		t.Errorf("Expected no first_cis requests when crawling down, got %d", got)
+	}
+}
+
+// TestCrawlGraphBoth 测试双向遍历：向上发现的边关系类型不确定时由向下查询补全
+func TestCrawlGraphBoth(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedGraph(f)
+
+	graph, err := f.newGraphCrawler().
+		SetMaxDepth(-1).
+		CrawlGraph(context.Background(), "_type:(4)")
+	if err != nil {
+		t.Fatalf("CrawlGraph failed: %v", err)
+	}
+
+	if len(graph.Nodes) != 6 || len(graph.Edges) != 6 {
+		t.Fatalf("Expected the whole graph with 6 nodes and 6 edges, got %d nodes and %d edges", len(graph.Nodes), len(graph.Edges))
+	}
+	if graph.Truncated {
+		t.Error("Expected graph not to be truncated")
+	}
+
+	edges := edgeMap(graph)
+	if edge := edges[[2]int{100, 1000}]; edge == nil || edge.RelationType != "run" {
+		t.Errorf("Expected run edge from server to db, got %+v", edge)
+	}
+	if edge := edges[[2]int{11, 100}]; edge == nil || edge.RelationType != "deploy" {
+		t.Errorf("Expected ambiguous upward edge to be typed by downward lookup, got %+v", edge)
+	}
+}
+
+// TestCrawlGraphMultipleRelationTypes 测试同一对CI之间的多种关系分别保留为独立的边
+func TestCrawlGraphMultipleRelationTypes(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedGraph(f)
+	f.addRelation(10, 100, "connect")
+
+	// 双向遍历时先从服务器向上发现无类型的边，再由应用向下查询补全
+	for _, direction := range []string{models.GraphDirectionDown, models.GraphDirectionBoth} {
+		seed := "_type:(1)"
+		if direction == models.GraphDirectionBoth {
+			seed = "_type:(4)"
+		}
+		graph, err := f.newGraphCrawler().
+			SetDirection(direction).
+			SetMaxDepth(-1).
+			CrawlGraph(context.Background(), seed)
+		if err != nil {
+			t.Fatalf("%s: CrawlGraph failed: %v", direction, err)
+		}
+
+		types := make(map[string]string)
+		for _, edge := range graph.Edges {
+			if edge.Source == 10 && edge.Target == 100 {
+				types[edge.RelationType] = edge.Constraint
+			}
+		}
+		expected := map[string]string{"deploy": models.ConstraintManyToMany, "connect": models.ConstraintOneToMany}
+		if len(types) != len(expected) || types["deploy"] != expected["deploy"] || types["connect"] != expected["connect"] {
+			t.Errorf("%s: expected deploy and connect edges from 10 to 100, got %v", direction, types)
+		}
+		if len(graph.Edges) != 7 {
+			t.Errorf("%s: expected 7 edges, got %d", direction, len(graph.Edges))
+		}
+	}
+}
+
+// TestCrawlGraphMaxNodes 测试节点数上限
+func TestCrawlGraphMaxNodes(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedGraph(f)
+
+	graph, err := f.newGraphCrawler().
+		SetDirection(models.GraphDirectionDown).
+		SetMaxDepth(-1).
+		SetMaxNodes(3).
+		CrawlGraph(context.Background(), "_type:(1)")
+	if err != nil {
+		t.Fatalf("CrawlGraph failed: %v", err)
+	}
+
+	if !graph.Truncated || len(graph.Nodes) != 3 {
+		t.Errorf("Expected truncated graph with 3 nodes, got truncated=%v with %d nodes", graph.Truncated, len(graph.Nodes))
+	}
+	for _, edge := range graph.Edges {
+		if edge.Target == 100 || edge.Target == 101 {
+			t.Errorf("Expected no edges to nodes beyond the limit, got %+v", edge)
+		}
+	}
+}
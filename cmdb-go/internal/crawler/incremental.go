@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// historyClockSkew 查询变更历史时向前多取的时间，避免客户端与服务端时钟偏差漏掉变更
+const historyClockSkew = time.Minute
+
+// incrementalBaseline 增量爬取的基线数据
+type incrementalBaseline struct {
+	configs map[string]models.ServiceTreeView
+	roots   map[string]map[int]*models.ServiceTreeNode
+	changed map[int]bool
+}
+
+// newIncrementalBaseline 根据上次的爬取结果和变更的CI构建基线
+func newIncrementalBaseline(previous []*models.ServiceTreeData, changed map[int]bool) *incrementalBaseline {
+	baseline := &incrementalBaseline{
+		configs: make(map[string]models.ServiceTreeView),
+		roots:   make(map[string]map[int]*models.ServiceTreeNode),
+		changed: changed,
+	}
+
+	for _, tree := range previous {
+		baseline.configs[tree.ViewName] = tree.Config
+		roots := make(map[int]*models.ServiceTreeNode, len(tree.RootNodes))
+		for _, root := range tree.RootNodes {
+			roots[root.ID] = root
+		}
+		baseline.roots[tree.ViewName] = roots
+	}
+
+	return baseline
+}
+
+// reusableSubtree 返回可以直接复用的上次子树，子树内任一CI发生变更或视图配置变化时返回false
+func (b *incrementalBaseline) reusableSubtree(viewName string, viewConfig models.ServiceTreeView,
+	node *models.ServiceTreeNode) (*models.ServiceTreeNode, bool) {
+
+	prevConfig, ok := b.configs[viewName]
+	if !ok || !sameTopology(prevConfig, viewConfig) {
+		return nil, false
+	}
+
+	prev, ok := b.roots[viewName][node.ID]
+	if !ok || prev.Type != node.Type || b.subtreeChanged(prev) {
+		return nil, false
+	}
+
+	return prev, true
+}
+
+// subtreeChanged 判断子树中是否有CI发生变更
+func (b *incrementalBaseline) subtreeChanged(node *models.ServiceTreeNode) bool {
+	if b.changed[node.ID] {
+		return true
+	}
+	for _, child := range node.Children {
+		if b.subtreeChanged(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameTopology 判断两个视图配置的层级结构是否一致，层级约束和叶子展示类型同样决定树的结构
+func sameTopology(a, b models.ServiceTreeView) bool {
+	return reflect.DeepEqual(a.Topo, b.Topo) &&
+		reflect.DeepEqual(a.TopoFlatten, b.TopoFlatten) &&
+		reflect.DeepEqual(a.Leaf, b.Leaf) &&
+		reflect.DeepEqual(a.Level2Constraint, b.Level2Constraint) &&
+		reflect.DeepEqual(a.Leaf2ShowTypes, b.Leaf2ShowTypes) &&
+		reflect.DeepEqual(a.Node2ShowTypes, b.Node2ShowTypes)
+}
+
+// crawlOptions 返回当前影响服务树内容的爬取选项
+func (c *ServiceTreeCrawler) crawlOptions() *models.CrawlOptions {
+	return &models.CrawlOptions{
+		MaxDepth:     c.maxDepth,
+		IncludeStats: c.includeStats,
+		IncludeM2M:   c.includeM2M,
+	}
+}
+
+// sameOptions 判断快照的爬取选项是否与当前一致，没有记录选项的旧快照视为不一致
+func sameOptions(previous, current *models.CrawlOptions) bool {
+	return previous != nil && current != nil && *previous == *current
+}
+
+// CrawlIncremental 增量爬取服务树
+// 查询上次爬取时间之后的属性和关系变更，只重新爬取包含变更CI的根节点子树，其余子树复用上次结果
+func (c *ServiceTreeCrawler) CrawlIncremental(ctx context.Context, previous []*models.ServiceTreeData,
+	targetViews []string) ([]*models.ServiceTreeData, error) {
+
+	if len(previous) == 0 {
+		c.logger.Info("No previous snapshot, falling back to full crawl")
+		return c.CrawlSpecificViews(ctx, targetViews)
+	}
+
+	// 在查询历史之前记录时间，作为新快照的爬取时间，保证下次增量不会漏掉本次爬取期间的变更
+	startedAt := time.Now()
+
+	since := previous[0].CrawledAt
+	for _, tree := range previous[1:] {
+		if tree.CrawledAt.Before(since) {
+			since = tree.CrawledAt
+		}
+	}
+
+	c.logger.Info("Starting incremental crawl",
+		zap.Time("since", since),
+		zap.Int("previous_trees", len(previous)))
+
+	changed, err := c.client.GetChangedCIIDs(ctx, since.Add(-historyClockSkew), c.pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load change history: %w", err)
+	}
+
+	// 爬取选项不同的视图不能复用上次的子树，退化为全量爬取
+	options := c.crawlOptions()
+	reusable := make([]*models.ServiceTreeData, 0, len(previous))
+	for _, tree := range previous {
+		if !sameOptions(tree.Options, options) {
+			c.logger.Warn("Crawl options differ from previous snapshot, crawling view in full",
+				zap.String("view_name", tree.ViewName),
+				zap.Any("previous_options", tree.Options),
+				zap.Any("options", options))
+			continue
+		}
+		reusable = append(reusable, tree)
+	}
+
+	c.baseline = newIncrementalBaseline(reusable, changed)
+	defer func() { c.baseline = nil }()
+
+	results, err := c.CrawlSpecificViews(ctx, targetViews)
+	for _, tree := range results {
+		tree.CrawledAt = startedAt
+	}
+
+	return results, err
+}
@@ -0,0 +1,188 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestCrawlIncremental 测试增量爬取只重新爬取变更的子树
+func TestCrawlIncremental(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	// 逐个节点查询，便于按请求数确认重新爬取的子树
+	crawler := f.newCrawler().SetIncludeStats(false).SetBatchSize(1)
+	previous, err := crawler.CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Full crawl failed: %v", err)
+	}
+	if len(previous) != 1 || previous[0].TotalNodes != 6 {
+		t.Fatalf("Expected 1 tree with 6 nodes, got %+v", previous)
+	}
+
+	// line-a 下的环境节点被修改，并新增一个产品
+	f.changed = []int{100}
+	f.addCI(12, 2, "product-a2", 1)
+	before := f.requestCount("ci_relations/s")
+
+	trees, err := crawler.CrawlIncremental(context.Background(), previous, nil)
+	if err != nil {
+		t.Fatalf("Incremental crawl failed: %v", err)
+	}
+
+	// 只有line-a子树被重新爬取：line-a、product-a1、product-a2 各一次子节点查询
+	if got := f.requestCount("ci_relations/s") - before; got != 3 {
+		t.Errorf("Expected 3 relation requests for the changed subtree, got %d", got)
+	}
+
+	if trees[0].TotalNodes != 7 {
+		t.Errorf("Expected 7 nodes after incremental crawl, got %d", trees[0].TotalNodes)
+	}
+	if !trees[0].CrawledAt.After(previous[0].CrawledAt) {
+		t.Error("Expected incremental snapshot to have a newer crawl time")
+	}
+}
+
+// TestCrawlIncrementalPagesHistory 测试变更记录超过一页时读取所有页
+func TestCrawlIncrementalPagesHistory(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	// 每次变更占两行历史，一页2行只合并成1条记录
+	crawler := f.newCrawler().SetIncludeStats(false).SetPageSize(2)
+	previous, err := crawler.CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Full crawl failed: %v", err)
+	}
+
+	// 第二页的变更位于line-b子树，只读第一页时新增的环境节点会丢失
+	f.changed = []int{100, 101}
+	f.addCI(102, 3, "env-b2", 11)
+
+	trees, err := crawler.CrawlIncremental(context.Background(), previous, nil)
+	if err != nil {
+		t.Fatalf("Incremental crawl failed: %v", err)
+	}
+	if trees[0].TotalNodes != 7 {
+		t.Errorf("Expected 7 nodes after incremental crawl, got %d", trees[0].TotalNodes)
+	}
+	// 两页各2行合并为1条记录，第三页为空
+	if got := f.requestCount("history/records/attribute"); got != 3 {
+		t.Errorf("Expected 3 attribute history requests, got %d", got)
+	}
+}
+
+// TestCrawlIncrementalNoPrevious 测试没有上次快照时退化为全量爬取
+func TestCrawlIncrementalNoPrevious(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	trees, err := f.newCrawler().CrawlIncremental(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("Incremental crawl failed: %v", err)
+	}
+	if len(trees) != 1 || trees[0].TotalNodes != 6 {
+		t.Errorf("Expected full crawl with 6 nodes, got %+v", trees)
+	}
+	if f.requestCount("history/records/attribute") != 0 {
+		t.Error("Expected no history requests without a previous snapshot")
+	}
+}
+
+// TestCrawlIncrementalOptionsChanged 测试爬取选项与上次快照不同时不复用子树
+func TestCrawlIncrementalOptionsChanged(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	crawler := f.newCrawler().SetIncludeStats(false).SetBatchSize(1)
+	previous, err := crawler.CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Full crawl failed: %v", err)
+	}
+
+	// 选项相同且没有变更时全部复用
+	before := f.requestCount("ci_relations/s")
+	if _, err := crawler.CrawlIncremental(context.Background(), previous, nil); err != nil {
+		t.Fatalf("Incremental crawl failed: %v", err)
+	}
+	if got := f.requestCount("ci_relations/s") - before; got != 0 {
+		t.Errorf("Expected all subtrees to be reused, got %d relation requests", got)
+	}
+
+	// 上次只爬到第2层，这次不限深度，复用会丢掉环境节点
+	shallow, err := f.newCrawler().SetIncludeStats(false).SetBatchSize(1).SetMaxDepth(2).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Shallow crawl failed: %v", err)
+	}
+	core, logs := observer.New(zap.WarnLevel)
+	trees, err := NewServiceTreeCrawler(f.newClient(), zap.New(core)).SetRequestInterval(0).SetIncludeStats(false).
+		CrawlIncremental(context.Background(), shallow, nil)
+	if err != nil {
+		t.Fatalf("Incremental crawl failed: %v", err)
+	}
+	if trees[0].TotalNodes != 6 {
+		t.Errorf("Expected subtrees to be crawled in full with 6 nodes, got %d", trees[0].TotalNodes)
+	}
+	if logs.FilterMessage("Crawl options differ from previous snapshot, crawling view in full").Len() != 1 {
+		t.Error("Expected a warning about changed crawl options")
+	}
+}
+
+// TestCrawlIncrementalViewShapeChanged 测试视图的层级约束或叶子展示类型变化时不复用子树
+func TestCrawlIncrementalViewShapeChanged(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		nodes int
+	}{
+		// product-a1 下的两个环境在一对一约束下只保留第一个
+		{"level constraint", "level2constraint", map[string]string{"2": models.ConstraintOneToOne}, 6},
+		// 环境下的服务器作为叶子展示类型加入
+		{"leaf show types", "leaf2show_types", map[string][]int{"3": {4}}, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeCMDB(t)
+			seedSimpleTree(f)
+			f.addCI(102, 3, "env-a2", 10)
+			f.addCI(1000, 4, "server-1", 100)
+
+			crawler := f.newCrawler().SetIncludeStats(false)
+			previous, err := crawler.CrawlAllServiceTrees(context.Background())
+			if err != nil {
+				t.Fatalf("Full crawl failed: %v", err)
+			}
+			// 叶子节点下的服务器还不是展示类型，不在树中
+			if previous[0].TotalNodes != 7 {
+				t.Fatalf("Expected 7 nodes in the full crawl, got %d", previous[0].TotalNodes)
+			}
+
+			view := simpleView()
+			view[tt.key] = tt.value
+			f.addView("product", view)
+			trees, err := crawler.CrawlIncremental(context.Background(), previous, nil)
+			if err != nil {
+				t.Fatalf("Incremental crawl failed: %v", err)
+			}
+			if trees[0].TotalNodes != tt.nodes {
+				t.Errorf("Expected %d nodes after the view changed, got %d", tt.nodes, trees[0].TotalNodes)
+			}
+		})
+	}
+}
+
+// TestSameTopologyNodeShowTypes 测试节点展示类型不同的视图配置视为结构不同
+func TestSameTopologyNodeShowTypes(t *testing.T) {
+	a := models.ServiceTreeView{Topo: [][]int{{1}, {2}}, TopoFlatten: []int{1, 2}}
+	b := a
+	b.Node2ShowTypes = map[string][]models.CIType{"2": {{ID: 4, Name: "server"}}}
+	if !sameTopology(a, a) || sameTopology(a, b) {
+		t.Error("Expected node show types to be part of the view topology")
+	}
+}
@@ -0,0 +1,390 @@
+package crawler
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// ciIDQueryBatchSize 按ID批量查询CI属性时单次请求的最大ID数量，避免URL过长
+const ciIDQueryBatchSize = 100
+
+// frontierNode 待展开子节点的节点，ancestors为从根节点到父节点的CI ID，root为所属根节点的下标
+type frontierNode struct {
+	node      *models.ServiceTreeNode
+	ancestors []int
+	root      int
+}
+
+// levelTask 同一层中一批父节点的子节点查询，同一批父节点的类型和查询的子类型相同
+type levelTask struct {
+	parents []*frontierNode
+	// parentIDs 去重后的父节点ID，多对多关系下同一CI可能在本层出现多次
+	parentIDs  []int
+	parentSet  map[int]bool
+	childTypes []int
+	leaf       bool
+	constraint string
+	// descendantIDs 查询时传给服务端的descendant_ids，两种查询路径都需带上，否则过滤结果不同
+	descendantIDs []int
+	// withAncestors 多对多关系下按祖先路径查询，只能逐个节点请求
+	withAncestors bool
+
+	children map[int][]models.CIInstance
+	err      error
+}
+
+// crawlLevels 从根节点开始逐层展开服务树，同一层的父节点按类型分批合并查询，查询由共享的工作池并发执行
+// 每棵子树展开完成后记录检查点，返回子树爬取失败的根节点及其错误
+// 上下文取消、需要中止的请求错误或流式输出写入失败时返回错误，已展开的节点保留在树中
+func (c *ServiceTreeCrawler) crawlLevels(ctx context.Context, viewName string, rootNodes []*models.ServiceTreeNode,
+	pending []int, viewConfig models.ServiceTreeView, id2Type map[string]models.CIType, emitter *nodeEmitter) (map[int]error, error) {
+
+	failed := make(map[int]error)
+	frontier := make([]*frontierNode, 0, len(pending))
+	for _, i := range pending {
+		frontier = append(frontier, &frontierNode{node: rootNodes[i], root: i})
+	}
+
+	// remaining 每个根节点在当前层待展开的节点数，降为0时子树已完成
+	remaining := make(map[int]int)
+	for _, fn := range frontier {
+		remaining[fn.root]++
+	}
+
+	for level := 1; len(frontier) > 0; level++ {
+		if err := ctx.Err(); err != nil {
+			return failed, err
+		}
+		if c.maxDepth > 0 && level >= c.maxDepth {
+			break
+		}
+
+		tasks := c.planLevel(frontier, level, viewConfig)
+		c.runLevelTasks(ctx, tasks, viewConfig)
+
+		var next []*frontierNode
+		for _, task := range tasks {
+			if task.err != nil {
+				if ctx.Err() != nil {
+					return failed, ctx.Err()
+				}
+				// 认证失败时其余请求同样会失败，中止整次爬取
+				if isAbortError(task.err) {
+					return failed, task.err
+				}
+				c.logger.Error("Failed to crawl children",
+					zap.Int("level", level),
+					zap.Int("parent_count", len(task.parents)),
+					zap.Error(task.err))
+				for _, parent := range task.parents {
+					if _, ok := failed[parent.root]; !ok {
+						failed[parent.root] = fmt.Errorf("failed to crawl children for node %s: %w",
+							rootNodes[parent.root].Name, task.err)
+					}
+				}
+				continue
+			}
+
+			for _, parent := range task.parents {
+				children, err := c.attachLevelChildren(parent, task, id2Type, level, emitter)
+				if err != nil {
+					return failed, err
+				}
+				next = append(next, children...)
+			}
+		}
+
+		// 本层之后没有待展开节点的根节点子树已完成
+		nextRemaining := make(map[int]int)
+		for _, fn := range next {
+			nextRemaining[fn.root]++
+		}
+		for root := range remaining {
+			if nextRemaining[root] == 0 {
+				c.finishSubtree(viewName, rootNodes, root, failed)
+			}
+		}
+		remaining = nextRemaining
+		frontier = next
+	}
+
+	// 达到最大深度时剩余的子树同样已完成
+	for root := range remaining {
+		c.finishSubtree(viewName, rootNodes, root, failed)
+	}
+
+	return failed, nil
+}
+
+// finishSubtree 子树没有失败时记录到检查点
+func (c *ServiceTreeCrawler) finishSubtree(viewName string, rootNodes []*models.ServiceTreeNode, root int, failed map[int]error) {
+	if _, ok := failed[root]; ok {
+		return
+	}
+	c.recordSubtree(viewName, rootNodes[root])
+}
+
+// planLevel 将当前层待展开的节点按类型分组并按批大小拆分为查询任务
+// 叶子类型节点或超出视图定义的层级时，只展开叶子节点下展示的CI
+func (c *ServiceTreeCrawler) planLevel(frontier []*frontierNode, level int, viewConfig models.ServiceTreeView) []*levelTask {
+	batchSize := c.batchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	withAncestors := c.includeM2M || viewConfig.TreeHasM2M()
+
+	var tasks []*levelTask
+	open := make(map[string]*levelTask)
+	for _, fn := range frontier {
+		node := fn.node
+		leaf := level >= len(viewConfig.Topo) || viewConfig.IsLeafType(node.Type)
+
+		var childTypes []int
+		if leaf {
+			node.IsLeaf = true
+			childTypes = viewConfig.LeafShowTypes(node.Type)
+		} else {
+			childTypes = viewConfig.Topo[level]
+		}
+		if len(childTypes) == 0 {
+			node.IsLeaf = true
+			continue
+		}
+
+		// 多对多关系下同一CI在不同路径上的子节点可能不同，逐个节点带上祖先路径查询
+		if withAncestors && len(fn.ancestors) > 0 {
+			tasks = append(tasks, c.newLevelTask(fn, childTypes, leaf, level, viewConfig, true))
+			continue
+		}
+
+		key := fmt.Sprintf("%t:%d", leaf, node.Type)
+		task, ok := open[key]
+		if ok && (task.hasParent(node.ID) || len(task.parentIDs) < batchSize) {
+			task.addParent(fn)
+			continue
+		}
+		task = c.newLevelTask(fn, childTypes, leaf, level, viewConfig, false)
+		open[key] = task
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// newLevelTask 以第一个父节点创建查询任务
+func (c *ServiceTreeCrawler) newLevelTask(fn *frontierNode, childTypes []int, leaf bool, level int,
+	viewConfig models.ServiceTreeView, withAncestors bool) *levelTask {
+
+	task := &levelTask{
+		childTypes:    childTypes,
+		leaf:          leaf,
+		withAncestors: withAncestors,
+	}
+	task.addParent(fn)
+	if leaf {
+		task.constraint = viewConfig.LevelConstraint(len(viewConfig.TopoFlatten))
+	} else {
+		task.constraint = viewConfig.LevelConstraint(level)
+		if len(viewConfig.TopoFlatten) > level+1 {
+			task.descendantIDs = viewConfig.TopoFlatten[level+1:]
+		}
+	}
+	return task
+}
+
+// hasParent 判断任务中是否已包含该CI
+func (t *levelTask) hasParent(id int) bool {
+	return t.parentSet[id]
+}
+
+// addParent 将父节点加入任务
+func (t *levelTask) addParent(fn *frontierNode) {
+	if t.parentSet == nil {
+		t.parentSet = make(map[int]bool)
+	}
+	if !t.parentSet[fn.node.ID] {
+		t.parentSet[fn.node.ID] = true
+		t.parentIDs = append(t.parentIDs, fn.node.ID)
+	}
+	t.parents = append(t.parents, fn)
+}
+
+// runLevelTasks 由最多maxWorkers个工作协程执行一层的查询任务，上下文取消后未开始的任务记为取消
+func (c *ServiceTreeCrawler) runLevelTasks(ctx context.Context, tasks []*levelTask, viewConfig models.ServiceTreeView) {
+	workers := c.maxWorkers
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan *levelTask)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				task.err = withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "crawl children", func() error {
+					return c.fetchLevelTask(ctx, task, viewConfig)
+				})
+			}
+		}()
+	}
+
+dispatch:
+	for i, task := range tasks {
+		select {
+		case queue <- task:
+		case <-ctx.Done():
+			for _, skipped := range tasks[i:] {
+				skipped.err = ctx.Err()
+			}
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// fetchLevelTask 查询任务中每个父节点的子节点
+// 单个父节点使用ci_relations/s，多个父节点先通过ci_relations/search/full批量取父子对应关系，再按ID批量取CI属性
+func (c *ServiceTreeCrawler) fetchLevelTask(ctx context.Context, task *levelTask, viewConfig models.ServiceTreeView) error {
+	if len(task.parentIDs) == 1 {
+		return c.fetchNodeChildren(ctx, task, viewConfig)
+	}
+
+	parentIDs := task.parentIDs
+	parentType := task.parents[0].node.Type
+	hasM2M := c.includeM2M || viewConfig.HasM2M()
+
+	// 每次请求只能指定一个子类型，多个子类型分别查询
+	childIDs := make(map[int][]int)
+	var allIDs []int
+	seenChild := make(map[int]bool)
+	for _, childType := range task.childTypes {
+		relTree, err := c.client.SearchCIRelationFull(ctx, parentIDs, 2, []int{parentType, childType}, task.descendantIDs, hasM2M)
+		if err != nil {
+			return fmt.Errorf("failed to search children for %d nodes: %w", len(parentIDs), err)
+		}
+		for _, root := range relTree {
+			for _, child := range root.Children {
+				childIDs[root.ID] = append(childIDs[root.ID], child.ID)
+				if !seenChild[child.ID] {
+					seenChild[child.ID] = true
+					allIDs = append(allIDs, child.ID)
+				}
+			}
+		}
+	}
+
+	cis := make(map[int]models.CIInstance, len(allIDs))
+	typeQuery := c.client.BuildCITypeQuery(task.childTypes)
+	for start := 0; start < len(allIDs); start += ciIDQueryBatchSize {
+		end := start + ciIDQueryBatchSize
+		if end > len(allIDs) {
+			end = len(allIDs)
+		}
+
+		query := fmt.Sprintf("%s,_id:(%s)", typeQuery, joinIDs(allIDs[start:end], ";"))
+		resp, err := c.client.SearchAllCI(ctx, query, c.pageSize, false)
+		if err != nil {
+			return fmt.Errorf("failed to load child CIs: %w", err)
+		}
+		for _, ci := range resp.Result {
+			cis[ci.ID] = ci
+		}
+	}
+
+	task.children = make(map[int][]models.CIInstance, len(childIDs))
+	for parentID, ids := range childIDs {
+		for _, id := range ids {
+			ci, ok := cis[id]
+			if !ok {
+				// 关系存在但CI查询不到（已删除或无权限），与ci_relations/s的结果保持一致
+				c.logger.Debug("Child CI not found",
+					zap.Int("parent_id", parentID),
+					zap.Int("ci_id", id))
+				continue
+			}
+			task.children[parentID] = append(task.children[parentID], ci)
+		}
+	}
+	return nil
+}
+
+// fetchNodeChildren 通过ci_relations/s查询单个节点的子节点
+func (c *ServiceTreeCrawler) fetchNodeChildren(ctx context.Context, task *levelTask, viewConfig models.ServiceTreeView) error {
+	parent := task.parents[0]
+
+	params := map[string]interface{}{
+		"q":       c.client.BuildCITypeQuery(task.childTypes),
+		"root_id": parent.node.ID,
+		"level":   1,
+	}
+	if len(task.descendantIDs) > 0 {
+		params["descendant_ids"] = task.descendantIDs
+	}
+	c.addM2MParams(params, parent.ancestors, viewConfig)
+
+	resp, err := c.client.SearchAllCIRelations(ctx, params, c.pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to search children for node %d: %w", parent.node.ID, err)
+	}
+
+	task.children = map[int][]models.CIInstance{parent.node.ID: resp.Result}
+	return nil
+}
+
+// attachLevelChildren 将查询到的子节点挂到父节点上，返回下一层待展开的节点
+func (c *ServiceTreeCrawler) attachLevelChildren(parent *frontierNode, task *levelTask,
+	id2Type map[string]models.CIType, level int, emitter *nodeEmitter) ([]*frontierNode, error) {
+
+	node := parent.node
+	cis := task.children[node.ID]
+	if len(cis) == 0 {
+		node.IsLeaf = true
+		return nil, nil
+	}
+	// search/full与ci_relations/s返回的子节点顺序不同，按ID排序后树的内容和一对一约束保留的子节点与分批方式无关
+	slices.SortFunc(cis, func(a, b models.CIInstance) int { return cmp.Compare(a.ID, b.ID) })
+	cis = c.applyConstraint(node, task.constraint, cis)
+
+	// 叶子节点下展示的CI是最后一层，不再展开
+	node.IsLeaf = false
+	ancestors := append(append(make([]int, 0, len(parent.ancestors)+1), parent.ancestors...), node.ID)
+	var next []*frontierNode
+	for _, ci := range cis {
+		child := newTreeNode(ci, id2Type, level)
+		if task.leaf {
+			child.IsLeaf = true
+		}
+		if err := c.attachChild(node, child, emitter); err != nil {
+			return nil, err
+		}
+		node.ChildCount++
+
+		if !task.leaf {
+			next = append(next, &frontierNode{node: child, ancestors: ancestors, root: parent.root})
+		}
+	}
+	return next, nil
+}
+
+// joinIDs 将ID列表用指定分隔符连接
+func joinIDs(ids []int, sep string) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+	return strings.Join(strs, sep)
+}
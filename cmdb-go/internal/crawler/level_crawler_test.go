@@ -0,0 +1,196 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+)
+
+// seedLargeTree 填充4条产品线，每条5个产品，每个产品5个环境，每个环境部署1台服务器
+func seedLargeTree(f *fakeCMDB) {
+	view := simpleView()
+	view["leaf2show_types"] = map[string][]int{"3": {4}}
+	view["node2show_types"] = map[string][]map[string]interface{}{
+		"3": {{"id": 4, "name": "server", "alias": "服务器"}},
+	}
+	f.addView("product", view)
+
+	productID, envID := 10, 100
+	for line := 1; line <= 4; line++ {
+		f.addCI(line, 1, fmt.Sprintf("line-%d", line), 0)
+		for p := 0; p < 5; p++ {
+			productID++
+			f.addCI(productID, 2, fmt.Sprintf("product-%d", productID), line)
+			for e := 0; e < 5; e++ {
+				envID++
+				f.addCI(envID, 3, fmt.Sprintf("env-%d", envID), productID)
+				f.addCI(envID+1000, 4, fmt.Sprintf("server-%d", envID), envID)
+			}
+		}
+	}
+	// env-101 同时属于 product-12
+	f.addRelation(12, 101, "")
+}
+
+// treeEdges 按 父节点>子节点 列出树中所有节点及其叶子标记和子节点数，用于比较两次爬取的结果
+func treeEdges(tree *models.ServiceTreeData) []string {
+	var edges []string
+	var walk func(parentID int, node *models.ServiceTreeNode)
+	walk = func(parentID int, node *models.ServiceTreeNode) {
+		edges = append(edges, fmt.Sprintf("%d>%d %s level=%d leaf=%v children=%d",
+			parentID, node.ID, node.Name, node.Level, node.IsLeaf, node.ChildCount))
+		for _, child := range node.Children {
+			walk(node.ID, child)
+		}
+	}
+	for _, root := range tree.RootNodes {
+		walk(0, root)
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// TestCrawlLevelBatching 测试逐层批量查询与逐个节点查询得到相同的树，且请求数大幅减少
+func TestCrawlLevelBatching(t *testing.T) {
+	endpoints := []string{"ci/s", "ci_relations/s", "ci_relations/search/full"}
+
+	crawl := func(batchSize int) (*models.ServiceTreeData, int) {
+		f := newFakeCMDB(t)
+		seedLargeTree(f)
+		trees, err := f.newCrawler().
+			SetIncludeStats(false).
+			SetMaxWorkers(4).
+			SetBatchSize(batchSize).
+			CrawlAllServiceTrees(context.Background())
+		if err != nil {
+			t.Fatalf("Crawl with batch size %d failed: %v", batchSize, err)
+		}
+
+		requests := 0
+		for _, endpoint := range endpoints {
+			requests += f.requestCount(endpoint)
+		}
+		return trees[0], requests
+	}
+
+	perNode, perNodeRequests := crawl(1)
+	if perNode.TotalNodes != 224 || perNode.MaxDepth != 4 {
+		t.Fatalf("Expected 224 nodes and depth 4, got %d nodes and depth %d", perNode.TotalNodes, perNode.MaxDepth)
+	}
+	// 根节点1次，产品线4次，产品20次，环境下的服务器101次（共享环境出现两次）
+	if perNodeRequests != 126 {
+		t.Errorf("Expected 126 requests when crawling node by node, got %d", perNodeRequests)
+	}
+
+	// 每层一次关系查询和一次CI查询，父节点超过批大小时拆分：
+	// 批大小8时产品层3批、环境层13批，默认批大小下根节点1次 + 3层各2次
+	want := treeEdges(perNode)
+	for batchSize, wantRequests := range map[int]int{8: 35, 100: 7} {
+		tree, requests := crawl(batchSize)
+
+		got := treeEdges(tree)
+		if len(got) != len(want) {
+			t.Fatalf("Batch size %d: expected %d nodes, got %d", batchSize, len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Batch size %d: expected node %q, got %q", batchSize, want[i], got[i])
+				break
+			}
+		}
+		if len(tree.SharedNodes) != 1 || tree.SharedNodes[0].ID != 101 {
+			t.Errorf("Batch size %d: expected env-101 to be shared, got %+v", batchSize, tree.SharedNodes)
+		}
+
+		t.Logf("Batch size %d: %d requests, %d when crawling node by node", batchSize, requests, perNodeRequests)
+		if requests != wantRequests {
+			t.Errorf("Batch size %d: expected %d requests, got %d", batchSize, wantRequests, requests)
+		}
+	}
+}
+
+// TestCrawlLevelBatchingM2M 测试多对多视图下带祖先路径的层级仍逐个节点查询
+func TestCrawlLevelBatchingM2M(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+	f.addRelation(11, 100, "")
+
+	trees, err := f.newCrawler().SetIncludeStats(false).SetIncludeM2M(true).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+	if trees[0].TotalNodes != 6 {
+		t.Errorf("Expected 6 nodes, got %d", trees[0].TotalNodes)
+	}
+
+	// 根节点没有祖先，一次批量查询；产品节点各自带 ancestor_ids 查询
+	if got := f.requestCount("ci_relations/search/full"); got != 1 {
+		t.Errorf("Expected 1 batched query for the root level, got %d", got)
+	}
+	if got := f.queryValues("ci_relations/s", "ancestor_ids"); len(got) != 2 {
+		t.Errorf("Expected 2 path-aware queries with ancestor_ids, got %v", got)
+	}
+}
+
+// TestCrawlLevelBatchingSameResult 测试批量查询与逐个节点查询的过滤条件和子节点顺序一致：
+// 两种路径都按descendant_ids过滤无权限的CI，一对一约束保留相同的子节点
+func TestCrawlLevelBatchingSameResult(t *testing.T) {
+	crawl := func(batchSize int) (*models.ServiceTreeData, *fakeCMDB) {
+		f := newFakeCMDB(t)
+		view := simpleView()
+		view["level2constraint"] = map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintOneToOne}
+		f.addView("product", view)
+		f.addCI(1, 1, "line-a", 0)
+		f.addCI(2, 1, "line-b", 0)
+		f.addCI(10, 2, "product-a1", 1)
+		f.addCI(12, 2, "product-a2", 1)
+		f.addCI(11, 2, "product-b1", 2)
+		f.addCI(13, 2, "product-b2", 2)
+		f.restricted[13] = true
+		f.addCI(100, 3, "env-a1", 10)
+		f.addCI(101, 3, "env-b1", 11)
+		// product-a2 违反一对一约束，ci_relations/s 先返回 env-105，search/full 先返回 env-104
+		f.addCI(105, 3, "env-105", 12)
+		f.addCI(104, 3, "env-104", 12)
+
+		trees, err := f.newCrawler().
+			SetIncludeStats(false).
+			SetMaxWorkers(4).
+			SetBatchSize(batchSize).
+			CrawlAllServiceTrees(context.Background())
+		if err != nil {
+			t.Fatalf("Crawl with batch size %d failed: %v", batchSize, err)
+		}
+		return trees[0], f
+	}
+
+	perNode, _ := crawl(1)
+	batched, f := crawl(100)
+	if got := f.requestCount("ci_relations/s"); got != 0 {
+		t.Errorf("Expected all levels to be batched, got %d ci_relations/s requests", got)
+	}
+	// 环境层之下没有更深的层级，只有产品层的查询带 descendant_ids
+	if got := f.queryValues("ci_relations/search/full", "descendant_ids"); len(got) != 1 || got[0] != "3" {
+		t.Errorf("Expected the product level batch to carry descendant_ids, got %v", got)
+	}
+
+	want := []string{
+		"0>1 line-a level=0 leaf=false children=2",
+		"0>2 line-b level=0 leaf=false children=1",
+		"10>100 env-a1 level=2 leaf=true children=0",
+		"11>101 env-b1 level=2 leaf=true children=0",
+		"12>104 env-104 level=2 leaf=true children=0",
+		"1>10 product-a1 level=1 leaf=false children=1",
+		"1>12 product-a2 level=1 leaf=false children=1",
+		"2>11 product-b1 level=1 leaf=false children=1",
+	}
+	for batchSize, tree := range map[int]*models.ServiceTreeData{1: perNode, 100: batched} {
+		if got := treeEdges(tree); !reflect.DeepEqual(got, want) {
+			t.Errorf("Batch size %d: expected tree\n%v\ngot\n%v", batchSize, want, got)
+		}
+	}
+}
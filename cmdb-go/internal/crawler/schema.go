@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// CrawlSchema 爬取CI类型模型图：全部CI类型、每个类型的属性定义以及类型之间的关系
+// 单个类型的属性获取失败时记录错误并继续，该类型的属性为空
+func (c *ServiceTreeCrawler) CrawlSchema(ctx context.Context) (*models.CITypeSchema, error) {
+	c.logger.Info("Starting to crawl CI type schema")
+
+	typesResp, err := c.client.GetCITypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI types: %w", err)
+	}
+
+	schema := &models.CITypeSchema{
+		Types:     typesResp.CITypes,
+		CrawledAt: time.Now(),
+	}
+	sort.Slice(schema.Types, func(i, j int) bool {
+		return schema.Types[i].ID < schema.Types[j].ID
+	})
+
+	if err := c.loadTypeAttributes(ctx, schema.Types); err != nil {
+		return nil, err
+	}
+
+	relationsResp, err := c.client.GetCITypeRelations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI type relations: %w", err)
+	}
+	schema.Relations = relationsResp.Relations
+	schema.CollectRelationTypes()
+
+	c.logger.Info("Successfully crawled CI type schema",
+		zap.Int("type_count", len(schema.Types)),
+		zap.Int("relation_count", len(schema.Relations)),
+		zap.Int("relation_type_count", len(schema.RelationTypes)))
+
+	return schema, nil
+}
+
+// loadTypeAttributes 并发获取每个CI类型的属性定义
+func (c *ServiceTreeCrawler) loadTypeAttributes(ctx context.Context, types []models.CITypeDefinition) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	semaphore := make(chan struct{}, c.maxWorkers)
+
+	for i := range types {
+		wg.Add(1)
+		go func(ciType *models.CITypeDefinition) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+
+			if err := c.wait(ctx); err != nil {
+				return
+			}
+
+			resp, err := c.client.GetCITypeAttributes(ctx, ciType.ID)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Error("Failed to load CI type attributes",
+						zap.Int("type_id", ciType.ID),
+						zap.String("type_name", ciType.Name),
+						zap.Error(err))
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+				return
+			}
+			ciType.Attributes = resp.Attributes
+		}(&types[i])
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		c.logger.Warn("Some CI type attributes failed to load",
+			zap.Int("error_count", failed))
+	}
+	return nil
+}
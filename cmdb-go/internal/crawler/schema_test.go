@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+)
+
+// TestCrawlSchema 测试CI类型、属性和类型关系的爬取
+func TestCrawlSchema(t *testing.T) {
+	f := newFakeCMDB(t)
+	f.addCIType(2, "product", "产品", "name", "owner")
+	f.addCIType(1, "product_line", "产品线", "name")
+	f.addCIType(3, "env", "环境")
+	f.addTypeRelation(1, 2, 1, "contain", models.ConstraintOneToMany)
+	f.addTypeRelation(2, 3, 1, "contain", models.ConstraintOneToMany)
+	f.addTypeRelation(3, 2, 2, "deploy", models.ConstraintManyToMany)
+
+	schema, err := f.newCrawler().CrawlSchema(context.Background())
+	if err != nil {
+		t.Fatalf("CrawlSchema failed: %v", err)
+	}
+
+	if len(schema.Types) != 3 || schema.Types[0].ID != 1 {
+		t.Fatalf("Expected 3 types sorted by ID, got %+v", schema.Types)
+	}
+	product, ok := schema.TypeByID(2)
+	if !ok || product.DisplayName() != "产品" {
+		t.Fatalf("Expected type 2 to be 产品, got %+v", product)
+	}
+	if len(product.Attributes) != 2 || product.Attributes[1].Name != "owner" {
+		t.Errorf("Unexpected attributes for type 2: %+v", product.Attributes)
+	}
+	if f.requestCount("ci_types/3/attributes") != 1 {
+		t.Error("Expected attributes to be fetched once per type")
+	}
+
+	if len(schema.ChildRelations(2)) != 1 || len(schema.ParentRelations(2)) != 2 {
+		t.Errorf("Unexpected relations for type 2: %+v", schema.Relations)
+	}
+	if len(schema.RelationTypes) != 2 || schema.RelationTypes[1].Name != "deploy" {
+		t.Errorf("Expected contain and deploy relation types, got %+v", schema.RelationTypes)
+	}
+}
@@ -2,10 +2,9 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
-	"strings"
-	"sync"
 	"time"
 
 	"cmdb-crawler/internal/client"
@@ -21,8 +20,15 @@ type ServiceTreeCrawler struct {
 	maxDepth        int
 	pageSize        int
 	maxWorkers      int
+	batchSize       int
 	includeStats    bool
+	includeM2M      bool
 	requestInterval time.Duration
+	// retry 爬取器层面的重试策略，只重试客户端自身不重试的临时错误
+	retry      client.RetryPolicy
+	checkpoint *Checkpoint
+	baseline   *incrementalBaseline
+	sink       NodeSink
 }
 
 // NewServiceTreeCrawler 创建服务树爬取器
@@ -33,8 +39,10 @@ func NewServiceTreeCrawler(client *client.CMDBClient, logger *zap.Logger) *Servi
 		maxDepth:        -1, // 无限制
 		pageSize:        1000,
 		maxWorkers:      10,
+		batchSize:       100,
 		includeStats:    true,
 		requestInterval: 100 * time.Millisecond,
+		retry:           defaultRetryPolicy(),
 	}
 }
 
@@ -56,24 +64,55 @@ func (c *ServiceTreeCrawler) SetMaxWorkers(workers int) *ServiceTreeCrawler {
 	return c
 }
 
+// SetBatchSize 设置同一层合并查询子节点的最大父节点数，为1时逐个节点查询
+func (c *ServiceTreeCrawler) SetBatchSize(size int) *ServiceTreeCrawler {
+	c.batchSize = size
+	return c
+}
+
 // SetIncludeStats 设置是否包含统计信息
 func (c *ServiceTreeCrawler) SetIncludeStats(include bool) *ServiceTreeCrawler {
 	c.includeStats = include
 	return c
 }
 
-// SetRequestInterval 设置请求间隔
+// SetIncludeM2M 设置是否请求多对多关系数据
+// 开启后查询子节点时带上 has_m2m 和祖先路径 ancestor_ids，同一CI在不同父节点下的子节点按路径分别返回
+func (c *ServiceTreeCrawler) SetIncludeM2M(include bool) *ServiceTreeCrawler {
+	c.includeM2M = include
+	return c
+}
+
+// SetRetryPolicy 设置爬取器层面的重试策略，客户端已按同一策略重试的错误不会再次重试
+func (c *ServiceTreeCrawler) SetRetryPolicy(policy client.RetryPolicy) *ServiceTreeCrawler {
+	c.retry = policy
+	return c
+}
+
+// SetRequestInterval 设置统计和模型查询中每个协程的请求间隔，客户端已启用限流时设为0，避免两者叠加降低速率
+// 逐层展开子节点的请求只由客户端限流器控制
 func (c *ServiceTreeCrawler) SetRequestInterval(interval time.Duration) *ServiceTreeCrawler {
 	c.requestInterval = interval
 	return c
 }
 
+// SetCheckpoint 设置检查点日志，已完成的视图和子树会被跳过并合并到结果中
+func (c *ServiceTreeCrawler) SetCheckpoint(checkpoint *Checkpoint) *ServiceTreeCrawler {
+	c.checkpoint = checkpoint
+	return c
+}
+
 // CrawlAllServiceTrees 爬取所有服务树
+// 上下文被取消时停止爬取，返回已爬取的服务树（含未完成的部分树）和上下文错误
 func (c *ServiceTreeCrawler) CrawlAllServiceTrees(ctx context.Context) ([]*models.ServiceTreeData, error) {
 	c.logger.Info("Starting to crawl all service trees")
 
 	// 获取服务树视图列表
-	viewsResp, err := c.client.GetRelationViews()
+	var viewsResp *models.RelationViewResponse
+	err := withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "get relation views", func() (err error) {
+		viewsResp, err = c.client.GetRelationViews(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get relation views: %w", err)
 	}
@@ -85,6 +124,13 @@ func (c *ServiceTreeCrawler) CrawlAllServiceTrees(ctx context.Context) ([]*model
 
 	var results []*models.ServiceTreeData
 	for viewName, viewConfig := range viewsResp.Views {
+		if err := ctx.Err(); err != nil {
+			c.logger.Warn("Crawl cancelled",
+				zap.Int("completed_trees", len(results)),
+				zap.Error(err))
+			return results, err
+		}
+
 		// 从name2id中获取view ID
 		viewID := c.findViewIDByName(viewName, viewsResp.Name2ID)
 
@@ -94,6 +140,20 @@ func (c *ServiceTreeCrawler) CrawlAllServiceTrees(ctx context.Context) ([]*model
 
 		treeData, err := c.CrawlServiceTree(ctx, viewName, viewID, viewConfig, viewsResp.ID2Type)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				if treeData != nil {
+					results = append(results, treeData)
+				}
+				c.logger.Warn("Crawl cancelled",
+					zap.String("view_name", viewName),
+					zap.Int("completed_trees", len(results)),
+					zap.Error(ctxErr))
+				return results, ctxErr
+			}
+			// 流式输出写入失败或认证失败时中止，其他错误（如视图无权限）跳过该视图
+			if errors.Is(err, errNodeSink) || isAbortError(err) {
+				return results, err
+			}
 			c.logger.Error("Failed to crawl service tree",
 				zap.String("view_name", viewName),
 				zap.Error(err))
@@ -110,6 +170,7 @@ func (c *ServiceTreeCrawler) CrawlAllServiceTrees(ctx context.Context) ([]*model
 }
 
 // CrawlServiceTree 爬取指定的服务树
+// 上下文被取消时返回已爬取的部分树和上下文错误
 func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName string, viewID int,
 	viewConfig models.ServiceTreeView, id2Type map[string]models.CIType) (*models.ServiceTreeData, error) {
 
@@ -121,13 +182,25 @@ func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName stri
 		return nil, fmt.Errorf("service tree has no levels defined")
 	}
 
+	// 检查点中已完成的视图直接复用
+	if c.checkpoint != nil && c.sink == nil {
+		if tree, ok := c.checkpoint.CompletedView(viewName); ok && sameOptions(tree.Options, c.crawlOptions()) {
+			c.logger.Info("Reusing service tree from checkpoint",
+				zap.String("view_name", viewName),
+				zap.Int("total_nodes", tree.TotalNodes))
+			return tree, nil
+		}
+	}
+
 	// 创建服务树数据结构
 	treeData := &models.ServiceTreeData{
 		ViewName:  viewName,
 		ViewID:    viewID,
 		Config:    viewConfig,
+		ID2Type:   viewCITypes(viewConfig, id2Type),
 		RootNodes: make([]*models.ServiceTreeNode, 0),
 		CrawledAt: time.Now(),
+		Options:   c.crawlOptions(),
 	}
 
 	// 获取根节点类型
@@ -135,9 +208,13 @@ func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName stri
 	c.logger.Info("Loading root nodes",
 		zap.Ints("root_type_ids", rootTypeIDs))
 
-	// 查询根节点实例
+	// 查询根节点实例（自动翻页）
 	query := c.client.BuildCITypeQuery(rootTypeIDs)
-	rootResp, err := c.client.SearchCI(query, c.pageSize, false)
+	var rootResp *models.CISearchResponse
+	err := withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "search root nodes", func() (err error) {
+		rootResp, err = c.client.SearchAllCI(ctx, query, c.pageSize, false)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search root nodes: %w", err)
 	}
@@ -151,62 +228,50 @@ func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName stri
 	// 构建根节点
 	rootNodes := make([]*models.ServiceTreeNode, 0, len(rootResp.Result))
 	for _, ci := range rootResp.Result {
-		ciType, exists := id2Type[strconv.Itoa(ci.Type)]
-		typeName := ""
-		if exists {
-			typeName = ciType.Alias
-			if typeName == "" {
-				typeName = ciType.Name
-			}
-		}
-
-		node := &models.ServiceTreeNode{
-			ID:         ci.ID,
-			Type:       ci.Type,
-			TypeName:   typeName,
-			Name:       ci.GetDisplayName(),
-			Level:      0,
-			Children:   make([]*models.ServiceTreeNode, 0),
-			IsLeaf:     false,
-			Attributes: ci.Attrs,
-		}
-
-		rootNodes = append(rootNodes, node)
+		rootNodes = append(rootNodes, newTreeNode(ci, treeData.ID2Type, 0))
 	}
 
-	// 如果需要统计信息，获取根节点的子节点统计
+	// 如果需要统计信息，获取根节点的叶子统计（流式模式下根节点随后立即输出）
 	if c.includeStats && len(viewConfig.Leaf) > 0 {
-		err = c.loadRootNodeStatistics(rootNodes, viewConfig)
-		if err != nil {
+		targets := make([]statsTarget, len(rootNodes))
+		for i, node := range rootNodes {
+			targets[i] = statsTarget{node: node}
+		}
+		if err := c.loadLevelStatistics(ctx, 0, targets, viewConfig, treeData.ID2Type); err != nil {
 			c.logger.Warn("Failed to load root node statistics", zap.Error(err))
 		}
 	}
 
-	// 并发爬取每个根节点的子树
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(rootNodes))
-	semaphore := make(chan struct{}, c.maxWorkers)
-
+	// 流式模式下先输出根节点
+	emitter := c.newNodeEmitter(viewName, viewID, viewConfig)
 	for _, rootNode := range rootNodes {
-		wg.Add(1)
-		go func(node *models.ServiceTreeNode) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			if err := c.crawlNodeChildren(ctx, node, viewConfig, id2Type, 1); err != nil {
-				errChan <- fmt.Errorf("failed to crawl children for node %s: %w", node.Name, err)
-			}
-		}(rootNode)
+		if err := emitter.emit(0, rootNode); err != nil {
+			return nil, fmt.Errorf("failed to write root node %d: %w", rootNode.ID, err)
+		}
 	}
 
-	wg.Wait()
-	close(errChan)
+	// 检查点中已完成或增量模式下未变更的子树直接合并，其余根节点逐层展开
+	var pending []int
+	for i, rootNode := range rootNodes {
+		if restored, ok := c.restoreSubtree(viewName, viewConfig, rootNode); ok {
+			rootNodes[i] = restored
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	failed, err := c.crawlLevels(ctx, viewName, rootNodes, pending, viewConfig, treeData.ID2Type, emitter)
+	// 流式输出写入失败时输出已不完整，认证失败时其余请求同样会失败，直接中止
+	if errors.Is(err, errNodeSink) || isAbortError(err) {
+		return nil, err
+	}
 
-	// 检查是否有错误
+	// 检查是否有错误（上下文取消导致的错误不单独记录）
 	var crawlErrors []error
-	for err := range errChan {
-		crawlErrors = append(crawlErrors, err)
+	for _, i := range pending {
+		if err, ok := failed[i]; ok {
+			crawlErrors = append(crawlErrors, err)
+		}
 	}
 
 	if len(crawlErrors) > 0 {
@@ -217,9 +282,40 @@ func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName stri
 		}
 	}
 
+	// 根节点以下各层的统计在整棵树爬取完成后按层批量加载，流式模式下节点已输出，只有根节点有统计
+	if c.includeStats && len(viewConfig.Leaf) > 0 && emitter == nil && ctx.Err() == nil {
+		c.loadTreeStatistics(ctx, rootNodes, viewConfig, treeData.ID2Type)
+	}
+
 	treeData.RootNodes = rootNodes
-	treeData.CountNodes()
-	treeData.CalculateMaxDepth()
+	if emitter != nil {
+		emitter.apply(treeData)
+	} else {
+		treeData.DetectSharedNodes()
+		treeData.CountNodes()
+		treeData.CalculateMaxDepth()
+	}
+	if len(treeData.SharedNodes) > 0 {
+		c.logger.Info("Found CIs under multiple parents",
+			zap.String("view_name", viewName),
+			zap.Int("shared_nodes", len(treeData.SharedNodes)))
+	}
+
+	if err := ctx.Err(); err != nil {
+		c.logger.Warn("Service tree crawl cancelled, returning partial tree",
+			zap.String("view_name", viewName),
+			zap.Int("total_nodes", treeData.TotalNodes))
+		return treeData, err
+	}
+
+	// 全部子树成功后才记录视图，失败的子树在恢复时会重新爬取
+	if c.checkpoint != nil && c.sink == nil && len(crawlErrors) == 0 {
+		if err := c.checkpoint.RecordView(treeData); err != nil {
+			c.logger.Warn("Failed to record view checkpoint",
+				zap.String("view_name", viewName),
+				zap.Error(err))
+		}
+	}
 
 	c.logger.Info("Successfully crawled service tree",
 		zap.String("view_name", viewName),
@@ -229,137 +325,166 @@ func (c *ServiceTreeCrawler) CrawlServiceTree(ctx context.Context, viewName stri
 	return treeData, nil
 }
 
-// crawlNodeChildren 递归爬取节点的子节点
-func (c *ServiceTreeCrawler) crawlNodeChildren(ctx context.Context, node *models.ServiceTreeNode,
-	viewConfig models.ServiceTreeView, id2Type map[string]models.CIType, currentLevel int) error {
+// subtreeKey 构建根节点子树在检查点中的Key
+func (c *ServiceTreeCrawler) subtreeKey(node *models.ServiceTreeNode) string {
+	return c.client.BuildTreeKey([]client.TreeKeySegment{{CIID: node.ID, TypeID: node.Type}})
+}
+
+// restoreSubtree 从检查点或增量基线中恢复无需重新爬取的子树
+func (c *ServiceTreeCrawler) restoreSubtree(viewName string, viewConfig models.ServiceTreeView,
+	node *models.ServiceTreeNode) (*models.ServiceTreeNode, bool) {
 
-	// 检查是否达到最大深度
-	if c.maxDepth > 0 && currentLevel >= c.maxDepth {
-		return nil
+	if c.sink != nil {
+		return nil, false
 	}
 
-	// 检查是否超出视图定义的层级
-	if currentLevel >= len(viewConfig.Topo) {
-		node.IsLeaf = true
-		return nil
+	if c.checkpoint != nil {
+		if restored, ok := c.checkpoint.CompletedSubtree(viewName, c.subtreeKey(node)); ok {
+			c.logger.Debug("Reusing subtree from checkpoint",
+				zap.String("view_name", viewName),
+				zap.Int("node_id", node.ID))
+			return restored, true
+		}
 	}
 
-	// 获取当前级别的子类型
-	childTypeIDs := viewConfig.Topo[currentLevel]
-	if len(childTypeIDs) == 0 {
-		node.IsLeaf = true
-		return nil
+	if c.baseline != nil {
+		if prev, ok := c.baseline.reusableSubtree(viewName, viewConfig, node); ok {
+			// 统计信息以本次查询结果为准
+			if node.Statistics != nil {
+				prev.Statistics = node.Statistics
+			}
+			c.logger.Debug("Reusing unchanged subtree from previous snapshot",
+				zap.String("view_name", viewName),
+				zap.Int("node_id", node.ID))
+			c.recordSubtree(viewName, prev)
+			return prev, true
+		}
 	}
 
-	// 请求间隔控制
-	time.Sleep(c.requestInterval)
+	return nil, false
+}
 
-	// 构建查询参数
-	params := map[string]interface{}{
-		"q":       c.client.BuildCITypeQuery(childTypeIDs),
-		"root_id": node.ID,
-		"level":   1,
-		"count":   c.pageSize,
+// recordSubtree 记录已完成的子树到检查点
+func (c *ServiceTreeCrawler) recordSubtree(viewName string, node *models.ServiceTreeNode) {
+	if c.checkpoint == nil || c.sink != nil {
+		return
 	}
 
-	// 添加descendant_ids参数
-	if len(viewConfig.TopoFlatten) > currentLevel+1 {
-		descendantIDs := viewConfig.TopoFlatten[currentLevel+1:]
-		descendantStrs := make([]string, len(descendantIDs))
-		for i, id := range descendantIDs {
-			descendantStrs[i] = strconv.Itoa(id)
-		}
-		params["descendant_ids"] = strings.Join(descendantStrs, ",")
+	if err := c.checkpoint.RecordSubtree(viewName, c.subtreeKey(node), node); err != nil {
+		c.logger.Warn("Failed to record subtree checkpoint",
+			zap.String("view_name", viewName),
+			zap.Int("node_id", node.ID),
+			zap.Error(err))
 	}
+}
 
-	// 搜索子节点
-	childResp, err := c.client.SearchCIRelation(params)
-	if err != nil {
-		return fmt.Errorf("failed to search children for node %d: %w", node.ID, err)
+// attachChild 将子节点挂到父节点上，流式模式下写入输出
+func (c *ServiceTreeCrawler) attachChild(node, child *models.ServiceTreeNode, emitter *nodeEmitter) error {
+	if emitter == nil {
+		node.AddChild(child)
+		return nil
 	}
 
-	if len(childResp.Result) == 0 {
-		node.IsLeaf = true
-		return nil
+	child.Path = node.BuildTreePath()
+	child.Level = node.Level + 1
+	if err := emitter.emit(node.ID, child); err != nil {
+		return fmt.Errorf("%w: node %d: %w", errNodeSink, child.ID, err)
 	}
+	return nil
+}
 
-	// 构建子节点
-	for _, ci := range childResp.Result {
-		ciType, exists := id2Type[strconv.Itoa(ci.Type)]
-		typeName := ""
-		if exists {
-			typeName = ciType.Alias
-			if typeName == "" {
-				typeName = ciType.Name
-			}
-		}
+// addM2MParams 按视图的层级约束添加多对多查询参数
+// 多对多关系下同一CI在不同路径上的子节点可能不同，需要带上祖先路径
+func (c *ServiceTreeCrawler) addM2MParams(params map[string]interface{}, ancestors []int, viewConfig models.ServiceTreeView) {
+	if c.includeM2M || viewConfig.HasM2M() {
+		params["has_m2m"] = 1
+	}
+	if (c.includeM2M || viewConfig.TreeHasM2M()) && len(ancestors) > 0 {
+		params["ancestor_ids"] = ancestors
+	}
+}
 
-		child := &models.ServiceTreeNode{
-			ID:         ci.ID,
-			Type:       ci.Type,
-			TypeName:   typeName,
-			Name:       ci.GetDisplayName(),
-			Level:      currentLevel,
-			Children:   make([]*models.ServiceTreeNode, 0),
-			IsLeaf:     false,
-			Attributes: ci.Attrs,
-		}
+// applyConstraint 按层级约束过滤子CI：一对一关系只保留第一个（ID最小的）子CI，
+// CMDB中的数据违反约束时记录警告和被丢弃的CI
+func (c *ServiceTreeCrawler) applyConstraint(node *models.ServiceTreeNode, constraint string,
+	cis []models.CIInstance) []models.CIInstance {
 
-		node.AddChild(child)
-
-		// 递归爬取子节点的子节点
-		if err := c.crawlNodeChildren(ctx, child, viewConfig, id2Type, currentLevel+1); err != nil {
-			c.logger.Error("Failed to crawl grandchildren",
-				zap.Int("parent_id", node.ID),
-				zap.Int("child_id", child.ID),
-				zap.Error(err))
-		}
+	if constraint != models.ConstraintOneToOne || len(cis) <= 1 {
+		return cis
 	}
 
-	node.ChildCount = len(node.Children)
-	return nil
+	dropped := make([]int, 0, len(cis)-1)
+	for _, ci := range cis[1:] {
+		dropped = append(dropped, ci.ID)
+	}
+	c.logger.Warn("One-to-one relation has multiple children, keeping the first",
+		zap.Int("node_id", node.ID),
+		zap.String("node_name", node.Name),
+		zap.Int("kept_id", cis[0].ID),
+		zap.Ints("dropped_ids", dropped))
+	return cis[:1]
 }
 
-// loadRootNodeStatistics 加载根节点统计信息
-func (c *ServiceTreeCrawler) loadRootNodeStatistics(rootNodes []*models.ServiceTreeNode,
-	viewConfig models.ServiceTreeView) error {
-
-	if len(rootNodes) == 0 || len(viewConfig.Leaf) == 0 {
-		return nil
+// newTreeNode 由CI实例构建服务树节点
+func newTreeNode(ci models.CIInstance, id2Type map[string]models.CIType, level int) *models.ServiceTreeNode {
+	typeName := ""
+	if ciType, exists := id2Type[strconv.Itoa(ci.Type)]; exists {
+		typeName = ciType.Alias
+		if typeName == "" {
+			typeName = ciType.Name
+		}
 	}
 
-	// 构建root_ids参数
-	rootIDs := make([]string, len(rootNodes))
-	for i, node := range rootNodes {
-		rootIDs[i] = strconv.Itoa(node.ID)
+	return &models.ServiceTreeNode{
+		ID:         ci.ID,
+		Type:       ci.Type,
+		TypeName:   typeName,
+		Name:       ci.GetDisplayName(),
+		Level:      level,
+		Children:   make([]*models.ServiceTreeNode, 0),
+		IsLeaf:     false,
+		Attributes: ci.Attrs,
 	}
+}
 
-	// 计算到叶子节点的层级深度
-	level := len(viewConfig.TopoFlatten)
-
-	// 构建统计查询参数
-	params := map[string]interface{}{
-		"root_ids": strings.Join(rootIDs, ","),
-		"level":    level,
-		"type_ids": viewConfig.Leaf,
-		"has_m2m":  0,
+// viewCITypes 从全局类型表中取出视图各层级和叶子展示类型用到的CI类型
+func viewCITypes(viewConfig models.ServiceTreeView, id2Type map[string]models.CIType) map[string]models.CIType {
+	typeIDs := viewConfig.TopoFlatten
+	if len(typeIDs) == 0 {
+		for _, level := range viewConfig.Topo {
+			typeIDs = append(typeIDs, level...)
+		}
 	}
 
-	// 获取统计信息
-	stats, err := c.client.GetCIRelationStatistics(params)
-	if err != nil {
-		return fmt.Errorf("failed to get statistics: %w", err)
+	types := make(map[string]models.CIType, len(typeIDs))
+	for _, id := range typeIDs {
+		key := strconv.Itoa(id)
+		if ciType, ok := id2Type[key]; ok {
+			types[key] = ciType
+		}
 	}
 
-	// 将统计信息设置到对应的根节点
-	for _, node := range rootNodes {
-		count := stats.GetCount(strconv.Itoa(node.ID))
-		node.Statistics = map[string]int{
-			"total_descendants": count,
+	// 叶子节点下展示的CI类型不在视图层级中，从node2show_types补充
+	for leafType, showTypeIDs := range viewConfig.Leaf2ShowTypes {
+		for _, ciType := range viewConfig.Node2ShowTypes[leafType] {
+			for _, id := range showTypeIDs {
+				if ciType.ID == id {
+					types[strconv.Itoa(id)] = ciType
+				}
+			}
 		}
 	}
+	return types
+}
 
-	return nil
+// wait 等待一个请求间隔，上下文取消时立即返回
+func (c *ServiceTreeCrawler) wait(ctx context.Context) error {
+	return client.SleepContext(ctx, c.requestInterval)
+}
+
+// isContextError 判断错误是否由上下文取消或超时引起
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
 // findViewIDByName 根据视图名称查找视图ID
@@ -385,7 +510,11 @@ func (c *ServiceTreeCrawler) CrawlSpecificViews(ctx context.Context, targetViews
 	c.logger.Info("Crawling specific service trees", zap.Strings("target_views", targetViews))
 
 	// 获取服务树视图列表
-	viewsResp, err := c.client.GetRelationViews()
+	var viewsResp *models.RelationViewResponse
+	err := withRetry(ctx, c.logger, c.retry, c.client.RetryPolicy(), "get relation views", func() (err error) {
+		viewsResp, err = c.client.GetRelationViews(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get relation views: %w", err)
 	}
@@ -401,6 +530,13 @@ func (c *ServiceTreeCrawler) CrawlSpecificViews(ctx context.Context, targetViews
 			continue
 		}
 
+		if err := ctx.Err(); err != nil {
+			c.logger.Warn("Crawl cancelled",
+				zap.Int("completed_trees", len(results)),
+				zap.Error(err))
+			return results, err
+		}
+
 		viewID := c.findViewIDByName(viewName, viewsResp.Name2ID)
 
 		c.logger.Info("Crawling specific service tree",
@@ -409,6 +545,20 @@ func (c *ServiceTreeCrawler) CrawlSpecificViews(ctx context.Context, targetViews
 
 		treeData, err := c.CrawlServiceTree(ctx, viewName, viewID, viewConfig, viewsResp.ID2Type)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				if treeData != nil {
+					results = append(results, treeData)
+				}
+				c.logger.Warn("Crawl cancelled",
+					zap.String("view_name", viewName),
+					zap.Int("completed_trees", len(results)),
+					zap.Error(ctxErr))
+				return results, ctxErr
+			}
+			// 流式输出写入失败或认证失败时中止，其他错误（如视图无权限）跳过该视图
+			if errors.Is(err, errNodeSink) || isAbortError(err) {
+				return results, err
+			}
 			c.logger.Error("Failed to crawl specific service tree",
 				zap.String("view_name", viewName),
 				zap.Error(err))
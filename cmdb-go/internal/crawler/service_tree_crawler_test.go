@@ -2,6 +2,8 @@ package crawler
 
 import (
 	"context"
+	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -9,22 +11,36 @@ import (
 	"cmdb-crawler/internal/models"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
-// 演示环境配置
+// 演示环境配置，凭证从环境变量 CMDB_DEMO_API_KEY / CMDB_DEMO_API_SECRET 读取
 const (
 	demoBaseURL    = "https://cmdb.veops.cn"
 	demoAPIVersion = "api/v0.1"
-	demoAPIKey     = "d0a8fb5aeedf466c92cc5142a18d1a68"
-	demoAPISecret  = "DSGYH81jqfw~%A&vgyJKXrO*UFVaW2xt"
 )
 
-// createTestClient 创建测试用的CMDB客户端
+// skipWithoutDemo 短模式或未设置演示环境凭证时跳过集成测试
+func skipWithoutDemo(tb testing.TB) {
+	if testing.Short() {
+		tb.Skip("Skipping integration test in short mode")
+	}
+	if os.Getenv("CMDB_DEMO_API_KEY") == "" || os.Getenv("CMDB_DEMO_API_SECRET") == "" {
+		tb.Skip("CMDB_DEMO_API_KEY and CMDB_DEMO_API_SECRET are not set")
+	}
+}
+
+// createTestClient 创建测试用的CMDB客户端，未设置演示环境凭证时使用占位凭证
 func createTestClient(t *testing.T) *client.CMDBClient {
 	logger, _ := zap.NewDevelopment()
 
+	apiKey, apiSecret := os.Getenv("CMDB_DEMO_API_KEY"), os.Getenv("CMDB_DEMO_API_SECRET")
+	if apiKey == "" || apiSecret == "" {
+		apiKey, apiSecret = "test-key", "test-secret"
+	}
+
 	cmdbClient := client.NewCMDBClient(demoBaseURL, demoAPIVersion, logger)
-	cmdbClient.SetAPICredentials(demoAPIKey, demoAPISecret)
+	cmdbClient.SetAPICredentials(apiKey, apiSecret)
 	cmdbClient.SetTimeout(30 * time.Second)
 	cmdbClient.SetRetry(3, 1*time.Second)
 
@@ -78,6 +94,10 @@ func TestNewServiceTreeCrawler(t *testing.T) {
 		t.Errorf("Expected default maxWorkers to be 10, got %d", crawler.maxWorkers)
 	}
 
+	if crawler.batchSize != 100 {
+		t.Errorf("Expected default batchSize to be 100, got %d", crawler.batchSize)
+	}
+
 	if !crawler.includeStats {
 		t.Error("Expected default includeStats to be true")
 	}
@@ -91,6 +111,7 @@ func TestSetterMethods(t *testing.T) {
 	result := crawler.SetMaxDepth(5).
 		SetPageSize(500).
 		SetMaxWorkers(15).
+		SetBatchSize(50).
 		SetIncludeStats(false).
 		SetRequestInterval(500 * time.Millisecond)
 
@@ -111,6 +132,10 @@ func TestSetterMethods(t *testing.T) {
 		t.Errorf("Expected maxWorkers to be 15, got %d", crawler.maxWorkers)
 	}
 
+	if crawler.batchSize != 50 {
+		t.Errorf("Expected batchSize to be 50, got %d", crawler.batchSize)
+	}
+
 	if crawler.includeStats {
 		t.Error("Expected includeStats to be false")
 	}
@@ -122,9 +147,7 @@ func TestSetterMethods(t *testing.T) {
 
 // TestCrawlAllServiceTrees 测试爬取所有服务树
 func TestCrawlAllServiceTrees(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 	ctx := context.Background()
@@ -167,15 +190,13 @@ func TestCrawlAllServiceTrees(t *testing.T) {
 
 // TestCrawlSpecificViews 测试爬取指定视图
 func TestCrawlSpecificViews(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 	ctx := context.Background()
 
 	// 首先获取可用的视图列表
-	viewsResp, err := crawler.client.GetRelationViews()
+	viewsResp, err := crawler.client.GetRelationViews(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get relation views: %v", err)
 	}
@@ -222,9 +243,7 @@ func TestCrawlSpecificViews(t *testing.T) {
 
 // TestCrawlSpecificViewsEmpty 测试爬取空的指定视图列表
 func TestCrawlSpecificViewsEmpty(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 	ctx := context.Background()
@@ -243,9 +262,7 @@ func TestCrawlSpecificViewsEmpty(t *testing.T) {
 
 // TestCrawlSpecificViewsNonExistent 测试爬取不存在的视图
 func TestCrawlSpecificViewsNonExistent(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 	ctx := context.Background()
@@ -297,9 +314,7 @@ func TestFindViewIDByName(t *testing.T) {
 
 // TestCrawlerWithContext 测试上下文控制
 func TestCrawlerWithContext(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 
@@ -315,11 +330,26 @@ func TestCrawlerWithContext(t *testing.T) {
 	}
 }
 
+// TestWaitCancelled 测试请求间隔等待可被上下文中断
+func TestWaitCancelled(t *testing.T) {
+	crawler := createTestCrawler(t)
+	crawler.SetRequestInterval(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := crawler.wait(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected wait to return immediately, took %v", elapsed)
+	}
+}
+
 // TestServiceTreeNodeStructure 测试服务树节点结构
 func TestServiceTreeNodeStructure(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	skipWithoutDemo(t)
 
 	crawler := createTestCrawler(t)
 	ctx := context.Background()
@@ -406,9 +436,7 @@ func validateNodeStructure(t *testing.T, node *models.ServiceTreeNode, currentLe
 
 // BenchmarkCrawlAllServiceTrees 性能测试
 func BenchmarkCrawlAllServiceTrees(b *testing.B) {
-	if testing.Short() {
-		b.Skip("Skipping benchmark in short mode")
-	}
+	skipWithoutDemo(b)
 
 	crawler := createTestCrawler(&testing.T{})
 	crawler.SetMaxDepth(1) // 限制深度以减少基准测试时间
@@ -424,3 +452,295 @@ func BenchmarkCrawlAllServiceTrees(b *testing.B) {
 		}
 	}
 }
+
+// TestCrawlSharedNodes 测试多对多关系：出现在多个父节点下的CI被标记为共享且只计一次
+func TestCrawlSharedNodes(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+	// env-a1 同时属于两个产品
+	f.addRelation(11, 100, "")
+
+	trees, err := f.newCrawler().SetIncludeM2M(true).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+
+	tree := trees[0]
+	if tree.TotalNodes != 6 {
+		t.Errorf("Expected shared node to be counted once in 6 nodes, got %d", tree.TotalNodes)
+	}
+	if len(tree.SharedNodes) != 1 || tree.SharedNodes[0].ID != 100 {
+		t.Fatalf("Expected node 100 to be shared, got %+v", tree.SharedNodes)
+	}
+	if parents := tree.SharedNodes[0].ParentIDs; len(parents) != 2 || parents[0] != 10 || parents[1] != 11 {
+		t.Errorf("Expected parents [10 11], got %v", parents)
+	}
+
+	occurrences := 0
+	for _, root := range tree.RootNodes {
+		for _, node := range root.GetAllDescendants() {
+			if node.ID == 100 {
+				occurrences++
+				if !node.Shared {
+					t.Errorf("Expected every occurrence of node 100 to be marked shared")
+				}
+			} else if node.Shared {
+				t.Errorf("Expected node %d not to be shared", node.ID)
+			}
+		}
+	}
+	if occurrences != 2 {
+		t.Errorf("Expected node 100 under both parents, got %d occurrences", occurrences)
+	}
+
+	// 统计和第二层以下的子节点查询都带上多对多参数和祖先路径
+	// 根节点一次，产品层按祖先路径分两组
+	if got := f.queryValues("ci_relations/statistics", "has_m2m"); len(got) != 3 || got[0] != "1" || got[1] != "1" || got[2] != "1" {
+		t.Errorf("Expected 3 statistics queries with has_m2m=1, got %v", got)
+	}
+	ancestors := f.queryValues("ci_relations/s", "ancestor_ids")
+	if len(ancestors) != 2 || (ancestors[0] != "1" && ancestors[0] != "2") {
+		t.Errorf("Expected ancestor_ids for the product level queries, got %v", ancestors)
+	}
+
+	// 流式模式下开启m2m时同样去重计数
+	f = newFakeCMDB(t)
+	seedSimpleTree(f)
+	f.addRelation(11, 100, "")
+	trees, err = f.newCrawler().SetIncludeStats(false).SetIncludeM2M(true).SetNodeSink(newRecordingSink()).
+		CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Streaming crawl failed: %v", err)
+	}
+	if trees[0].TotalNodes != 6 || len(trees[0].SharedNodes) != 1 {
+		t.Errorf("Expected 6 nodes and 1 shared node when streaming, got %d and %+v", trees[0].TotalNodes, trees[0].SharedNodes)
+	}
+}
+
+// TestCrawlLevelConstraints 测试按视图层级约束决定多对多查询参数
+func TestCrawlLevelConstraints(t *testing.T) {
+	tests := []struct {
+		name          string
+		constraints   map[string]string
+		wantM2M       bool
+		wantAncestors bool
+		wantShared    int
+		// wantDropped 一对一约束下被丢弃的子CI
+		wantDropped []int
+	}{
+		{
+			name:        "one-to-many",
+			constraints: map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintOneToMany},
+		},
+		{
+			name:        "one-to-one",
+			constraints: map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintOneToOne},
+			wantDropped: []int{102},
+		},
+		{
+			name:          "many-to-many",
+			constraints:   map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintManyToMany},
+			wantM2M:       true,
+			wantAncestors: true,
+			wantShared:    1,
+		},
+		{
+			// 只有叶子展示类型为多对多时，树内路径唯一，不需要祖先路径
+			name:        "many-to-many leaf",
+			constraints: map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintOneToMany, "3": models.ConstraintManyToMany},
+			wantM2M:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeCMDB(t)
+			seedSimpleTree(f)
+			view := simpleView()
+			view["level2constraint"] = tt.constraints
+			f.addView("product", view)
+			// product-a1 下有两个环境，一对一约束下只保留第一个
+			f.addCI(102, 3, "env-a2", 10)
+			if tt.wantShared > 0 {
+				f.addRelation(11, 100, "")
+			}
+
+			core, logs := observer.New(zap.WarnLevel)
+			trees, err := NewServiceTreeCrawler(f.newClient(), zap.New(core)).
+				SetRequestInterval(0).
+				SetPageSize(100).
+				CrawlAllServiceTrees(context.Background())
+			if err != nil {
+				t.Fatalf("Crawl failed: %v", err)
+			}
+
+			tree := trees[0]
+			if len(tree.SharedNodes) != tt.wantShared {
+				t.Errorf("Expected %d shared nodes, got %+v", tt.wantShared, tree.SharedNodes)
+			}
+			if want := 7 - len(tt.wantDropped); tree.TotalNodes != want {
+				t.Errorf("Expected %d nodes, got %d", want, tree.TotalNodes)
+			}
+			product := tree.RootNodes[0].Children[0]
+			if want := 2 - len(tt.wantDropped); len(product.Children) != want || product.Children[0].ID != 100 {
+				t.Errorf("Expected %d environments under product-a1 starting with 100, got %+v", want, product.Children)
+			}
+
+			warnings := logs.FilterMessage("One-to-one relation has multiple children, keeping the first").All()
+			dropped := make([]interface{}, len(tt.wantDropped))
+			for i, id := range tt.wantDropped {
+				dropped[i] = id
+			}
+			if len(tt.wantDropped) == 0 && len(warnings) != 0 {
+				t.Errorf("Expected no constraint warnings, got %d", len(warnings))
+			} else if len(tt.wantDropped) > 0 && (len(warnings) != 1 || warnings[0].ContextMap()["node_id"] != int64(10) ||
+				!reflect.DeepEqual(warnings[0].ContextMap()["dropped_ids"], dropped)) {
+				t.Errorf("Expected one warning recording dropped CIs %v, got %+v", tt.wantDropped, warnings)
+			}
+
+			wantM2M := "0"
+			if tt.wantM2M {
+				wantM2M = "1"
+			}
+			for _, got := range f.queryValues("ci_relations/statistics", "has_m2m") {
+				if got != wantM2M {
+					t.Errorf("Expected statistics with has_m2m=%s, got %s", wantM2M, got)
+				}
+			}
+			// 单个父节点和批量查询子节点分别走 ci_relations/s 和 ci_relations/search/full
+			childM2M := append(f.queryValues("ci_relations/s", "has_m2m"), f.queryValues("ci_relations/search/full", "has_m2m")...)
+			if tt.wantM2M != (len(childM2M) > 0) {
+				t.Errorf("Expected has_m2m on child queries to be %v, got %v", tt.wantM2M, childM2M)
+			}
+			if got := f.queryValues("ci_relations/s", "ancestor_ids"); tt.wantAncestors != (len(got) > 0) {
+				t.Errorf("Expected ancestor_ids on child queries to be %v, got %v", tt.wantAncestors, got)
+			}
+		})
+	}
+}
+
+// TestCrawlLeafShowTypes 测试叶子节点下按leaf2show_types展开展示类型的CI
+func TestCrawlLeafShowTypes(t *testing.T) {
+	newView := func(showLeafNode bool) map[string]interface{} {
+		view := simpleView()
+		view["leaf2show_types"] = map[string][]int{"3": {4}}
+		view["node2show_types"] = map[string][]map[string]interface{}{
+			"1": {{"id": 4, "name": "server", "alias": "服务器"}},
+			"2": {{"id": 4, "name": "server", "alias": "服务器"}},
+			"3": {{"id": 4, "name": "server", "alias": "服务器"}},
+		}
+		view["level2constraint"] = map[string]string{"1": models.ConstraintOneToMany, "2": models.ConstraintOneToMany, "3": models.ConstraintManyToMany}
+		view["option"] = map[string]interface{}{"is_show_leaf_node": showLeafNode}
+		return view
+	}
+	seed := func(f *fakeCMDB, showLeafNode bool) {
+		seedSimpleTree(f)
+		f.addView("product", newView(showLeafNode))
+		// server-1 同时部署在两个环境
+		f.addCI(1000, 4, "server-1", 100)
+		f.addCI(1001, 4, "server-2", 101)
+		f.addRelation(101, 1000, "")
+	}
+
+	// 逐个节点查询，便于按请求数确认叶子节点的查询
+	f := newFakeCMDB(t)
+	seed(f, true)
+	trees, err := f.newCrawler().SetBatchSize(1).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+
+	tree := trees[0]
+	if tree.TotalNodes != 8 || tree.MaxDepth != 4 {
+		t.Errorf("Expected 8 nodes and depth 4 with leaf CIs, got %d nodes and depth %d", tree.TotalNodes, tree.MaxDepth)
+	}
+	if tree.ID2Type["4"].Alias != "服务器" {
+		t.Errorf("Expected show type in id2type, got %+v", tree.ID2Type)
+	}
+	env := tree.RootNodes[0].Children[0].Children[0]
+	if env.IsLeaf || env.ChildCount != 1 {
+		t.Errorf("Expected env node with 1 leaf CI, got is_leaf=%v child_count=%d", env.IsLeaf, env.ChildCount)
+	}
+	if server := env.Children[0]; server.ID != 1000 || server.TypeName != "服务器" || !server.IsLeaf || server.Level != 3 {
+		t.Errorf("Unexpected leaf CI: %+v", server)
+	}
+	if len(tree.SharedNodes) != 1 || tree.SharedNodes[0].ID != 1000 {
+		t.Errorf("Expected server-1 to be shared by both envs, got %+v", tree.SharedNodes)
+	}
+	if got := f.requestCount("ci_relations/s"); got != 6 {
+		t.Errorf("Expected 4 tree level queries and 2 leaf queries, got %d", got)
+	}
+
+	// 关闭叶子节点展示时不查询展示类型
+	f = newFakeCMDB(t)
+	seed(f, false)
+	trees, err = f.newCrawler().SetBatchSize(1).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+	if trees[0].TotalNodes != 6 || f.requestCount("ci_relations/s") != 4 {
+		t.Errorf("Expected no leaf CIs when is_show_leaf_node is false, got %d nodes and %d queries",
+			trees[0].TotalNodes, f.requestCount("ci_relations/s"))
+	}
+}
+
+// TestCrawlNodeStatistics 测试每层非叶子节点按叶子类型分组的统计，同一层批量请求
+func TestCrawlNodeStatistics(t *testing.T) {
+	f := newFakeCMDB(t)
+	// 产品线(1) > 产品(2)/文档(5) > 环境(3)，文档是第二层的叶子类型
+	f.addView("product", map[string]interface{}{
+		"topo":         [][]int{{1}, {2, 5}, {3}},
+		"topo_flatten": []int{1, 2, 5, 3},
+		"leaf":         []int{5, 3},
+	})
+	f.addCIType(1, "line", "产品线")
+	f.addCIType(2, "product", "产品")
+	f.addCIType(3, "env", "环境")
+	f.addCIType(5, "doc", "文档")
+	f.addCI(1, 1, "line-a", 0)
+	f.addCI(2, 1, "line-b", 0)
+	f.addCI(10, 2, "product-a1", 1)
+	f.addCI(11, 2, "product-b1", 2)
+	f.addCI(12, 5, "doc-a", 1)
+	f.addCI(100, 3, "env-a1", 10)
+	f.addCI(101, 3, "env-a2", 10)
+	f.addCI(102, 3, "env-b1", 11)
+
+	trees, err := f.newCrawler().CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+
+	nodes := make(map[int]*models.ServiceTreeNode)
+	for _, root := range trees[0].RootNodes {
+		nodes[root.ID] = root
+		for _, node := range root.GetAllDescendants() {
+			nodes[node.ID] = node
+		}
+	}
+
+	expected := map[int]map[string]int{
+		1:  {models.StatTotalDescendants: 3, "env": 2, "doc": 1},
+		2:  {models.StatTotalDescendants: 1, "env": 1},
+		10: {models.StatTotalDescendants: 2, "env": 2},
+		11: {models.StatTotalDescendants: 1, "env": 1},
+	}
+	for id, want := range expected {
+		if got := nodes[id].Statistics; !reflect.DeepEqual(got, want) {
+			t.Errorf("Node %d: expected statistics %v, got %v", id, want, got)
+		}
+	}
+	for _, id := range []int{12, 100, 101, 102} {
+		if nodes[id].Statistics != nil {
+			t.Errorf("Expected leaf node %d to have no statistics, got %v", id, nodes[id].Statistics)
+		}
+	}
+
+	// 根节点每个叶子层一次，产品层一次
+	if got := f.requestCount("ci_relations/statistics"); got != 3 {
+		t.Errorf("Expected 3 batched statistics requests, got %d", got)
+	}
+	if got := f.queryValues("ci_relations/statistics", "root_ids"); got[len(got)-1] != "10,11" {
+		t.Errorf("Expected product level to be queried in one batch, got %v", got)
+	}
+}
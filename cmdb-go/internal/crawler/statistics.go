@@ -0,0 +1,182 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// statisticsBatchSize 单次统计请求的最大root_ids数量
+const statisticsBatchSize = 500
+
+// statsTarget 待加载统计信息的节点及其祖先路径
+type statsTarget struct {
+	node      *models.ServiceTreeNode
+	ancestors []int
+}
+
+// loadTreeStatistics 为根节点以下每一层的非叶子节点加载叶子统计，同一层的节点批量查询
+func (c *ServiceTreeCrawler) loadTreeStatistics(ctx context.Context, rootNodes []*models.ServiceTreeNode,
+	viewConfig models.ServiceTreeView, id2Type map[string]models.CIType) {
+
+	var targets []statsTarget
+	for _, root := range rootNodes {
+		targets = append(targets, statsTarget{node: root})
+	}
+
+	for level := 1; level < len(viewConfig.Topo); level++ {
+		var next []statsTarget
+		for _, target := range targets {
+			ancestors := append(append(make([]int, 0, len(target.ancestors)+1), target.ancestors...), target.node.ID)
+			for _, child := range target.node.Children {
+				next = append(next, statsTarget{node: child, ancestors: ancestors})
+			}
+		}
+		if len(next) == 0 {
+			return
+		}
+
+		if err := c.loadLevelStatistics(ctx, level, next, viewConfig, id2Type); err != nil {
+			c.logger.Warn("Failed to load node statistics",
+				zap.Int("level", level),
+				zap.Error(err))
+		}
+		targets = next
+	}
+}
+
+// loadLevelStatistics 加载同一层节点到各叶子类型的统计
+// 每个叶子类型所在层一次请求，多对多关系下按祖先路径分组，每组root_ids超过批大小时拆分
+func (c *ServiceTreeCrawler) loadLevelStatistics(ctx context.Context, level int, targets []statsTarget,
+	viewConfig models.ServiceTreeView, id2Type map[string]models.CIType) error {
+
+	leafLevels := viewLeafLevels(viewConfig)
+	withAncestors := c.includeM2M || viewConfig.TreeHasM2M()
+	hasM2M := 0
+	if c.includeM2M || viewConfig.HasM2M() {
+		hasM2M = 1
+	}
+
+	// 只统计属于该层类型的非叶子节点，叶子节点下展示的CI不参与
+	levelTypes := make(map[int]bool)
+	if level < len(viewConfig.Topo) {
+		for _, id := range viewConfig.Topo[level] {
+			levelTypes[id] = !viewConfig.IsLeafType(id)
+		}
+	}
+
+	var groupKeys []string
+	groups := make(map[string][]statsTarget)
+	for _, target := range targets {
+		if !levelTypes[target.node.Type] {
+			continue
+		}
+		key := ""
+		if withAncestors {
+			key = joinInts(target.ancestors)
+		}
+		if _, ok := groups[key]; !ok {
+			groupKeys = append(groupKeys, key)
+		}
+		groups[key] = append(groups[key], target)
+	}
+
+	for _, key := range groupKeys {
+		nodesByID := make(map[int][]*models.ServiceTreeNode)
+		var ids []int
+		for _, target := range groups[key] {
+			if _, ok := nodesByID[target.node.ID]; !ok {
+				ids = append(ids, target.node.ID)
+			}
+			nodesByID[target.node.ID] = append(nodesByID[target.node.ID], target.node)
+			target.node.Statistics = map[string]int{models.StatTotalDescendants: 0}
+		}
+
+		for start := 0; start < len(ids); start += statisticsBatchSize {
+			end := start + statisticsBatchSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			batch := ids[start:end]
+
+			for _, leafLevel := range sortedLevels(leafLevels) {
+				if leafLevel <= level {
+					continue
+				}
+
+				if err := c.wait(ctx); err != nil {
+					return err
+				}
+
+				params := map[string]interface{}{
+					"root_ids": joinInts(batch),
+					"level":    leafLevel - level,
+					"type_ids": leafLevels[leafLevel],
+					"has_m2m":  hasM2M,
+				}
+				if key != "" {
+					params["ancestor_ids"] = key
+				}
+
+				stats, err := c.client.GetCIRelationStatistics(ctx, params)
+				if err != nil {
+					return fmt.Errorf("failed to get statistics: %w", err)
+				}
+
+				for _, id := range batch {
+					rootID := strconv.Itoa(id)
+					counts := stats.GetTypeCounts(rootID)
+					for _, node := range nodesByID[id] {
+						node.Statistics[models.StatTotalDescendants] += stats.GetCount(rootID)
+						for typeID, count := range counts {
+							node.Statistics[statTypeKey(typeID, id2Type)] += count
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// viewLeafLevels 按叶子类型所在的Topo层分组
+func viewLeafLevels(viewConfig models.ServiceTreeView) map[int][]int {
+	levels := make(map[int][]int)
+	for level, typeIDs := range viewConfig.Topo {
+		for _, id := range typeIDs {
+			if viewConfig.IsLeafType(id) {
+				levels[level] = append(levels[level], id)
+			}
+		}
+	}
+	return levels
+}
+
+// sortedLevels 返回按层级排序的层号
+func sortedLevels(levels map[int][]int) []int {
+	keys := make([]int, 0, len(levels))
+	for level := range levels {
+		keys = append(keys, level)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// statTypeKey 统计信息中叶子类型的键，优先使用类型名
+func statTypeKey(typeID string, id2Type map[string]models.CIType) string {
+	if ciType, ok := id2Type[typeID]; ok && ciType.Name != "" {
+		return ciType.Name
+	}
+	return "type_" + typeID
+}
+
+// joinInts 将整数列表用逗号连接
+func joinInts(ids []int) string {
+	return joinIDs(ids, ",")
+}
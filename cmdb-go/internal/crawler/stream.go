@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"cmdb-crawler/internal/models"
+)
+
+// errNodeSink 流式输出写入失败，需要中止爬取而不是跳过节点
+var errNodeSink = errors.New("failed to write node to sink")
+
+// NodeSink 节点流式输出接口，爬取器发现节点时立即推送
+// 实现需要支持多个goroutine并发调用
+type NodeSink interface {
+	WriteNode(viewName string, viewID int, parentID int, node *models.ServiceTreeNode) error
+}
+
+// SetNodeSink 设置节点流式输出
+// 设置后节点在发现时即写入sink，结果中只保留不含子节点的根节点以及节点总数、最大深度和共享节点，
+// 内存中不保留完整的树；多对多视图或 SetIncludeM2M 时仍需为每个CI保留父节点ID以识别共享节点和去重计数，
+// 占用的内存随CI数量增长。流式模式下不使用检查点和增量基线，已写出的节点不会标记 shared，
+// 共享节点只记录在 SharedNodes 中
+func (c *ServiceTreeCrawler) SetNodeSink(sink NodeSink) *ServiceTreeCrawler {
+	c.sink = sink
+	return c
+}
+
+// nodeEmitter 单个视图的流式输出状态
+type nodeEmitter struct {
+	sink     NodeSink
+	viewName string
+	viewID   int
+
+	mu       sync.Mutex
+	total    int
+	maxDepth int
+	// 每个CI的父节点ID，用于去重计数和识别多父节点，只在可能出现共享节点时记录，否则为nil
+	parents map[int][]int
+	shared  []models.SharedNode
+}
+
+// newNodeEmitter 创建视图的流式输出，未设置sink时返回nil
+// 只有多对多视图或开启 includeM2M 时同一CI才会出现在多个父节点下，其余视图直接计数不保留CI的父节点
+func (c *ServiceTreeCrawler) newNodeEmitter(viewName string, viewID int, viewConfig models.ServiceTreeView) *nodeEmitter {
+	if c.sink == nil {
+		return nil
+	}
+
+	emitter := &nodeEmitter{sink: c.sink, viewName: viewName, viewID: viewID}
+	if c.includeM2M || viewConfig.HasM2M() {
+		emitter.parents = make(map[int][]int)
+	}
+	return emitter
+}
+
+// emit 写入节点并更新统计，nil emitter不做任何处理
+func (e *nodeEmitter) emit(parentID int, node *models.ServiceTreeNode) error {
+	if e == nil {
+		return nil
+	}
+
+	if err := e.sink.WriteNode(e.viewName, e.viewID, parentID, node); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if node.Level+1 > e.maxDepth {
+		e.maxDepth = node.Level + 1
+	}
+	if e.parents == nil {
+		e.total++
+		return nil
+	}
+
+	parents, seen := e.parents[node.ID]
+	if !seen {
+		e.total++
+	}
+	if parentID != 0 && !slices.Contains(parents, parentID) {
+		parents = append(parents, parentID)
+		if len(parents) == 2 {
+			e.shared = append(e.shared, models.SharedNode{
+				ID:       node.ID,
+				Type:     node.Type,
+				TypeName: node.TypeName,
+				Name:     node.Name,
+			})
+		}
+	}
+	e.parents[node.ID] = parents
+	return nil
+}
+
+// apply 将流式统计写入服务树数据，根节点的子节点已在流式输出中丢弃
+func (e *nodeEmitter) apply(treeData *models.ServiceTreeData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, root := range treeData.RootNodes {
+		root.Children = nil
+	}
+	treeData.TotalNodes = e.total
+	treeData.MaxDepth = e.maxDepth
+
+	treeData.SharedNodes = nil
+	for _, shared := range e.shared {
+		shared.ParentIDs = e.parents[shared.ID]
+		treeData.SharedNodes = append(treeData.SharedNodes, shared)
+	}
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+)
+
+// recordingSink 记录写入节点的测试sink
+type recordingSink struct {
+	mu      sync.Mutex
+	parents map[int]int
+	paths   map[int]string
+	failOn  int
+}
+
+// newRecordingSink 创建测试sink
+func newRecordingSink() *recordingSink {
+	return &recordingSink{parents: make(map[int]int), paths: make(map[int]string)}
+}
+
+// WriteNode 记录节点的父节点和路径，failOn指定的节点返回错误
+func (s *recordingSink) WriteNode(viewName string, viewID int, parentID int, node *models.ServiceTreeNode) error {
+	if node.ID == s.failOn {
+		return errors.New("disk full")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parents[node.ID] = parentID
+	s.paths[node.ID] = node.BuildTreePath()
+	return nil
+}
+
+// TestCrawlWithNodeSink 测试流式模式下节点写入sink且不保留子节点
+func TestCrawlWithNodeSink(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	sink := newRecordingSink()
+	trees, err := f.newCrawler().SetIncludeStats(false).SetNodeSink(sink).CrawlAllServiceTrees(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+
+	if len(sink.parents) != 6 {
+		t.Fatalf("Expected 6 streamed nodes, got %d", len(sink.parents))
+	}
+	if sink.parents[1] != 0 || sink.parents[10] != 1 || sink.parents[100] != 10 {
+		t.Errorf("Unexpected parent IDs: %v", sink.parents)
+	}
+	if sink.paths[100] != "line-a > product-a1 > env-a1" {
+		t.Errorf("Unexpected path for node 100: %s", sink.paths[100])
+	}
+
+	tree := trees[0]
+	if tree.TotalNodes != 6 || tree.MaxDepth != 3 || len(tree.RootNodes) != 2 {
+		t.Errorf("Expected 2 roots, 6 nodes and depth 3, got %d roots, %d nodes, depth %d",
+			len(tree.RootNodes), tree.TotalNodes, tree.MaxDepth)
+	}
+	for _, root := range tree.RootNodes {
+		if len(root.Children) != 0 || root.ChildCount != 1 {
+			t.Errorf("Expected root %d without retained children but child count 1, got %+v", root.ID, root)
+		}
+	}
+
+	// 非多对多视图不为每个CI保留父节点
+	if emitter := f.newCrawler().SetNodeSink(sink).newNodeEmitter(tree.ViewName, tree.ViewID, tree.Config); emitter.parents != nil {
+		t.Error("Expected no per-CI parent map without many-to-many relations")
+	}
+}
+
+// TestCrawlWithNodeSinkFailure 测试sink写入失败时中止爬取
+func TestCrawlWithNodeSinkFailure(t *testing.T) {
+	f := newFakeCMDB(t)
+	seedSimpleTree(f)
+
+	sink := newRecordingSink()
+	sink.failOn = 100
+	_, err := f.newCrawler().SetIncludeStats(false).SetNodeSink(sink).CrawlAllServiceTrees(context.Background())
+	if !errors.Is(err, errNodeSink) {
+		t.Fatalf("Expected sink error to abort the crawl, got %v", err)
+	}
+}
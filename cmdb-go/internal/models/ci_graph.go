@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// 关系图遍历方向
+const (
+	// GraphDirectionDown 沿 second_cis 向下查找子CI
+	GraphDirectionDown = "down"
+	// GraphDirectionUp 沿 first_cis 向上查找父CI
+	GraphDirectionUp = "up"
+	// GraphDirectionBoth 同时向上和向下
+	GraphDirectionBoth = "both"
+)
+
+// SecondCIsResponse /ci_relations/<id>/second_cis API响应
+type SecondCIsResponse struct {
+	NumFound  int          `json:"numfound"`
+	Total     int          `json:"total"`
+	Page      int          `json:"page"`
+	SecondCIs []CIInstance `json:"second_cis"`
+}
+
+// FirstCIsResponse /ci_relations/<id>/first_cis API响应
+type FirstCIsResponse struct {
+	NumFound int          `json:"numfound"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	FirstCIs []CIInstance `json:"first_cis"`
+}
+
+// CIGraphNode 关系图节点
+type CIGraphNode struct {
+	ID       int    `json:"id"`
+	Type     int    `json:"type"`
+	TypeName string `json:"type_name"`
+	Name     string `json:"name"`
+	// Depth 距最近种子节点的跳数，种子为0
+	Depth      int                    `json:"depth"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// CIGraphEdge 关系图中的有向边，方向为父CI(first_ci)指向子CI(second_ci)
+type CIGraphEdge struct {
+	Source int `json:"source"`
+	Target int `json:"target"`
+	// RelationType 关系类型名称（如 contain、deploy），无法从类型关系推断时为空
+	RelationType string `json:"relation_type,omitempty"`
+	// Constraint 类型关系约束，取值见 Constraint* 常量
+	Constraint string `json:"constraint,omitempty"`
+}
+
+// CIGraph 从种子CI出发按关系广度优先遍历得到的关系图，包含多对多关系
+type CIGraph struct {
+	SeedQuery string         `json:"seed_query"`
+	Seeds     []int          `json:"seeds"`
+	Direction string         `json:"direction"`
+	MaxDepth  int            `json:"max_depth"`
+	Nodes     []*CIGraphNode `json:"nodes"`
+	Edges     []*CIGraphEdge `json:"edges"`
+	// Truncated 达到节点数上限后停止扩展
+	Truncated bool      `json:"truncated"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// ConstraintName 返回约束的可读名称
+func ConstraintName(constraint string) string {
+	switch constraint {
+	case ConstraintOneToMany:
+		return "one-to-many"
+	case ConstraintOneToOne:
+		return "one-to-one"
+	case ConstraintManyToMany:
+		return "many-to-many"
+	default:
+		return constraint
+	}
+}
@@ -0,0 +1,337 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ChangeType 变更类型
+type ChangeType string
+
+const (
+	ChangeAdded             ChangeType = "added"
+	ChangeRemoved           ChangeType = "removed"
+	ChangeMoved             ChangeType = "moved"
+	ChangeRenamed           ChangeType = "renamed"
+	ChangeAttributesChanged ChangeType = "attributes_changed"
+)
+
+// ViewStatus 视图对比状态
+type ViewStatus string
+
+const (
+	ViewAdded     ViewStatus = "added"
+	ViewRemoved   ViewStatus = "removed"
+	ViewModified  ViewStatus = "modified"
+	ViewUnchanged ViewStatus = "unchanged"
+)
+
+// AttributeChange 单个属性的变更
+type AttributeChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// NodeChange 节点变更记录，以视图名称和节点ID为键
+type NodeChange struct {
+	ViewName     string            `json:"view_name"`
+	NodeID       int               `json:"node_id"`
+	Change       ChangeType        `json:"change"`
+	NodeType     int               `json:"node_type"`
+	TypeName     string            `json:"type_name,omitempty"`
+	Name         string            `json:"name"`
+	OldName      string            `json:"old_name,omitempty"`
+	OldParentIDs []int             `json:"old_parent_ids,omitempty"`
+	NewParentIDs []int             `json:"new_parent_ids,omitempty"`
+	OldPath      string            `json:"old_path,omitempty"`
+	NewPath      string            `json:"new_path,omitempty"`
+	Attributes   []AttributeChange `json:"attributes,omitempty"`
+}
+
+// DiffSummary 变更计数
+type DiffSummary struct {
+	Added             int `json:"added"`
+	Removed           int `json:"removed"`
+	Moved             int `json:"moved"`
+	Renamed           int `json:"renamed"`
+	AttributesChanged int `json:"attributes_changed"`
+}
+
+// Total 返回变更总数
+func (s DiffSummary) Total() int {
+	return s.Added + s.Removed + s.Moved + s.Renamed + s.AttributesChanged
+}
+
+// add 累加一条变更
+func (s *DiffSummary) add(change ChangeType) {
+	switch change {
+	case ChangeAdded:
+		s.Added++
+	case ChangeRemoved:
+		s.Removed++
+	case ChangeMoved:
+		s.Moved++
+	case ChangeRenamed:
+		s.Renamed++
+	case ChangeAttributesChanged:
+		s.AttributesChanged++
+	}
+}
+
+// ViewDiff 单个视图的变更
+type ViewDiff struct {
+	ViewName     string       `json:"view_name"`
+	Status       ViewStatus   `json:"status"`
+	OldCrawledAt time.Time    `json:"old_crawled_at,omitempty"`
+	NewCrawledAt time.Time    `json:"new_crawled_at,omitempty"`
+	Summary      DiffSummary  `json:"summary"`
+	Changes      []NodeChange `json:"changes,omitempty"`
+}
+
+// TreeDiff 两次服务树快照的变更报告
+type TreeDiff struct {
+	Summary DiffSummary `json:"summary"`
+	Views   []ViewDiff  `json:"views"`
+}
+
+// HasChanges 判断是否存在变更
+func (d *TreeDiff) HasChanges() bool {
+	for _, view := range d.Views {
+		if view.Status != ViewUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// diffNode 对比时的节点快照
+type diffNode struct {
+	node      *ServiceTreeNode
+	parentIDs []int
+}
+
+// DiffServiceTrees 对比两次爬取的服务树，视图按名称匹配，节点按ID匹配
+func DiffServiceTrees(oldTrees, newTrees []*ServiceTreeData) *TreeDiff {
+	oldByName := make(map[string]*ServiceTreeData, len(oldTrees))
+	for _, tree := range oldTrees {
+		oldByName[tree.ViewName] = tree
+	}
+	newByName := make(map[string]*ServiceTreeData, len(newTrees))
+	for _, tree := range newTrees {
+		newByName[tree.ViewName] = tree
+	}
+
+	names := make([]string, 0, len(oldByName)+len(newByName))
+	for name := range oldByName {
+		names = append(names, name)
+	}
+	for name := range newByName {
+		if _, exists := oldByName[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := &TreeDiff{Views: make([]ViewDiff, 0, len(names))}
+	for _, name := range names {
+		view := diffView(name, oldByName[name], newByName[name])
+		diff.Summary.Added += view.Summary.Added
+		diff.Summary.Removed += view.Summary.Removed
+		diff.Summary.Moved += view.Summary.Moved
+		diff.Summary.Renamed += view.Summary.Renamed
+		diff.Summary.AttributesChanged += view.Summary.AttributesChanged
+		diff.Views = append(diff.Views, view)
+	}
+
+	return diff
+}
+
+// diffView 对比单个视图，oldTree或newTree为nil表示视图被新增或删除
+func diffView(name string, oldTree, newTree *ServiceTreeData) ViewDiff {
+	view := ViewDiff{ViewName: name}
+	if oldTree != nil {
+		view.OldCrawledAt = oldTree.CrawledAt
+	}
+	if newTree != nil {
+		view.NewCrawledAt = newTree.CrawledAt
+	}
+
+	oldNodes := flattenForDiff(oldTree)
+	newNodes := flattenForDiff(newTree)
+
+	addChange := func(change NodeChange) {
+		change.ViewName = name
+		view.Summary.add(change.Change)
+		view.Changes = append(view.Changes, change)
+	}
+
+	for _, id := range sortedNodeIDs(oldNodes, newNodes) {
+		oldNode, inOld := oldNodes[id]
+		newNode, inNew := newNodes[id]
+
+		switch {
+		case !inOld:
+			addChange(NodeChange{
+				NodeID:       id,
+				Change:       ChangeAdded,
+				NodeType:     newNode.node.Type,
+				TypeName:     newNode.node.TypeName,
+				Name:         newNode.node.Name,
+				NewParentIDs: newNode.parentIDs,
+				NewPath:      newNode.node.BuildTreePath(),
+			})
+		case !inNew:
+			addChange(NodeChange{
+				NodeID:       id,
+				Change:       ChangeRemoved,
+				NodeType:     oldNode.node.Type,
+				TypeName:     oldNode.node.TypeName,
+				Name:         oldNode.node.Name,
+				OldParentIDs: oldNode.parentIDs,
+				OldPath:      oldNode.node.BuildTreePath(),
+			})
+		default:
+			base := NodeChange{
+				NodeID:   id,
+				NodeType: newNode.node.Type,
+				TypeName: newNode.node.TypeName,
+				Name:     newNode.node.Name,
+			}
+
+			if !reflect.DeepEqual(oldNode.parentIDs, newNode.parentIDs) {
+				change := base
+				change.Change = ChangeMoved
+				change.OldParentIDs = oldNode.parentIDs
+				change.NewParentIDs = newNode.parentIDs
+				change.OldPath = oldNode.node.BuildTreePath()
+				change.NewPath = newNode.node.BuildTreePath()
+				addChange(change)
+			}
+
+			if oldNode.node.Name != newNode.node.Name {
+				change := base
+				change.Change = ChangeRenamed
+				change.OldName = oldNode.node.Name
+				addChange(change)
+			}
+
+			if attrs := diffAttributes(oldNode.node.Attributes, newNode.node.Attributes); len(attrs) > 0 {
+				change := base
+				change.Change = ChangeAttributesChanged
+				change.Attributes = attrs
+				addChange(change)
+			}
+		}
+	}
+
+	switch {
+	case oldTree == nil:
+		view.Status = ViewAdded
+	case newTree == nil:
+		view.Status = ViewRemoved
+	case len(view.Changes) > 0:
+		view.Status = ViewModified
+	default:
+		view.Status = ViewUnchanged
+	}
+
+	return view
+}
+
+// flattenForDiff 展开服务树，同一节点出现在多个父节点下时合并父节点ID
+func flattenForDiff(tree *ServiceTreeData) map[int]*diffNode {
+	nodes := make(map[int]*diffNode)
+	if tree == nil {
+		return nodes
+	}
+
+	var walk func(node *ServiceTreeNode, parentID int)
+	walk = func(node *ServiceTreeNode, parentID int) {
+		entry, exists := nodes[node.ID]
+		if !exists {
+			entry = &diffNode{node: node, parentIDs: []int{}}
+			nodes[node.ID] = entry
+		}
+		if parentID > 0 {
+			entry.parentIDs = appendUnique(entry.parentIDs, parentID)
+		}
+		for _, child := range node.Children {
+			walk(child, node.ID)
+		}
+	}
+
+	for _, root := range tree.RootNodes {
+		walk(root, 0)
+	}
+
+	for _, entry := range nodes {
+		sort.Ints(entry.parentIDs)
+	}
+
+	return nodes
+}
+
+// diffAttributes 对比节点属性，按属性名排序返回
+func diffAttributes(oldAttrs, newAttrs map[string]interface{}) []AttributeChange {
+	keys := make(map[string]bool, len(oldAttrs)+len(newAttrs))
+	for k := range oldAttrs {
+		keys[k] = true
+	}
+	for k := range newAttrs {
+		keys[k] = true
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	var changes []AttributeChange
+	for _, k := range sortedKeys {
+		oldValue, newValue := oldAttrs[k], newAttrs[k]
+		if !attributeValuesEqual(oldValue, newValue) {
+			changes = append(changes, AttributeChange{Key: k, Old: oldValue, New: newValue})
+		}
+	}
+
+	return changes
+}
+
+// attributeValuesEqual 比较属性值，JSON与YAML解码出的数字类型不同，退化为按JSON编码比较
+func attributeValuesEqual(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// sortedNodeIDs 返回两侧节点ID的并集（升序）
+func sortedNodeIDs(a, b map[int]*diffNode) []int {
+	ids := make([]int, 0, len(a)+len(b))
+	for id := range a {
+		ids = append(ids, id)
+	}
+	for id := range b {
+		if _, exists := a[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// appendUnique 追加不重复的ID
+func appendUnique(ids []int, id int) []int {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
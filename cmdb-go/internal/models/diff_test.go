@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+)
+
+// buildDiffTree 构建对比测试用的服务树
+func buildDiffTree(viewName string, build func(root *ServiceTreeNode)) *ServiceTreeData {
+	root := &ServiceTreeNode{ID: 1, Type: 1, Name: "line"}
+	build(root)
+	return &ServiceTreeData{ViewName: viewName, RootNodes: []*ServiceTreeNode{root}}
+}
+
+// TestDiffServiceTrees 测试节点新增、删除、移动、重命名和属性变更
+func TestDiffServiceTrees(t *testing.T) {
+	oldTree := buildDiffTree("product", func(root *ServiceTreeNode) {
+		a := &ServiceTreeNode{ID: 10, Type: 2, Name: "app-a", Attributes: map[string]interface{}{"owner": "alice", "port": float64(80)}}
+		b := &ServiceTreeNode{ID: 11, Type: 2, Name: "app-b"}
+		root.AddChild(a)
+		root.AddChild(b)
+		a.AddChild(&ServiceTreeNode{ID: 100, Type: 3, Name: "env"})
+		b.AddChild(&ServiceTreeNode{ID: 101, Type: 3, Name: "gone"})
+	})
+
+	newTree := buildDiffTree("product", func(root *ServiceTreeNode) {
+		a := &ServiceTreeNode{ID: 10, Type: 2, Name: "app-a", Attributes: map[string]interface{}{"owner": "bob", "port": 80}}
+		b := &ServiceTreeNode{ID: 11, Type: 2, Name: "app-b2"}
+		root.AddChild(a)
+		root.AddChild(b)
+		b.AddChild(&ServiceTreeNode{ID: 100, Type: 3, Name: "env"})
+		b.AddChild(&ServiceTreeNode{ID: 102, Type: 3, Name: "new"})
+	})
+
+	diff := DiffServiceTrees([]*ServiceTreeData{oldTree}, []*ServiceTreeData{newTree})
+
+	expected := DiffSummary{Added: 1, Removed: 1, Moved: 1, Renamed: 1, AttributesChanged: 1}
+	if diff.Summary != expected {
+		t.Fatalf("Expected summary %+v, got %+v", expected, diff.Summary)
+	}
+
+	changes := make(map[ChangeType]NodeChange)
+	for _, change := range diff.Views[0].Changes {
+		changes[change.Change] = change
+	}
+
+	if c := changes[ChangeAdded]; c.NodeID != 102 {
+		t.Errorf("Expected node 102 added, got %+v", c)
+	}
+	if c := changes[ChangeRemoved]; c.NodeID != 101 {
+		t.Errorf("Expected node 101 removed, got %+v", c)
+	}
+	if c := changes[ChangeMoved]; c.NodeID != 100 || c.OldParentIDs[0] != 10 || c.NewParentIDs[0] != 11 {
+		t.Errorf("Expected node 100 moved from 10 to 11, got %+v", c)
+	}
+	if c := changes[ChangeRenamed]; c.NodeID != 11 || c.OldName != "app-b" {
+		t.Errorf("Expected node 11 renamed from app-b, got %+v", c)
+	}
+	// port在两侧分别为float64和int，不应视为变更
+	if c := changes[ChangeAttributesChanged]; len(c.Attributes) != 1 || c.Attributes[0].Key != "owner" {
+		t.Errorf("Expected only owner attribute changed, got %+v", c.Attributes)
+	}
+	if diff.Views[0].Status != ViewModified {
+		t.Errorf("Expected view status modified, got %s", diff.Views[0].Status)
+	}
+}
+
+// TestDiffServiceTreesViews 测试视图的新增、删除和未变更状态
+func TestDiffServiceTreesViews(t *testing.T) {
+	same := func(root *ServiceTreeNode) {}
+	oldTrees := []*ServiceTreeData{buildDiffTree("kept", same), buildDiffTree("dropped", same)}
+	newTrees := []*ServiceTreeData{buildDiffTree("kept", same), buildDiffTree("created", same)}
+
+	diff := DiffServiceTrees(oldTrees, newTrees)
+
+	statuses := make(map[string]ViewStatus)
+	for _, view := range diff.Views {
+		statuses[view.ViewName] = view.Status
+	}
+
+	if statuses["kept"] != ViewUnchanged {
+		t.Errorf("Expected kept to be unchanged, got %s", statuses["kept"])
+	}
+	if statuses["dropped"] != ViewRemoved {
+		t.Errorf("Expected dropped to be removed, got %s", statuses["dropped"])
+	}
+	if statuses["created"] != ViewAdded {
+		t.Errorf("Expected created to be added, got %s", statuses["created"])
+	}
+	if !diff.HasChanges() {
+		t.Error("Expected diff to report changes")
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "sort"
+
+// HistoryRecordResponse 变更历史API响应
+// 属性历史和关系历史的记录结构不同，这里保留原始数据，只提取涉及的CI ID
+type HistoryRecordResponse struct {
+	Records []interface{} `json:"records"`
+	// Total 当前页的原始历史行数（按record_id合并前），不是记录数，也不是符合条件的总数
+	Total int `json:"total"`
+}
+
+// historyCIIDKeys 历史记录中表示CI ID的字段
+var historyCIIDKeys = map[string]bool{
+	"ci_id":        true,
+	"first_ci_id":  true,
+	"second_ci_id": true,
+}
+
+// ChangedCIIDs 提取记录中涉及的所有CI ID（去重并排序）
+func (r *HistoryRecordResponse) ChangedCIIDs() []int {
+	seen := make(map[int]bool)
+	for _, record := range r.Records {
+		collectCIIDs(record, seen)
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// collectCIIDs 递归遍历记录，收集CI ID字段的值
+func collectCIIDs(value interface{}, seen map[int]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if historyCIIDKeys[key] {
+				if id, ok := item.(float64); ok && id > 0 {
+					seen[int(id)] = true
+					continue
+				}
+			}
+			collectCIIDs(item, seen)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectCIIDs(item, seen)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// CIRecord 导出用的CI实例，保留全部属性
+type CIRecord struct {
+	ID         int                    `json:"id"`
+	Type       int                    `json:"type"`
+	TypeName   string                 `json:"type_name"`
+	Name       string                 `json:"name"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// NewCIRecord 从搜索结果构建CI记录
+func NewCIRecord(ci CIInstance, typeName string) CIRecord {
+	return CIRecord{
+		ID:         ci.ID,
+		Type:       ci.Type,
+		TypeName:   typeName,
+		Name:       ci.GetDisplayName(),
+		Attributes: ci.Attrs,
+	}
+}
+
+// CITypeInventory 单个CI类型下的全部CI
+type CITypeInventory struct {
+	Type     CITypeDefinition `json:"type"`
+	NumFound int              `json:"numfound"`
+	CIs      []CIRecord       `json:"cis"`
+	// Error 该类型爬取失败时的错误信息
+	Error string `json:"error,omitempty"`
+}
+
+// CIInventory 不依赖服务树视图的全量CI清单，按类型分组
+type CIInventory struct {
+	Types     []*CITypeInventory `json:"types"`
+	TotalCIs  int                `json:"total_cis"`
+	CrawledAt time.Time          `json:"crawled_at"`
+}
+
+// CountCIs 统计CI总数
+func (inv *CIInventory) CountCIs() int {
+	total := 0
+	for _, typeInventory := range inv.Types {
+		total += len(typeInventory.CIs)
+	}
+	inv.TotalCIs = total
+	return total
+}
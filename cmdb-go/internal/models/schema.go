@@ -0,0 +1,180 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// 类型关系约束，与CMDB中ConstraintEnum的取值一致
+const (
+	ConstraintOneToMany  = "0"
+	ConstraintOneToOne   = "1"
+	ConstraintManyToMany = "2"
+)
+
+// 属性值类型，与CMDB中ValueTypeEnum的取值一致
+const (
+	ValueTypeInt      = "0"
+	ValueTypeFloat    = "1"
+	ValueTypeText     = "2"
+	ValueTypeDatetime = "3"
+	ValueTypeDate     = "4"
+	ValueTypeTime     = "5"
+	ValueTypeJSON     = "6"
+	ValueTypeBool     = "7"
+)
+
+// CITypeListResponse CI类型列表API响应
+type CITypeListResponse struct {
+	NumFound int                `json:"numfound"`
+	CITypes  []CITypeDefinition `json:"ci_types"`
+}
+
+// CITypeDefinition CI类型定义
+type CITypeDefinition struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Alias      string `json:"alias"`
+	UniqueID   int    `json:"unique_id"`
+	UniqueKey  string `json:"unique_key,omitempty"`
+	ShowID     int    `json:"show_id,omitempty"`
+	ShowName   string `json:"show_name,omitempty"`
+	Enabled    bool   `json:"enabled"`
+	IsAttached bool   `json:"is_attached"`
+	Icon       string `json:"icon,omitempty"`
+	// ParentIDs 继承的父类型
+	ParentIDs  []int         `json:"parent_ids,omitempty"`
+	Attributes []CIAttribute `json:"attributes,omitempty"`
+}
+
+// DisplayName 获取类型显示名称，优先使用别名
+func (t CITypeDefinition) DisplayName() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+// CIAttribute CI类型属性定义
+type CIAttribute struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Alias           string `json:"alias"`
+	ValueType       string `json:"value_type"`
+	IsChoice        bool   `json:"is_choice"`
+	IsList          bool   `json:"is_list"`
+	IsUnique        bool   `json:"is_unique"`
+	IsIndex         bool   `json:"is_index"`
+	IsLink          bool   `json:"is_link"`
+	IsPassword      bool   `json:"is_password"`
+	IsSortable      bool   `json:"is_sortable"`
+	IsDynamic       bool   `json:"is_dynamic"`
+	IsBool          bool   `json:"is_bool"`
+	IsReference     bool   `json:"is_reference"`
+	ReferenceTypeID int    `json:"reference_type_id,omitempty"`
+	IsRequired      bool   `json:"is_required"`
+	DefaultShow     bool   `json:"default_show"`
+	Order           int    `json:"order"`
+	// Inherited 为true时属性继承自InheritedFrom类型
+	Inherited     bool   `json:"inherited"`
+	InheritedFrom string `json:"inherited_from,omitempty"`
+}
+
+// CITypeAttributesResponse CI类型属性API响应
+type CITypeAttributesResponse struct {
+	Attributes []CIAttribute `json:"attributes"`
+	TypeID     int           `json:"type_id"`
+	UniqueID   int           `json:"unique_id"`
+	Unique     string        `json:"unique"`
+}
+
+// CITypeRelationListResponse CI类型关系API响应
+type CITypeRelationListResponse struct {
+	Relations []CITypeRelation `json:"relations"`
+}
+
+// RelationType 关系类型，如contain、deploy
+type RelationType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CITypeRelation CI类型之间的父子关系
+type CITypeRelation struct {
+	ID             int              `json:"id"`
+	ParentID       int              `json:"parent_id"`
+	ChildID        int              `json:"child_id"`
+	RelationTypeID int              `json:"relation_type_id"`
+	Constraint     string           `json:"constraint"`
+	ParentAttrIDs  []int            `json:"parent_attr_ids,omitempty"`
+	ChildAttrIDs   []int            `json:"child_attr_ids,omitempty"`
+	Parent         *CITypeReference `json:"parent,omitempty"`
+	Child          *CITypeReference `json:"child,omitempty"`
+	RelationType   *RelationType    `json:"relation_type,omitempty"`
+}
+
+// CITypeReference 关系中引用的CI类型
+type CITypeReference struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+// CITypeSchema CI类型模型图：类型及其属性、类型间的父子关系和关系类型
+type CITypeSchema struct {
+	Types         []CITypeDefinition `json:"types"`
+	Relations     []CITypeRelation   `json:"relations"`
+	RelationTypes []RelationType     `json:"relation_types"`
+	CrawledAt     time.Time          `json:"crawled_at"`
+}
+
+// TypeByID 根据ID查找CI类型
+func (s *CITypeSchema) TypeByID(id int) (*CITypeDefinition, bool) {
+	for i := range s.Types {
+		if s.Types[i].ID == id {
+			return &s.Types[i], true
+		}
+	}
+	return nil, false
+}
+
+// ChildRelations 返回以指定类型为父类型的关系
+func (s *CITypeSchema) ChildRelations(typeID int) []CITypeRelation {
+	var relations []CITypeRelation
+	for _, relation := range s.Relations {
+		if relation.ParentID == typeID {
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// ParentRelations 返回以指定类型为子类型的关系
+func (s *CITypeSchema) ParentRelations(typeID int) []CITypeRelation {
+	var relations []CITypeRelation
+	for _, relation := range s.Relations {
+		if relation.ChildID == typeID {
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// CollectRelationTypes 从关系中收集关系类型，按ID排序
+func (s *CITypeSchema) CollectRelationTypes() []RelationType {
+	seen := make(map[int]bool)
+	var relationTypes []RelationType
+	for _, relation := range s.Relations {
+		if relation.RelationType == nil || seen[relation.RelationType.ID] {
+			continue
+		}
+		seen[relation.RelationType.ID] = true
+		relationTypes = append(relationTypes, *relation.RelationType)
+	}
+
+	sort.Slice(relationTypes, func(i, j int) bool {
+		return relationTypes[i].ID < relationTypes[j].ID
+	})
+	s.RelationTypes = relationTypes
+	return relationTypes
+}
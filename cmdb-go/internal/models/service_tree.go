@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"slices"
+	"strconv"
 	"time"
 )
 
@@ -70,6 +72,16 @@ type CIRelationSearchResponse struct {
 	Facet    interface{}  `json:"facet"`
 }
 
+// CIRelationTreeNode /ci_relations/search/full API返回的关系树节点，只包含ID和类型
+type CIRelationTreeNode struct {
+	ID          int                  `json:"id"`
+	TypeID      int                  `json:"type_id"`
+	IsLeaf      bool                 `json:"isLeaf"`
+	Title       interface{}          `json:"title"`
+	UniqueValue interface{}          `json:"uniqueValue"`
+	Children    []CIRelationTreeNode `json:"children"`
+}
+
 // StatisticsResponse 统计API响应
 type StatisticsResponse struct {
 	// 根节点统计数据 (动态字段)
@@ -120,6 +132,25 @@ func (s *StatisticsResponse) GetCount(rootID string) int {
 	return 0
 }
 
+// GetTypeCounts 获取指定根节点按CI类型ID分组的统计数量（detail字段）
+func (s *StatisticsResponse) GetTypeCounts(rootID string) map[string]int {
+	detail, ok := s.Detail[rootID].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	counts := make(map[string]int, len(detail))
+	for typeID, count := range detail {
+		if value, ok := count.(float64); ok {
+			counts[typeID] = int(value)
+		}
+	}
+	return counts
+}
+
+// StatTotalDescendants 节点统计信息中叶子CI总数的键，其余键为叶子类型名
+const StatTotalDescendants = "total_descendants"
+
 // ServiceTreeNode 服务树节点
 type ServiceTreeNode struct {
 	ID         int                    `json:"id"`
@@ -133,17 +164,97 @@ type ServiceTreeNode struct {
 	IsLeaf     bool                   `json:"is_leaf"`
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 	Statistics map[string]int         `json:"statistics,omitempty"`
+	// Shared 该CI在树中有多个父节点（多对多关系），每个父节点下各出现一次
+	Shared bool `json:"shared,omitempty"`
+}
+
+// SharedNode 在服务树中出现在多个父节点下的CI
+type SharedNode struct {
+	ID       int    `json:"id"`
+	Type     int    `json:"type"`
+	TypeName string `json:"type_name"`
+	Name     string `json:"name"`
+	// ParentIDs 所有父节点的CI ID，按首次出现的顺序
+	ParentIDs []int `json:"parent_ids"`
 }
 
 // ServiceTreeData 完整的服务树数据
 type ServiceTreeData struct {
-	ViewName   string             `json:"view_name"`
-	ViewID     int                `json:"view_id"`
-	Config     ServiceTreeView    `json:"config"`
-	RootNodes  []*ServiceTreeNode `json:"root_nodes"`
-	TotalNodes int                `json:"total_nodes"`
-	MaxDepth   int                `json:"max_depth"`
-	CrawledAt  time.Time          `json:"crawled_at"`
+	ViewName  string             `json:"view_name"`
+	ViewID    int                `json:"view_id"`
+	Config    ServiceTreeView    `json:"config"`
+	ID2Type   map[string]CIType  `json:"id2type,omitempty"`
+	RootNodes []*ServiceTreeNode `json:"root_nodes"`
+	// TotalNodes 不同CI的数量，多个父节点下重复出现的CI只计一次
+	TotalNodes int `json:"total_nodes"`
+	MaxDepth   int `json:"max_depth"`
+	// SharedNodes 出现在多个父节点下的CI
+	SharedNodes []SharedNode `json:"shared_nodes,omitempty"`
+	CrawledAt   time.Time    `json:"crawled_at"`
+	// Options 生成该快照时的爬取选项，旧版本的快照没有记录
+	Options *CrawlOptions `json:"crawl_options,omitempty"`
+}
+
+// CrawlOptions 影响服务树内容的爬取选项，增量爬取只复用选项相同的快照
+type CrawlOptions struct {
+	MaxDepth     int  `json:"max_depth"`
+	IncludeStats bool `json:"include_stats"`
+	IncludeM2M   bool `json:"include_m2m"`
+}
+
+// UnmarshalJSON 自定义JSON反序列化，未配置option时与前端一致默认展示叶子节点下的CI
+func (v *ServiceTreeView) UnmarshalJSON(data []byte) error {
+	type Alias ServiceTreeView
+	alias := Alias{Option: ServiceTreeOption{IsShowLeafNode: true}}
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*v = ServiceTreeView(alias)
+	return nil
+}
+
+// LevelConstraint 返回第level层与上一层之间的类型关系约束，level从1开始
+// 第len(TopoFlatten)层为叶子节点与其展示类型之间的约束，未配置时返回空
+func (v ServiceTreeView) LevelConstraint(level int) string {
+	return v.Level2Constraint[strconv.Itoa(level)]
+}
+
+// HasM2M 任一层级（含叶子展示类型）为多对多关系时，查询需要带上 has_m2m
+func (v ServiceTreeView) HasM2M() bool {
+	for _, constraint := range v.Level2Constraint {
+		if constraint == ConstraintManyToMany {
+			return true
+		}
+	}
+	return false
+}
+
+// TreeHasM2M 树内层级存在多对多关系时，同一CI可经不同路径到达，查询下级需要带上祖先路径
+func (v ServiceTreeView) TreeHasM2M() bool {
+	for level := 1; level < len(v.TopoFlatten); level++ {
+		if v.LevelConstraint(level) == ConstraintManyToMany {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLeafType 判断CI类型是否为视图的叶子类型
+func (v ServiceTreeView) IsLeafType(typeID int) bool {
+	for _, id := range v.Leaf {
+		if id == typeID {
+			return true
+		}
+	}
+	return false
+}
+
+// LeafShowTypes 返回叶子类型节点下要展示的CI类型，视图关闭叶子节点展示时返回空
+func (v ServiceTreeView) LeafShowTypes(typeID int) []int {
+	if !v.Option.IsShowLeafNode {
+		return nil
+	}
+	return v.Leaf2ShowTypes[strconv.Itoa(typeID)]
 }
 
 // UnmarshalJSON 自定义JSON反序列化，处理动态属性
@@ -222,14 +333,72 @@ func (node *ServiceTreeNode) GetAllDescendants() []*ServiceTreeNode {
 	return descendants
 }
 
-// CountNodes 统计节点总数
+// CountNodes 统计不同CI的数量，同一CI在多个位置出现时只计一次
 func (data *ServiceTreeData) CountNodes() int {
-	count := len(data.RootNodes)
+	seen := make(map[int]bool)
 	for _, root := range data.RootNodes {
-		count += len(root.GetAllDescendants())
+		seen[root.ID] = true
+		for _, node := range root.GetAllDescendants() {
+			seen[node.ID] = true
+		}
 	}
-	data.TotalNodes = count
-	return count
+	data.TotalNodes = len(seen)
+	return data.TotalNodes
+}
+
+// DetectSharedNodes 找出有多个不同父节点的CI，标记每处出现的节点并记录到SharedNodes
+// 共享节点的后代会随之重复出现，但它们只有一个父CI，不算共享节点
+func (data *ServiceTreeData) DetectSharedNodes() []SharedNode {
+	parents := make(map[int][]int)
+	first := make(map[int]*ServiceTreeNode)
+	var order []int
+
+	var walk func(parentID int, node *ServiceTreeNode)
+	walk = func(parentID int, node *ServiceTreeNode) {
+		if _, ok := first[node.ID]; !ok {
+			first[node.ID] = node
+			order = append(order, node.ID)
+		}
+		if parentID != 0 && !slices.Contains(parents[node.ID], parentID) {
+			parents[node.ID] = append(parents[node.ID], parentID)
+		}
+		for _, child := range node.Children {
+			walk(node.ID, child)
+		}
+	}
+	for _, root := range data.RootNodes {
+		walk(0, root)
+	}
+
+	shared := make(map[int]bool)
+	data.SharedNodes = nil
+	for _, id := range order {
+		if len(parents[id]) < 2 {
+			continue
+		}
+		shared[id] = true
+		node := first[id]
+		data.SharedNodes = append(data.SharedNodes, SharedNode{
+			ID:        id,
+			Type:      node.Type,
+			TypeName:  node.TypeName,
+			Name:      node.Name,
+			ParentIDs: parents[id],
+		})
+	}
+
+	var mark func(node *ServiceTreeNode)
+	mark = func(node *ServiceTreeNode) {
+		node.Shared = shared[node.ID]
+		for _, child := range node.Children {
+			mark(child)
+		}
+	}
+	for _, root := range data.RootNodes {
+		mark(root)
+	}
+
+	return data.SharedNodes
 }
 
 // CalculateMaxDepth 计算最大深度
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// CIGraphExport CI关系图导出文件结构（JSON/YAML）
+type CIGraphExport struct {
+	Metadata GraphMetadata   `json:"metadata" yaml:"metadata"`
+	Graph    *models.CIGraph `json:"graph" yaml:"graph"`
+}
+
+// GraphMetadata CI关系图导出元数据
+type GraphMetadata struct {
+	ExportedAt time.Time `json:"exported_at" yaml:"exported_at"`
+	Format     string    `json:"format" yaml:"format"`
+	Version    string    `json:"version" yaml:"version"`
+	NodeCount  int       `json:"node_count" yaml:"node_count"`
+	EdgeCount  int       `json:"edge_count" yaml:"edge_count"`
+}
+
+// SupportsCIGraph 判断当前格式是否支持导出CI关系图
+func (e *Exporter) SupportsCIGraph() bool {
+	switch e.format {
+	case FormatJSON, FormatYAML, FormatDOT, FormatGraphML, FormatGEXF:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportCIGraph 导出CI关系图，GraphML/GEXF的边属性为 relation_type
+func (e *Exporter) ExportCIGraph(graph *models.CIGraph, outputPath string) error {
+	e.logger.Info("Exporting CI graph",
+		zap.String("format", string(e.format)),
+		zap.String("output_path", outputPath),
+		zap.Int("nodes", len(graph.Nodes)),
+		zap.Int("edges", len(graph.Edges)))
+
+	if err := e.ensureOutputDir(outputPath); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	switch e.format {
+	case FormatJSON, FormatYAML:
+		return e.exportCIGraphDocument(graph, outputPath)
+	case FormatDOT:
+		return e.exportCIGraphDOT(graph, outputPath)
+	case FormatGraphML:
+		return e.writeGraphML(buildCIGraph(graph), "ci_graph", outputPath)
+	case FormatGEXF:
+		return e.writeGEXF(buildCIGraph(graph), "CI relation graph: "+graph.SeedQuery, outputPath)
+	default:
+		return fmt.Errorf("unsupported format for CI graph: %s", e.format)
+	}
+}
+
+// exportCIGraphDocument 导出为带元数据的JSON或YAML文件
+func (e *Exporter) exportCIGraphDocument(graph *models.CIGraph, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create graph file: %w", err)
+	}
+	defer file.Close()
+
+	export := CIGraphExport{
+		Metadata: GraphMetadata{
+			ExportedAt: time.Now(),
+			Format:     string(e.format),
+			Version:    "1.0",
+			NodeCount:  len(graph.Nodes),
+			EdgeCount:  len(graph.Edges),
+		},
+		Graph: graph,
+	}
+
+	if e.format == FormatYAML {
+		encoder := yaml.NewEncoder(file)
+		defer encoder.Close()
+		if err := encoder.Encode(export); err != nil {
+			return fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	} else {
+		encoder := json.NewEncoder(file)
+		if e.prettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(export); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	}
+
+	e.logger.Info("Successfully exported CI graph", zap.String("file", outputPath))
+	return nil
+}
+
+// exportCIGraphDOT 导出为Graphviz DOT格式
+func (e *Exporter) exportCIGraphDOT(graph *models.CIGraph, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create DOT file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := e.writeCIGraphDOT(writer, graph); err != nil {
+		return fmt.Errorf("failed to write DOT graph: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write DOT file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported DOT", zap.String("file", outputPath))
+	return nil
+}
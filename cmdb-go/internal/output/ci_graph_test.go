@@ -0,0 +1,93 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildTestCIGraph 构建一个服务器被两个应用共享的关系图
+func buildTestCIGraph() *models.CIGraph {
+	return &models.CIGraph{
+		SeedQuery: "_type:(2)",
+		Seeds:     []int{10, 11},
+		Direction: models.GraphDirectionDown,
+		MaxDepth:  1,
+		Nodes: []*models.CIGraphNode{
+			{ID: 10, Type: 2, TypeName: "应用", Name: "order-service", Attributes: map[string]interface{}{"port": float64(8080)}},
+			{ID: 11, Type: 2, TypeName: "应用", Name: "pay-service"},
+			{ID: 100, Type: 3, TypeName: "服务器", Name: "server-1", Depth: 1},
+		},
+		Edges: []*models.CIGraphEdge{
+			{Source: 10, Target: 100, RelationType: "deploy", Constraint: models.ConstraintManyToMany},
+			{Source: 11, Target: 100, RelationType: "deploy", Constraint: models.ConstraintManyToMany},
+		},
+	}
+}
+
+// TestExportCIGraph 测试关系图导出为JSON、GraphML和DOT时保留边的关系类型
+func TestExportCIGraph(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "graph.json")
+	if err := NewExporter("json", false, zap.NewNop()).ExportCIGraph(buildTestCIGraph(), jsonPath); err != nil {
+		t.Fatalf("ExportCIGraph json failed: %v", err)
+	}
+	content, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var exported CIGraphExport
+	if err := json.Unmarshal(content, &exported); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if exported.Metadata.NodeCount != 3 || exported.Metadata.EdgeCount != 2 || exported.Graph.Edges[0].Constraint != models.ConstraintManyToMany {
+		t.Errorf("Unexpected JSON export: %+v", exported)
+	}
+
+	graphMLPath := filepath.Join(dir, "graph.graphml")
+	if err := NewExporter("graphml", true, zap.NewNop()).ExportCIGraph(buildTestCIGraph(), graphMLPath); err != nil {
+		t.Fatalf("ExportCIGraph graphml failed: %v", err)
+	}
+	content, err = os.ReadFile(graphMLPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var doc graphMLDocument
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("Expected valid XML, got error: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("Expected 3 nodes and 2 edges, got %d nodes and %d edges", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	if data := doc.Graph.Edges[0].Data; len(data) != 1 || data[0].Key != graphKeyRelation || data[0].Value != "deploy" {
+		t.Errorf("Expected relation_type edge data, got %+v", data)
+	}
+
+	dotPath := filepath.Join(dir, "graph.dot")
+	exporter := NewExporter("dot", false, zap.NewNop()).SetColorByType(true)
+	if err := exporter.ExportCIGraph(buildTestCIGraph(), dotPath); err != nil {
+		t.Fatalf("ExportCIGraph dot failed: %v", err)
+	}
+	content, err = os.ReadFile(dotPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	dot := string(content)
+	for _, want := range []string{`n11 -> n100 [label="deploy"];`, "penwidth=2", "legend_3"} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT output to contain %q:\n%s", want, dot)
+		}
+	}
+
+	if NewExporter("csv", false, zap.NewNop()).SupportsCIGraph() {
+		t.Error("Expected csv not to support CI graph export")
+	}
+}
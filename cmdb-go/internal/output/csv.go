@@ -0,0 +1,360 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// CSVAttributeMode CSV属性列的分组方式
+type CSVAttributeMode string
+
+const (
+	// CSVAttributesNone 只输出固定列
+	CSVAttributesNone CSVAttributeMode = "none"
+	// CSVAttributesView 每个视图一个文件，属性列为该视图内属性键的并集
+	CSVAttributesView CSVAttributeMode = "view"
+	// CSVAttributesType 每个CI类型一个文件，属性列为该类型属性键的并集
+	CSVAttributesType CSVAttributeMode = "type"
+)
+
+// csvListSeparator 展开列表值时的分隔符
+const csvListSeparator = "|"
+
+// CSVOptions CSV属性列选项
+type CSVOptions struct {
+	Attributes CSVAttributeMode
+	// Include 非空时只输出这些属性，并按给定顺序排列
+	Include []string
+	// Exclude 不输出的属性，展开后的子键按顶层属性名匹配
+	Exclude []string
+	// Flatten 为true时对象展开为"父键.子键"列、列表用"|"连接，否则编码为JSON
+	Flatten bool
+}
+
+// ParseCSVAttributeMode 解析属性列分组方式，空字符串视为none
+func ParseCSVAttributeMode(mode string) (CSVAttributeMode, error) {
+	switch CSVAttributeMode(strings.ToLower(mode)) {
+	case "", CSVAttributesNone:
+		return CSVAttributesNone, nil
+	case CSVAttributesView:
+		return CSVAttributesView, nil
+	case CSVAttributesType:
+		return CSVAttributesType, nil
+	default:
+		return "", fmt.Errorf("unsupported CSV attribute mode: %s", mode)
+	}
+}
+
+// SetCSVOptions 设置CSV属性列选项
+func (e *Exporter) SetCSVOptions(options CSVOptions) *Exporter {
+	if options.Attributes == "" {
+		options.Attributes = CSVAttributesNone
+	}
+	e.csvOptions = options
+	return e
+}
+
+// SplitsCSVFiles 判断CSV导出是否按视图或CI类型拆分为多个文件
+func (e *Exporter) SplitsCSVFiles() bool {
+	return e.format == FormatCSV && e.csvOptions.Attributes != CSVAttributesNone
+}
+
+// CSVGroupFilePath 生成按视图或CI类型分组后的文件路径，如 trees.csv -> trees_产品服务树.csv
+func CSVGroupFilePath(outputPath, group string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + "_" + sanitizeFileName(group) + ext
+}
+
+// csvRow 待写入的节点行
+type csvRow struct {
+	record []string
+	attrs  map[string]string
+}
+
+// csvGroup 写入同一个文件的节点行
+type csvGroup struct {
+	name string
+	keys map[string]bool
+	rows []csvRow
+}
+
+// exportCSVWithAttributes 按视图或CI类型分组导出带属性列的CSV，每组一个文件
+func (e *Exporter) exportCSVWithAttributes(data []*models.ServiceTreeData, outputPath string) error {
+	var groups []*csvGroup
+	index := make(map[string]*csvGroup)
+	statColumns := csvStatColumns(data)
+
+	var walk func(tree *models.ServiceTreeData, parentID int, node *models.ServiceTreeNode)
+	walk = func(tree *models.ServiceTreeData, parentID int, node *models.ServiceTreeNode) {
+		name := tree.ViewName
+		if e.csvOptions.Attributes == CSVAttributesType {
+			name = strconv.Itoa(node.Type)
+			if node.TypeName != "" {
+				name += "_" + node.TypeName
+			}
+		}
+
+		group, ok := index[name]
+		if !ok {
+			group = &csvGroup{name: name, keys: make(map[string]bool)}
+			index[name] = group
+			groups = append(groups, group)
+		}
+
+		attrs := e.flattenAttributes(node.Attributes)
+		for key := range attrs {
+			group.keys[key] = true
+		}
+		group.rows = append(group.rows, csvRow{
+			record: append(csvNodeRecord(tree.ViewName, tree.ViewID, node, parentID), csvStatRecord(node, statColumns)...),
+			attrs:  attrs,
+		})
+
+		for _, child := range node.Children {
+			walk(tree, node.ID, child)
+		}
+	}
+
+	for _, tree := range data {
+		for _, root := range tree.RootNodes {
+			walk(tree, 0, root)
+		}
+	}
+
+	for _, group := range groups {
+		path := CSVGroupFilePath(outputPath, group.name)
+		if err := e.writeCSVGroup(path, group, statColumns); err != nil {
+			return err
+		}
+		e.logger.Info("Successfully exported CSV",
+			zap.String("file", path),
+			zap.Int("rows", len(group.rows)))
+	}
+
+	return nil
+}
+
+// writeCSVGroup 写入一组节点，统计列在固定列之后、属性列之前
+func (e *Exporter) writeCSVGroup(path string, group *csvGroup, statColumns []string) error {
+	columns := e.selectAttributeColumns(group.keys)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	headers := append(append([]string{}, csvHeaders...), csvStatHeaders(statColumns)...)
+	if err := writer.Write(append(headers, columns...)); err != nil {
+		return fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+
+	for _, row := range group.rows {
+		record := row.record
+		for _, column := range columns {
+			record = append(record, row.attrs[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	return nil
+}
+
+// csvStatColumns 收集节点统计信息的键，叶子CI总数在前，叶子类型按名称排序；没有统计信息时返回空
+func csvStatColumns(data []*models.ServiceTreeData) []string {
+	keys := make(map[string]bool)
+	var walk func(node *models.ServiceTreeNode)
+	walk = func(node *models.ServiceTreeNode) {
+		for key := range node.Statistics {
+			keys[key] = true
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, tree := range data {
+		for _, root := range tree.RootNodes {
+			walk(root)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+	delete(keys, models.StatTotalDescendants)
+	columns := make([]string, 0, len(keys))
+	for key := range keys {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+	return append([]string{models.StatTotalDescendants}, columns...)
+}
+
+// csvStatHeaders 统计列的列名，叶子类型列加 leaf_ 前缀
+func csvStatHeaders(columns []string) []string {
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column
+		if column != models.StatTotalDescendants {
+			headers[i] = "leaf_" + column
+		}
+	}
+	return headers
+}
+
+// csvStatRecord 生成节点的统计列，没有统计信息的节点留空
+func csvStatRecord(node *models.ServiceTreeNode, columns []string) []string {
+	record := make([]string, len(columns))
+	if node.Statistics == nil {
+		return record
+	}
+	for i, column := range columns {
+		record[i] = strconv.Itoa(node.Statistics[column])
+	}
+	return record
+}
+
+// selectAttributeColumns 按包含和排除列表确定属性列
+// 指定了包含列表时按其顺序输出（即使该组中没有这个属性，便于多个文件对齐），否则按名称排序
+func (e *Exporter) selectAttributeColumns(keys map[string]bool) []string {
+	var columns []string
+	if len(e.csvOptions.Include) > 0 {
+		for _, include := range e.csvOptions.Include {
+			matched := false
+			var nested []string
+			for key := range keys {
+				if strings.HasPrefix(key, include+".") {
+					nested = append(nested, key)
+				}
+				if key == include {
+					matched = true
+				}
+			}
+			// 展开后的对象属性按子键输出
+			if !matched && len(nested) > 0 {
+				sort.Strings(nested)
+				columns = append(columns, nested...)
+				continue
+			}
+			columns = append(columns, include)
+		}
+	} else {
+		for key := range keys {
+			columns = append(columns, key)
+		}
+		sort.Strings(columns)
+	}
+
+	if len(e.csvOptions.Exclude) == 0 {
+		return columns
+	}
+
+	filtered := columns[:0]
+	for _, column := range columns {
+		if !e.excludedAttribute(column) {
+			filtered = append(filtered, column)
+		}
+	}
+	return filtered
+}
+
+// excludedAttribute 判断属性列是否被排除，"a"同时排除展开后的"a.b"
+func (e *Exporter) excludedAttribute(column string) bool {
+	for _, exclude := range e.csvOptions.Exclude {
+		if column == exclude || strings.HasPrefix(column, exclude+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenAttributes 将属性转为列名到单元格文本的映射，nil值输出为空
+func (e *Exporter) flattenAttributes(attrs map[string]interface{}) map[string]string {
+	flat := make(map[string]string, len(attrs))
+	for key, value := range attrs {
+		if e.csvOptions.Flatten {
+			flattenCSVValue(flat, key, value)
+		} else {
+			flat[key] = formatCSVValue(value)
+		}
+	}
+	return flat
+}
+
+// flattenCSVValue 递归展开对象属性，列表元素用分隔符连接
+func flattenCSVValue(flat map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			flat[key] = ""
+			return
+		}
+		for subKey, subValue := range v {
+			flattenCSVValue(flat, key+"."+subKey, subValue)
+		}
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatCSVValue(item)
+		}
+		flat[key] = strings.Join(parts, csvListSeparator)
+	default:
+		flat[key] = formatCSVValue(v)
+	}
+}
+
+// formatCSVValue 格式化单个属性值，数字不使用科学计数法，列表和对象编码为JSON（对象键有序）
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int32, int64, json.Number:
+		return fmt.Sprintf("%v", v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// sanitizeFileName 替换文件名中不允许的字符
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// readCSV 读取CSV文件的全部记录
+func readCSV(t *testing.T, path string) [][]string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return records
+}
+
+// buildCSVTrees 构建产品节点带有数字、列表、对象和敏感属性的测试服务树
+func buildCSVTrees() []*models.ServiceTreeData {
+	tree := newTestTree("产品服务树")
+	testNode(tree, 2).Attributes = map[string]interface{}{
+		"port":     float64(1e7),
+		"tags":     []interface{}{"web", "core"},
+		"meta":     map[string]interface{}{"zone": "b", "rack": float64(3)},
+		"password": "secret",
+	}
+	return []*models.ServiceTreeData{tree}
+}
+
+// TestExportCSVAttributesByType 测试按CI类型拆分文件、排除属性和展开对象
+func TestExportCSVAttributesByType(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "trees.csv")
+	exporter := NewExporter("csv", false, zap.NewNop()).SetCSVOptions(CSVOptions{
+		Attributes: CSVAttributesType,
+		Exclude:    []string{"password"},
+		Flatten:    true,
+	})
+	if err := exporter.ExportServiceTrees(buildCSVTrees(), outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	records := readCSV(t, CSVGroupFilePath(outputPath, "2_产品"))
+	header := records[0][len(csvHeaders):]
+	if expected := []string{"meta.rack", "meta.zone", "port", "tags"}; !reflect.DeepEqual(header, expected) {
+		t.Errorf("Expected attribute columns %v, got %v", expected, header)
+	}
+	if values := records[1][len(csvHeaders):]; !reflect.DeepEqual(values, []string{"3", "b", "10000000", "web|core"}) {
+		t.Errorf("Unexpected attribute values %v", values)
+	}
+
+	lines := readCSV(t, CSVGroupFilePath(outputPath, "1_产品线"))
+	if len(lines) != 2 || lines[0][len(csvHeaders)] != "owner" {
+		t.Errorf("Expected product line file with owner column, got %v", lines)
+	}
+}
+
+// TestExportCSVAttributesByView 测试按视图导出、包含列表顺序和JSON编码
+func TestExportCSVAttributesByView(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "trees.csv")
+	exporter := NewExporter("csv", false, zap.NewNop()).SetCSVOptions(CSVOptions{
+		Attributes: CSVAttributesView,
+		Include:    []string{"tags", "owner", "missing", "meta"},
+	})
+	if err := exporter.ExportServiceTrees(buildCSVTrees(), outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	records := readCSV(t, CSVGroupFilePath(outputPath, "产品服务树"))
+	if len(records) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d", len(records))
+	}
+	if header := records[0][len(csvHeaders):]; !reflect.DeepEqual(header, []string{"tags", "owner", "missing", "meta"}) {
+		t.Errorf("Expected include order to be kept, got %v", header)
+	}
+	if values := records[2][len(csvHeaders):]; !reflect.DeepEqual(values, []string{`["web","core"]`, "", "", `{"rack":3,"zone":"b"}`}) {
+		t.Errorf("Unexpected attribute values %v", values)
+	}
+}
+
+// TestExportCSVStatistics 测试统计列：叶子CI总数在前，叶子类型加前缀，没有统计的节点留空
+func TestExportCSVStatistics(t *testing.T) {
+	trees := buildCSVTrees()
+	trees[0].RootNodes[0].Statistics = map[string]int{models.StatTotalDescendants: 5, "server": 3, "db": 2}
+
+	outputPath := filepath.Join(t.TempDir(), "trees.csv")
+	if err := NewExporter("csv", false, zap.NewNop()).ExportServiceTrees(trees, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	records := readCSV(t, outputPath)
+	if header := records[0][len(csvHeaders):]; !reflect.DeepEqual(header, []string{"total_descendants", "leaf_db", "leaf_server"}) {
+		t.Errorf("Unexpected statistics columns %v", header)
+	}
+	if values := records[1][len(csvHeaders):]; !reflect.DeepEqual(values, []string{"5", "2", "3"}) {
+		t.Errorf("Unexpected root statistics %v", values)
+	}
+	if values := records[2][len(csvHeaders):]; !reflect.DeepEqual(values, []string{"", "", ""}) {
+		t.Errorf("Expected empty statistics for node without statistics, got %v", values)
+	}
+}
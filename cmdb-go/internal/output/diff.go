@@ -0,0 +1,160 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"cmdb-crawler/internal/models"
+)
+
+// DiffFormat 变更报告格式
+type DiffFormat string
+
+const (
+	DiffFormatText     DiffFormat = "text"
+	DiffFormatJSON     DiffFormat = "json"
+	DiffFormatMarkdown DiffFormat = "markdown"
+)
+
+// WriteDiff 按指定格式输出变更报告
+func WriteDiff(w io.Writer, diff *models.TreeDiff, format string, prettyPrint bool) error {
+	switch DiffFormat(strings.ToLower(format)) {
+	case DiffFormatText, "":
+		return writeDiffText(w, diff)
+	case DiffFormatJSON:
+		encoder := json.NewEncoder(w)
+		if prettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(diff)
+	case DiffFormatMarkdown, "md":
+		return writeDiffMarkdown(w, diff)
+	default:
+		return fmt.Errorf("unsupported diff format: %s", format)
+	}
+}
+
+// writeDiffText 输出纯文本变更报告
+func writeDiffText(w io.Writer, diff *models.TreeDiff) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Service tree diff: %s\n", formatSummary(diff.Summary))
+	for _, view := range diff.Views {
+		if view.Status == models.ViewUnchanged {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n[%s] %s (%s)\n", view.Status, view.ViewName, formatSummary(view.Summary))
+		for _, change := range view.Changes {
+			fmt.Fprintf(&b, "  %-18s #%d %s\n", change.Change, change.NodeID, describeChange(change))
+			for _, attr := range change.Attributes {
+				fmt.Fprintf(&b, "      %s: %s -> %s\n", attr.Key, formatValue(attr.Old), formatValue(attr.New))
+			}
+		}
+	}
+
+	if !diff.HasChanges() {
+		b.WriteString("\nNo changes.\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDiffMarkdown 输出Markdown变更报告
+func writeDiffMarkdown(w io.Writer, diff *models.TreeDiff) error {
+	var b strings.Builder
+
+	b.WriteString("# Service Tree Diff\n\n")
+	b.WriteString("| View | Status | Added | Removed | Moved | Renamed | Attributes |\n")
+	b.WriteString("|------|--------|-------|---------|-------|---------|------------|\n")
+	for _, view := range diff.Views {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %d | %d |\n",
+			escapeMarkdown(view.ViewName), view.Status,
+			view.Summary.Added, view.Summary.Removed, view.Summary.Moved,
+			view.Summary.Renamed, view.Summary.AttributesChanged)
+	}
+	fmt.Fprintf(&b, "| **Total** | | %d | %d | %d | %d | %d |\n",
+		diff.Summary.Added, diff.Summary.Removed, diff.Summary.Moved,
+		diff.Summary.Renamed, diff.Summary.AttributesChanged)
+
+	for _, view := range diff.Views {
+		if len(view.Changes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n## %s\n\n", escapeMarkdown(view.ViewName))
+		b.WriteString("| Change | Node ID | Type | Details |\n")
+		b.WriteString("|--------|---------|------|---------|\n")
+		for _, change := range view.Changes {
+			details := describeChange(change)
+			for _, attr := range change.Attributes {
+				details += fmt.Sprintf("<br>`%s`: %s → %s", attr.Key, formatValue(attr.Old), formatValue(attr.New))
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s | %s |\n",
+				change.Change, change.NodeID, escapeMarkdown(change.TypeName), escapeMarkdown(details))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// describeChange 生成变更的单行描述
+func describeChange(change models.NodeChange) string {
+	switch change.Change {
+	case models.ChangeAdded:
+		return fmt.Sprintf("%s (%s)", change.Name, change.NewPath)
+	case models.ChangeRemoved:
+		return fmt.Sprintf("%s (%s)", change.Name, change.OldPath)
+	case models.ChangeMoved:
+		return fmt.Sprintf("%s: parent %s -> %s", change.Name,
+			formatIDs(change.OldParentIDs), formatIDs(change.NewParentIDs))
+	case models.ChangeRenamed:
+		return fmt.Sprintf("%s -> %s", change.OldName, change.Name)
+	default:
+		return change.Name
+	}
+}
+
+// formatSummary 格式化变更计数
+func formatSummary(s models.DiffSummary) string {
+	return fmt.Sprintf("+%d -%d moved %d renamed %d attrs %d",
+		s.Added, s.Removed, s.Moved, s.Renamed, s.AttributesChanged)
+}
+
+// formatIDs 格式化父节点ID列表，空列表表示根节点
+func formatIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "(root)"
+	}
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+// formatValue 格式化属性值
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "<nil>"
+	}
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+// escapeMarkdown 转义Markdown表格中的特殊字符
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
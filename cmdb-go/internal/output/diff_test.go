@@ -0,0 +1,84 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+)
+
+// buildTestDiff 构建包含新增、删除、移动和重命名节点的变更报告
+func buildTestDiff() *models.TreeDiff {
+	build := func(apps ...*models.ServiceTreeNode) []*models.ServiceTreeData {
+		root := &models.ServiceTreeNode{ID: 1, Type: 1, Name: "line"}
+		for _, app := range apps {
+			root.AddChild(app)
+		}
+		return []*models.ServiceTreeData{{ViewName: "product", RootNodes: []*models.ServiceTreeNode{root}}}
+	}
+
+	oldA := &models.ServiceTreeNode{ID: 10, Type: 2, TypeName: "app", Name: "app-a"}
+	oldB := &models.ServiceTreeNode{ID: 11, Type: 2, TypeName: "app", Name: "app-b"}
+	oldA.AddChild(&models.ServiceTreeNode{ID: 100, Type: 3, TypeName: "env", Name: "env"})
+	oldB.AddChild(&models.ServiceTreeNode{ID: 101, Type: 3, TypeName: "env", Name: "gone"})
+
+	newA := &models.ServiceTreeNode{ID: 10, Type: 2, TypeName: "app", Name: "app-a"}
+	newB := &models.ServiceTreeNode{ID: 11, Type: 2, TypeName: "app", Name: "app-b2"}
+	newB.AddChild(&models.ServiceTreeNode{ID: 100, Type: 3, TypeName: "env", Name: "env"})
+	newB.AddChild(&models.ServiceTreeNode{ID: 102, Type: 3, TypeName: "env", Name: "new"})
+
+	return models.DiffServiceTrees(build(oldA, oldB), build(newA, newB))
+}
+
+// TestWriteDiffText 测试文本报告逐行列出每种变更
+func TestWriteDiffText(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDiff(&b, buildTestDiff(), "text", false); err != nil {
+		t.Fatalf("WriteDiff failed: %v", err)
+	}
+
+	expected := "Service tree diff: +1 -1 moved 1 renamed 1 attrs 0\n" +
+		"\n" +
+		"[modified] product (+1 -1 moved 1 renamed 1 attrs 0)\n" +
+		"  renamed            #11 app-b -> app-b2\n" +
+		"  moved              #100 env: parent [10] -> [11]\n" +
+		"  removed            #101 gone (app-b > gone)\n" +
+		"  added              #102 new (app-b2 > new)\n"
+	if got := b.String(); got != expected {
+		t.Errorf("Unexpected text report:\n%s\nwant:\n%s", got, expected)
+	}
+
+	b.Reset()
+	if err := WriteDiff(&b, &models.TreeDiff{}, "text", false); err != nil {
+		t.Fatalf("WriteDiff failed: %v", err)
+	}
+	if got := b.String(); got != "Service tree diff: +0 -0 moved 0 renamed 0 attrs 0\n\nNo changes.\n" {
+		t.Errorf("Unexpected report without changes: %q", got)
+	}
+}
+
+// TestWriteDiffJSON 测试JSON报告包含每个变更的父节点和路径
+func TestWriteDiffJSON(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDiff(&b, buildTestDiff(), "json", false); err != nil {
+		t.Fatalf("WriteDiff failed: %v", err)
+	}
+
+	summary := `{"added":1,"removed":1,"moved":1,"renamed":1,"attributes_changed":0}`
+	expected := `{"summary":` + summary + `,"views":[{"view_name":"product","status":"modified",` +
+		`"old_crawled_at":"0001-01-01T00:00:00Z","new_crawled_at":"0001-01-01T00:00:00Z","summary":` + summary + `,"changes":[` +
+		`{"view_name":"product","node_id":11,"change":"renamed","node_type":2,"type_name":"app","name":"app-b2","old_name":"app-b"},` +
+		`{"view_name":"product","node_id":100,"change":"moved","node_type":3,"type_name":"env","name":"env",` +
+		`"old_parent_ids":[10],"new_parent_ids":[11],"old_path":"app-a \u003e env","new_path":"app-b2 \u003e env"},` +
+		`{"view_name":"product","node_id":101,"change":"removed","node_type":3,"type_name":"env","name":"gone",` +
+		`"old_parent_ids":[11],"old_path":"app-b \u003e gone"},` +
+		`{"view_name":"product","node_id":102,"change":"added","node_type":3,"type_name":"env","name":"new",` +
+		`"new_parent_ids":[11],"new_path":"app-b2 \u003e new"}]}]}` + "\n"
+	if got := b.String(); got != expected {
+		t.Errorf("Unexpected JSON report:\n%s\nwant:\n%s", got, expected)
+	}
+
+	if err := WriteDiff(&b, buildTestDiff(), "xml", false); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
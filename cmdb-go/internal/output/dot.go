@@ -0,0 +1,226 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// dotPalette 按CI类型着色时使用的填充色
+var dotPalette = []string{
+	"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3",
+	"#fdb462", "#b3de69", "#fccde5", "#d9d9d9", "#bc80bd",
+}
+
+// exportDOT 导出为Graphviz DOT格式，每个服务树一个digraph
+func (e *Exporter) exportDOT(data []*models.ServiceTreeData, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create DOT file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for i, tree := range data {
+		if i > 0 {
+			writer.WriteString("\n")
+		}
+		if err := e.writeDOTGraph(writer, tree); err != nil {
+			return fmt.Errorf("failed to write DOT graph: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write DOT file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported DOT", zap.String("file", outputPath))
+	return nil
+}
+
+// writeDOTGraph 写入单个服务树，每个根节点的子树放在独立的cluster中
+func (e *Exporter) writeDOTGraph(w io.Writer, tree *models.ServiceTreeData) error {
+	colors := e.dotTypeColors(tree)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(tree.ViewName))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [color=\"#666666\"];\n")
+	fmt.Fprintf(&b, "  label=%s;\n  labelloc=t;\n", dotQuote(fmt.Sprintf("%s (%d nodes)", tree.ViewName, tree.TotalNodes)))
+
+	// 同一CI可能出现在多个父节点下，只声明一次
+	declared := make(map[int]bool)
+	var edges []string
+
+	for _, root := range tree.RootNodes {
+		fmt.Fprintf(&b, "\n  subgraph cluster_%d {\n", root.ID)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(root.Name))
+		b.WriteString("    style=dashed;\n")
+		e.writeDOTNode(&b, root, colors, declared, &edges)
+		b.WriteString("  }\n")
+	}
+
+	if len(edges) > 0 {
+		b.WriteString("\n")
+		for _, edge := range edges {
+			b.WriteString(edge)
+		}
+	}
+
+	if len(colors) > 0 {
+		e.writeDOTLegend(&b, tree, colors)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTNode 递归写入节点声明并收集边
+func (e *Exporter) writeDOTNode(b *strings.Builder, node *models.ServiceTreeNode, colors map[int]string,
+	declared map[int]bool, edges *[]string) {
+
+	if !declared[node.ID] {
+		declared[node.ID] = true
+
+		label := node.Name
+		if node.TypeName != "" {
+			label += "\n" + node.TypeName
+		}
+
+		attrs := "label=" + dotQuote(label)
+		if color, ok := colors[node.Type]; ok {
+			attrs += ", fillcolor=" + dotQuote(color)
+		}
+		fmt.Fprintf(b, "    n%d [%s];\n", node.ID, attrs)
+	}
+
+	if !e.withinMaxDepth(node.Level + 1) {
+		return
+	}
+
+	for _, child := range node.Children {
+		*edges = append(*edges, fmt.Sprintf("  n%d -> n%d;\n", node.ID, child.ID))
+		e.writeDOTNode(b, child, colors, declared, edges)
+	}
+}
+
+// writeDOTLegend 写入类型颜色图例
+func (e *Exporter) writeDOTLegend(b *strings.Builder, tree *models.ServiceTreeData, colors map[int]string) {
+	b.WriteString("\n  subgraph cluster_legend {\n")
+	b.WriteString("    label=\"Legend\";\n")
+	b.WriteString("    style=solid;\n")
+	for _, showType := range tree.Config.ShowTypes {
+		color, ok := colors[showType.ID]
+		if !ok {
+			continue
+		}
+		name := showType.Alias
+		if name == "" {
+			name = showType.Name
+		}
+		fmt.Fprintf(b, "    legend_%d [label=%s, fillcolor=%s];\n", showType.ID, dotQuote(name), dotQuote(color))
+	}
+	b.WriteString("  }\n")
+}
+
+// dotTypeColors 按Config.ShowTypes的顺序为CI类型分配颜色，未开启着色时返回空
+func (e *Exporter) dotTypeColors(tree *models.ServiceTreeData) map[int]string {
+	colors := make(map[int]string)
+	if !e.colorByType {
+		return colors
+	}
+
+	for i, showType := range tree.Config.ShowTypes {
+		if _, exists := colors[showType.ID]; !exists {
+			colors[showType.ID] = dotPalette[i%len(dotPalette)]
+		}
+	}
+	return colors
+}
+
+// dotQuote 生成带引号并转义的DOT字符串
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// writeCIGraphDOT 将关系图写为单个digraph，种子节点加粗，边上标注关系类型
+func (e *Exporter) writeCIGraphDOT(w io.Writer, graph *models.CIGraph) error {
+	// 按类型首次出现的顺序分配颜色
+	colors := make(map[int]string)
+	var legend []*models.CIGraphNode
+	if e.colorByType {
+		for _, node := range graph.Nodes {
+			if _, exists := colors[node.Type]; !exists {
+				colors[node.Type] = dotPalette[len(colors)%len(dotPalette)]
+				legend = append(legend, node)
+			}
+		}
+	}
+
+	seeds := make(map[int]bool, len(graph.Seeds))
+	for _, id := range graph.Seeds {
+		seeds[id] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph \"ci_graph\" {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [color=\"#666666\", fontname=\"Helvetica\", fontsize=10];\n")
+	fmt.Fprintf(&b, "  label=%s;\n  labelloc=t;\n", dotQuote(fmt.Sprintf("%s (%d nodes)", graph.SeedQuery, len(graph.Nodes))))
+	b.WriteString("\n")
+
+	for _, node := range graph.Nodes {
+		label := node.Name
+		if node.TypeName != "" {
+			label += "\n" + node.TypeName
+		}
+
+		attrs := "label=" + dotQuote(label)
+		if color, ok := colors[node.Type]; ok {
+			attrs += ", fillcolor=" + dotQuote(color)
+		}
+		if seeds[node.ID] {
+			attrs += ", penwidth=2"
+		}
+		fmt.Fprintf(&b, "  n%d [%s];\n", node.ID, attrs)
+	}
+
+	if len(graph.Edges) > 0 {
+		b.WriteString("\n")
+		for _, edge := range graph.Edges {
+			attrs := ""
+			if edge.RelationType != "" {
+				attrs = " [label=" + dotQuote(edge.RelationType) + "]"
+			}
+			fmt.Fprintf(&b, "  n%d -> n%d%s;\n", edge.Source, edge.Target, attrs)
+		}
+	}
+
+	if len(legend) > 0 {
+		b.WriteString("\n  subgraph cluster_legend {\n")
+		b.WriteString("    label=\"Legend\";\n")
+		b.WriteString("    style=solid;\n")
+		for _, node := range legend {
+			fmt.Fprintf(&b, "    legend_%d [label=%s, fillcolor=%s];\n", node.Type, dotQuote(node.TypeName), dotQuote(colors[node.Type]))
+		}
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
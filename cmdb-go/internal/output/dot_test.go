@@ -0,0 +1,69 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildDOTTree 节点名称包含引号，用于测试标签转义
+func buildDOTTree() *models.ServiceTreeData {
+	tree := newTestTree("产品服务树")
+	testNode(tree, 2).Name = `电商"APP"`
+	return tree
+}
+
+// exportDOTString 导出DOT并返回文件内容
+func exportDOTString(t *testing.T, exporter *Exporter, data []*models.ServiceTreeData) string {
+	outputPath := filepath.Join(t.TempDir(), "trees.dot")
+	if err := exporter.ExportServiceTrees(data, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read DOT file: %v", err)
+	}
+	return string(content)
+}
+
+// TestExportDOT 测试DOT导出的图结构、标签转义和类型着色
+func TestExportDOT(t *testing.T) {
+	exporter := NewExporter("dot", false, zap.NewNop()).SetColorByType(true)
+	content := exportDOTString(t, exporter, []*models.ServiceTreeData{buildDOTTree()})
+
+	expected := []string{
+		`digraph "产品服务树" {`,
+		"subgraph cluster_1 {",
+		`n2 [label="电商\"APP\"\n产品", fillcolor="` + dotPalette[1] + `"];`,
+		"n1 -> n2;",
+		"n2 -> n3;",
+		"subgraph cluster_legend {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, content)
+		}
+	}
+}
+
+// TestExportDOTMaxDepth 测试最大深度限制和关闭着色
+func TestExportDOTMaxDepth(t *testing.T) {
+	exporter := NewExporter("dot", false, zap.NewNop()).SetMaxDepth(2)
+	content := exportDOTString(t, exporter, []*models.ServiceTreeData{buildDOTTree()})
+
+	if !strings.Contains(content, "n1 -> n2;") {
+		t.Errorf("Expected edge within max depth, got:\n%s", content)
+	}
+	if strings.Contains(content, "n3") {
+		t.Errorf("Expected node beyond max depth to be omitted, got:\n%s", content)
+	}
+	if strings.Contains(content, "cluster_legend") {
+		t.Errorf("Expected no legend without color-by-type, got:\n%s", content)
+	}
+}
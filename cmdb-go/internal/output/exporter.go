@@ -19,9 +19,17 @@ import (
 type ExportFormat string
 
 const (
-	FormatJSON ExportFormat = "json"
-	FormatYAML ExportFormat = "yaml"
-	FormatCSV  ExportFormat = "csv"
+	FormatJSON     ExportFormat = "json"
+	FormatYAML     ExportFormat = "yaml"
+	FormatCSV      ExportFormat = "csv"
+	FormatDOT      ExportFormat = "dot"
+	FormatGraphML  ExportFormat = "graphml"
+	FormatGEXF     ExportFormat = "gexf"
+	FormatNDJSON   ExportFormat = "ndjson"
+	FormatSQLite   ExportFormat = "sqlite"
+	FormatXLSX     ExportFormat = "xlsx"
+	FormatHTML     ExportFormat = "html"
+	FormatMarkdown ExportFormat = "markdown"
 )
 
 // Exporter 数据导出器
@@ -29,6 +37,13 @@ type Exporter struct {
 	logger      *zap.Logger
 	format      ExportFormat
 	prettyPrint bool
+	maxDepth    int
+	colorByType bool
+	// 追加快照而不是覆盖输出文件
+	appendSnapshot bool
+	csvOptions     CSVOptions
+	// CI类型模型图，为nil时不导出
+	schema *models.CITypeSchema
 }
 
 // NewExporter 创建数据导出器
@@ -37,9 +52,39 @@ func NewExporter(format string, prettyPrint bool, logger *zap.Logger) *Exporter
 		logger:      logger,
 		format:      ExportFormat(strings.ToLower(format)),
 		prettyPrint: prettyPrint,
+		maxDepth:    -1,
+		csvOptions:  CSVOptions{Attributes: CSVAttributesNone},
 	}
 }
 
+// SetMaxDepth 设置导出的最大层级深度，-1表示无限制
+func (e *Exporter) SetMaxDepth(depth int) *Exporter {
+	e.maxDepth = depth
+	return e
+}
+
+// SetColorByType 设置图形导出时是否按CI类型着色
+func (e *Exporter) SetColorByType(enabled bool) *Exporter {
+	e.colorByType = enabled
+	return e
+}
+
+// SetSchema 设置随服务树一起导出的CI类型模型图
+func (e *Exporter) SetSchema(schema *models.CITypeSchema) *Exporter {
+	e.schema = schema
+	return e
+}
+
+// withinMaxDepth 判断指定层级的节点是否在最大深度内
+func (e *Exporter) withinMaxDepth(level int) bool {
+	return withinDepth(e.maxDepth, level)
+}
+
+// withinDepth 判断指定层级是否在maxDepth内，maxDepth<=0表示无限制
+func withinDepth(maxDepth, level int) bool {
+	return maxDepth <= 0 || level < maxDepth
+}
+
 // ExportServiceTrees 导出服务树数据
 func (e *Exporter) ExportServiceTrees(data []*models.ServiceTreeData, outputPath string) error {
 	e.logger.Info("Exporting service trees",
@@ -59,6 +104,22 @@ func (e *Exporter) ExportServiceTrees(data []*models.ServiceTreeData, outputPath
 		return e.exportYAML(data, outputPath)
 	case FormatCSV:
 		return e.exportCSV(data, outputPath)
+	case FormatDOT:
+		return e.exportDOT(data, outputPath)
+	case FormatGraphML:
+		return e.exportGraphML(data, outputPath)
+	case FormatGEXF:
+		return e.exportGEXF(data, outputPath)
+	case FormatNDJSON:
+		return e.exportNDJSON(data, outputPath)
+	case FormatSQLite:
+		return e.exportSQLite(data, outputPath)
+	case FormatXLSX:
+		return e.exportXLSX(data, outputPath)
+	case FormatMarkdown, "md":
+		return e.exportMarkdown(data, outputPath)
+	case FormatHTML:
+		return e.exportHTML(data, outputPath)
 	default:
 		return fmt.Errorf("unsupported export format: %s", e.format)
 	}
@@ -78,19 +139,7 @@ func (e *Exporter) exportJSON(data []*models.ServiceTreeData, outputPath string)
 	}
 
 	// 添加元数据
-	export := struct {
-		Metadata     ExportMetadata            `json:"metadata"`
-		ServiceTrees []*models.ServiceTreeData `json:"service_trees"`
-	}{
-		Metadata: ExportMetadata{
-			ExportedAt: time.Now(),
-			Format:     string(e.format),
-			Version:    "1.0",
-			TreeCount:  len(data),
-			TotalNodes: e.countTotalNodes(data),
-		},
-		ServiceTrees: data,
-	}
+	export := e.newServiceTreeExport(data)
 
 	if err := encoder.Encode(export); err != nil {
 		return fmt.Errorf("failed to encode JSON: %w", err)
@@ -109,19 +158,7 @@ func (e *Exporter) exportYAML(data []*models.ServiceTreeData, outputPath string)
 	defer file.Close()
 
 	// 添加元数据
-	export := struct {
-		Metadata     ExportMetadata            `json:"metadata"`
-		ServiceTrees []*models.ServiceTreeData `json:"service_trees"`
-	}{
-		Metadata: ExportMetadata{
-			ExportedAt: time.Now(),
-			Format:     string(e.format),
-			Version:    "1.0",
-			TreeCount:  len(data),
-			TotalNodes: e.countTotalNodes(data),
-		},
-		ServiceTrees: data,
-	}
+	export := e.newServiceTreeExport(data)
 
 	encoder := yaml.NewEncoder(file)
 	defer encoder.Close()
@@ -134,8 +171,18 @@ func (e *Exporter) exportYAML(data []*models.ServiceTreeData, outputPath string)
 	return nil
 }
 
+// csvHeaders CSV导出的固定列
+var csvHeaders = []string{
+	"view_name", "view_id", "node_id", "node_type", "node_type_name",
+	"node_name", "node_path", "level", "is_leaf", "child_count", "parent_id",
+}
+
 // exportCSV 导出为CSV格式
 func (e *Exporter) exportCSV(data []*models.ServiceTreeData, outputPath string) error {
+	if e.csvOptions.Attributes != CSVAttributesNone {
+		return e.exportCSVWithAttributes(data, outputPath)
+	}
+
 	file, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %w", err)
@@ -146,18 +193,15 @@ func (e *Exporter) exportCSV(data []*models.ServiceTreeData, outputPath string)
 	defer writer.Flush()
 
 	// 写入CSV头部
-	headers := []string{
-		"view_name", "view_id", "node_id", "node_type", "node_type_name",
-		"node_name", "node_path", "level", "is_leaf", "child_count", "parent_id",
-	}
-	if err := writer.Write(headers); err != nil {
+	statColumns := csvStatColumns(data)
+	if err := writer.Write(append(append([]string{}, csvHeaders...), csvStatHeaders(statColumns)...)); err != nil {
 		return fmt.Errorf("failed to write CSV headers: %w", err)
 	}
 
 	// 遍历所有服务树
 	for _, tree := range data {
 		for _, rootNode := range tree.RootNodes {
-			e.writeNodeToCSV(writer, tree.ViewName, tree.ViewID, rootNode, 0)
+			e.writeNodeToCSV(writer, tree.ViewName, tree.ViewID, rootNode, 0, statColumns)
 		}
 	}
 
@@ -167,9 +211,19 @@ func (e *Exporter) exportCSV(data []*models.ServiceTreeData, outputPath string)
 
 // writeNodeToCSV 递归写入节点到CSV
 func (e *Exporter) writeNodeToCSV(writer *csv.Writer, viewName string, viewID int,
-	node *models.ServiceTreeNode, parentID int) {
+	node *models.ServiceTreeNode, parentID int, statColumns []string) {
+
+	writer.Write(append(csvNodeRecord(viewName, viewID, node, parentID), csvStatRecord(node, statColumns)...))
+
+	// 递归写入子节点
+	for _, child := range node.Children {
+		e.writeNodeToCSV(writer, viewName, viewID, child, node.ID, statColumns)
+	}
+}
 
-	record := []string{
+// csvNodeRecord 生成节点的固定列
+func csvNodeRecord(viewName string, viewID int, node *models.ServiceTreeNode, parentID int) []string {
+	return []string{
 		viewName,
 		fmt.Sprintf("%d", viewID),
 		fmt.Sprintf("%d", node.ID),
@@ -182,13 +236,6 @@ func (e *Exporter) writeNodeToCSV(writer *csv.Writer, viewName string, viewID in
 		fmt.Sprintf("%d", node.ChildCount),
 		fmt.Sprintf("%d", parentID),
 	}
-
-	writer.Write(record)
-
-	// 递归写入子节点
-	for _, child := range node.Children {
-		e.writeNodeToCSV(writer, viewName, viewID, child, node.ID)
-	}
 }
 
 // ensureOutputDir 确保输出目录存在
@@ -209,7 +256,7 @@ func (e *Exporter) ensureOutputDir(outputPath string) error {
 }
 
 // countTotalNodes 统计总节点数
-func (e *Exporter) countTotalNodes(data []*models.ServiceTreeData) int {
+func countTotalNodes(data []*models.ServiceTreeData) int {
 	total := 0
 	for _, tree := range data {
 		total += tree.TotalNodes
@@ -217,6 +264,39 @@ func (e *Exporter) countTotalNodes(data []*models.ServiceTreeData) int {
 	return total
 }
 
+// ServiceTreeExport 服务树导出文件结构（JSON/YAML）
+type ServiceTreeExport struct {
+	Metadata     ExportMetadata            `json:"metadata"`
+	Schema       *models.CITypeSchema      `json:"schema,omitempty" yaml:"schema,omitempty"`
+	ServiceTrees []*models.ServiceTreeData `json:"service_trees"`
+}
+
+// newServiceTreeExport 构建带元数据的导出结构
+func (e *Exporter) newServiceTreeExport(data []*models.ServiceTreeData) ServiceTreeExport {
+	export := NewServiceTreeExport(string(e.format), data)
+	export.Schema = e.schema
+	return export
+}
+
+// NewServiceTreeExport 构建带元数据的服务树导出结构
+func NewServiceTreeExport(format string, data []*models.ServiceTreeData) ServiceTreeExport {
+	return ServiceTreeExport{
+		Metadata:     NewExportMetadata(format, data),
+		ServiceTrees: data,
+	}
+}
+
+// NewExportMetadata 构建导出元数据
+func NewExportMetadata(format string, data []*models.ServiceTreeData) ExportMetadata {
+	return ExportMetadata{
+		ExportedAt: time.Now(),
+		Format:     format,
+		Version:    "1.0",
+		TreeCount:  len(data),
+		TotalNodes: countTotalNodes(data),
+	}
+}
+
 // ExportMetadata 导出元数据
 type ExportMetadata struct {
 	ExportedAt time.Time `json:"exported_at" yaml:"exported_at"`
@@ -231,8 +311,22 @@ func (e *Exporter) ExportSingleTree(tree *models.ServiceTreeData, outputPath str
 	return e.ExportServiceTrees([]*models.ServiceTreeData{tree}, outputPath)
 }
 
-// ExportSummary 导出服务树摘要信息
-func (e *Exporter) ExportSummary(data []*models.ServiceTreeData, outputPath string) error {
+// SummaryExport 服务树摘要导出文件结构
+type SummaryExport struct {
+	Metadata ExportMetadata       `json:"metadata"`
+	Summary  []ServiceTreeSummary `json:"summary"`
+}
+
+// NewSummaryExport 构建带元数据的摘要导出结构
+func NewSummaryExport(format string, data []*models.ServiceTreeData) SummaryExport {
+	return SummaryExport{
+		Metadata: NewExportMetadata(format, data),
+		Summary:  BuildSummaries(data),
+	}
+}
+
+// BuildSummaries 生成每个服务树的摘要
+func BuildSummaries(data []*models.ServiceTreeData) []ServiceTreeSummary {
 	summaries := make([]ServiceTreeSummary, len(data))
 
 	for i, tree := range data {
@@ -244,25 +338,32 @@ func (e *Exporter) ExportSummary(data []*models.ServiceTreeData, outputPath stri
 			MaxDepth:   tree.MaxDepth,
 			CrawledAt:  tree.CrawledAt,
 			IsPublic:   tree.Config.IsPublic,
-			LeafTypes:  e.getLeafTypeNames(tree),
+			LeafTypes:  getLeafTypeNames(tree),
 		}
 	}
 
-	// 创建摘要导出结构
-	export := struct {
-		Metadata ExportMetadata       `json:"metadata"`
-		Summary  []ServiceTreeSummary `json:"summary"`
-	}{
-		Metadata: ExportMetadata{
-			ExportedAt: time.Now(),
-			Format:     string(e.format),
-			Version:    "1.0",
-			TreeCount:  len(data),
-			TotalNodes: e.countTotalNodes(data),
-		},
-		Summary: summaries,
+	return summaries
+}
+
+// SupportsSummary 判断当前格式是否支持导出摘要
+func (e *Exporter) SupportsSummary() bool {
+	switch e.format {
+	case FormatJSON, FormatYAML, FormatNDJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportSummary 导出服务树摘要信息
+func (e *Exporter) ExportSummary(data []*models.ServiceTreeData, outputPath string) error {
+	if !e.SupportsSummary() {
+		return fmt.Errorf("unsupported format for summary: %s", e.format)
 	}
 
+	// 创建摘要导出结构
+	export := NewSummaryExport(string(e.format), data)
+
 	file, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create summary file: %w", err)
@@ -280,6 +381,15 @@ func (e *Exporter) ExportSummary(data []*models.ServiceTreeData, outputPath stri
 		encoder := yaml.NewEncoder(file)
 		defer encoder.Close()
 		return encoder.Encode(export)
+	case FormatNDJSON:
+		// 每行一个服务树摘要
+		encoder := json.NewEncoder(file)
+		for _, summary := range export.Summary {
+			if err := encoder.Encode(summary); err != nil {
+				return err
+			}
+		}
+		return nil
 	default:
 		return fmt.Errorf("unsupported format for summary: %s", e.format)
 	}
@@ -298,7 +408,7 @@ type ServiceTreeSummary struct {
 }
 
 // getLeafTypeNames 获取叶子节点类型名称
-func (e *Exporter) getLeafTypeNames(tree *models.ServiceTreeData) []string {
+func getLeafTypeNames(tree *models.ServiceTreeData) []string {
 	var leafTypes []string
 	for _, showType := range tree.Config.ShowTypes {
 		leafTypes = append(leafTypes, showType.Name)
@@ -324,6 +434,22 @@ func (e *Exporter) GenerateFileName(prefix string, timestamp bool) string {
 		return filename + ".yaml"
 	case FormatCSV:
 		return filename + ".csv"
+	case FormatDOT:
+		return filename + ".dot"
+	case FormatGraphML:
+		return filename + ".graphml"
+	case FormatGEXF:
+		return filename + ".gexf"
+	case FormatNDJSON:
+		return filename + ".ndjson"
+	case FormatSQLite:
+		return filename + ".db"
+	case FormatXLSX:
+		return filename + ".xlsx"
+	case FormatMarkdown, "md":
+		return filename + ".md"
+	case FormatHTML:
+		return filename + ".html"
 	default:
 		return filename + ".txt"
 	}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// gexfDocument GEXF 1.2文档结构
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+// gexfMeta 文档元数据
+type gexfMeta struct {
+	LastModified string `xml:"lastmodifieddate,attr"`
+	Creator      string `xml:"creator"`
+	Description  string `xml:"description"`
+}
+
+// gexfGraph 图
+type gexfGraph struct {
+	Mode            string           `xml:"mode,attr"`
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+// gexfAttributes 节点或边的属性定义
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+// gexfAttribute 属性定义
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+// gexfNode 节点
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+// gexfEdge 边
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+// gexfAttValue 属性值
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// exportGEXF 导出为GEXF格式，供Gephi使用
+func (e *Exporter) exportGEXF(data []*models.ServiceTreeData, outputPath string) error {
+	return e.writeGEXF(e.buildServiceGraph(data), fmt.Sprintf("%d service trees", len(data)), outputPath)
+}
+
+// writeGEXF 将图写入GEXF文件
+func (e *Exporter) writeGEXF(graph *serviceGraph, description, outputPath string) error {
+	nodeAttrs := gexfAttributes{
+		Class: "node",
+		Attributes: []gexfAttribute{
+			{ID: graphKeyType, Title: graphKeyType, Type: string(graphAttrLong)},
+			{ID: graphKeyTypeName, Title: graphKeyTypeName, Type: string(graphAttrString)},
+		},
+	}
+	attrIDs := make(map[string]string, len(graph.Attrs))
+	for i, attr := range graph.Attrs {
+		id := "a" + strconv.Itoa(i)
+		attrIDs[attr.Name] = id
+		nodeAttrs.Attributes = append(nodeAttrs.Attributes, gexfAttribute{ID: id, Title: attr.Name, Type: string(attr.Kind)})
+	}
+
+	doc := gexfDocument{
+		XMLNS:   "http://www.gexf.net/1.2draft",
+		Version: "1.2",
+		Meta: gexfMeta{
+			LastModified: time.Now().Format("2006-01-02"),
+			Creator:      "cmdb-crawler",
+			Description:  description,
+		},
+		Graph: gexfGraph{
+			Mode:            "static",
+			DefaultEdgeType: "directed",
+			Attributes: []gexfAttributes{
+				nodeAttrs,
+				{
+					Class:      "edge",
+					Attributes: []gexfAttribute{{ID: graph.EdgeKey, Title: graph.EdgeKey, Type: string(graphAttrString)}},
+				},
+			},
+		},
+	}
+
+	for _, node := range graph.Nodes {
+		gNode := gexfNode{
+			ID:    strconv.Itoa(node.ID),
+			Label: node.Name,
+			AttValues: []gexfAttValue{
+				{For: graphKeyType, Value: strconv.Itoa(node.Type)},
+				{For: graphKeyTypeName, Value: node.TypeName},
+			},
+		}
+		for _, attr := range graph.Attrs {
+			value, ok := node.Attributes[attr.Name]
+			if !ok || value == nil {
+				continue
+			}
+			gNode.AttValues = append(gNode.AttValues, gexfAttValue{For: attrIDs[attr.Name], Value: formatGraphValue(value, attr.Kind)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gNode)
+	}
+
+	for i, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:        strconv.Itoa(i),
+			Source:    strconv.Itoa(edge.Source),
+			Target:    strconv.Itoa(edge.Target),
+			Label:     edge.Label,
+			AttValues: []gexfAttValue{{For: graph.EdgeKey, Value: edge.Label}},
+		})
+	}
+
+	if err := e.writeXML(outputPath, doc); err != nil {
+		return fmt.Errorf("failed to write GEXF: %w", err)
+	}
+
+	e.logger.Info("Successfully exported GEXF",
+		zap.String("file", outputPath),
+		zap.Int("nodes", len(graph.Nodes)),
+		zap.Int("edges", len(graph.Edges)))
+	return nil
+}
@@ -0,0 +1,223 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"cmdb-crawler/internal/models"
+)
+
+// graphAttrKind 图属性的数据类型
+type graphAttrKind string
+
+const (
+	graphAttrString  graphAttrKind = "string"
+	graphAttrLong    graphAttrKind = "long"
+	graphAttrDouble  graphAttrKind = "double"
+	graphAttrBoolean graphAttrKind = "boolean"
+)
+
+// 节点固定属性名，与CI属性同名时固定属性优先
+const (
+	graphKeyName     = "ci_name"
+	graphKeyType     = "ci_type_id"
+	graphKeyTypeName = "ci_type_name"
+	graphKeyView     = "view_name"
+	graphKeyRelation = "relation_type"
+)
+
+// graphAttr 节点属性定义
+type graphAttr struct {
+	Name string
+	Kind graphAttrKind
+}
+
+// graphEdge 父子关系边，Label为边属性EdgeKey的值
+type graphEdge struct {
+	Source int
+	Target int
+	Label  string
+}
+
+// serviceGraph 多个服务树合并后的图，同一CI在多个视图或多个父节点下只保留一个节点
+type serviceGraph struct {
+	Nodes []*models.ServiceTreeNode
+	Edges []graphEdge
+	Attrs []graphAttr
+	// EdgeKey 边属性名：服务树为所属视图，关系图为关系类型
+	EdgeKey string
+}
+
+// buildServiceGraph 将服务树展开为节点和边，节点按首次出现的顺序排列
+func (e *Exporter) buildServiceGraph(data []*models.ServiceTreeData) *serviceGraph {
+	graph := &serviceGraph{EdgeKey: graphKeyView}
+	seenNodes := make(map[int]bool)
+	seenEdges := make(map[graphEdge]bool)
+
+	var walk func(viewName string, node *models.ServiceTreeNode)
+	walk = func(viewName string, node *models.ServiceTreeNode) {
+		if !seenNodes[node.ID] {
+			seenNodes[node.ID] = true
+			graph.Nodes = append(graph.Nodes, node)
+		}
+
+		if !e.withinMaxDepth(node.Level + 1) {
+			return
+		}
+
+		for _, child := range node.Children {
+			edge := graphEdge{Source: node.ID, Target: child.ID, Label: viewName}
+			if !seenEdges[edge] {
+				seenEdges[edge] = true
+				graph.Edges = append(graph.Edges, edge)
+			}
+			walk(viewName, child)
+		}
+	}
+
+	for _, tree := range data {
+		for _, root := range tree.RootNodes {
+			walk(tree.ViewName, root)
+		}
+	}
+
+	graph.Attrs = collectGraphAttrs(graph.Nodes)
+	return graph
+}
+
+// buildCIGraph 将关系图转换为导出用的图，边属性为关系类型
+func buildCIGraph(ciGraph *models.CIGraph) *serviceGraph {
+	graph := &serviceGraph{EdgeKey: graphKeyRelation}
+
+	for _, node := range ciGraph.Nodes {
+		graph.Nodes = append(graph.Nodes, &models.ServiceTreeNode{
+			ID:         node.ID,
+			Type:       node.Type,
+			TypeName:   node.TypeName,
+			Name:       node.Name,
+			Level:      node.Depth,
+			Attributes: node.Attributes,
+		})
+	}
+	for _, edge := range ciGraph.Edges {
+		graph.Edges = append(graph.Edges, graphEdge{Source: edge.Source, Target: edge.Target, Label: edge.RelationType})
+	}
+
+	graph.Attrs = collectGraphAttrs(graph.Nodes)
+	return graph
+}
+
+// collectGraphAttrs 汇总节点属性并推断类型，按属性名排序
+func collectGraphAttrs(nodes []*models.ServiceTreeNode) []graphAttr {
+	kinds := make(map[string]graphAttrKind)
+	for _, node := range nodes {
+		for key, value := range node.Attributes {
+			if isReservedGraphKey(key) {
+				continue
+			}
+			if kind, ok := graphValueKind(value); ok {
+				kinds[key] = mergeGraphAttrKind(kinds[key], kind)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]graphAttr, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, graphAttr{Name: name, Kind: kinds[name]})
+	}
+	return attrs
+}
+
+// isReservedGraphKey 判断属性名是否与节点固定属性冲突
+func isReservedGraphKey(key string) bool {
+	switch key {
+	case graphKeyName, graphKeyType, graphKeyTypeName:
+		return true
+	}
+	return false
+}
+
+// graphValueKind 推断属性值的类型，nil值不参与推断
+func graphValueKind(value interface{}) (graphAttrKind, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case bool:
+		return graphAttrBoolean, true
+	case int, int32, int64:
+		return graphAttrLong, true
+	case float32:
+		return floatKind(float64(v)), true
+	case float64:
+		return floatKind(v), true
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return graphAttrLong, true
+		}
+		return graphAttrDouble, true
+	default:
+		return graphAttrString, true
+	}
+}
+
+// floatKind JSON数字统一解码为float64，整数值按long处理
+func floatKind(v float64) graphAttrKind {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		return graphAttrLong
+	}
+	return graphAttrDouble
+}
+
+// mergeGraphAttrKind 合并同名属性在不同节点上的类型，无法统一时退化为字符串
+func mergeGraphAttrKind(current, next graphAttrKind) graphAttrKind {
+	switch {
+	case current == "" || current == next:
+		return next
+	case (current == graphAttrLong && next == graphAttrDouble) || (current == graphAttrDouble && next == graphAttrLong):
+		return graphAttrDouble
+	default:
+		return graphAttrString
+	}
+}
+
+// formatGraphValue 按属性类型格式化属性值，列表和对象编码为JSON字符串
+func formatGraphValue(value interface{}, kind graphAttrKind) string {
+	switch kind {
+	case graphAttrLong:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatInt(int64(v), 10)
+		case float32:
+			return strconv.FormatInt(int64(v), 10)
+		}
+	case graphAttrDouble:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		case float32:
+			return strconv.FormatFloat(float64(v), 'g', -1, 32)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool, int, int32, int64, float32, float64, json.Number:
+		return fmt.Sprintf("%v", v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
@@ -0,0 +1,104 @@
+package output
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildGraphTrees 构建两个共享全部节点的服务树，产品节点带有各种类型的属性
+func buildGraphTrees() []*models.ServiceTreeData {
+	trees := []*models.ServiceTreeData{newTestTree("产品服务树"), newTestTree("业务服务树")}
+	for _, tree := range trees {
+		testNode(tree, 2).Attributes = map[string]interface{}{
+			"port":    float64(8080),
+			"weight":  0.5,
+			"online":  true,
+			"owners":  []interface{}{"alice", "bob"},
+			"ci_name": "shadowed",
+		}
+	}
+	return trees
+}
+
+// TestBuildServiceGraph 测试节点去重、按视图区分的边和属性类型推断
+func TestBuildServiceGraph(t *testing.T) {
+	exporter := NewExporter("graphml", false, zap.NewNop())
+	graph := exporter.buildServiceGraph(buildGraphTrees())
+
+	if len(graph.Nodes) != 3 {
+		t.Errorf("Expected shared nodes to be deduplicated into 3 nodes, got %d", len(graph.Nodes))
+	}
+	labels := make(map[string]int)
+	for _, edge := range graph.Edges {
+		labels[edge.Label]++
+	}
+	if len(graph.Edges) != 4 || len(labels) != 2 || labels["产品服务树"] != 2 {
+		t.Errorf("Expected separate edges per view, got %+v", graph.Edges)
+	}
+
+	kinds := make(map[string]graphAttrKind)
+	for _, attr := range graph.Attrs {
+		kinds[attr.Name] = attr.Kind
+	}
+	expected := map[string]graphAttrKind{
+		"port":   graphAttrLong,
+		"weight": graphAttrDouble,
+		"online": graphAttrBoolean,
+		"owners": graphAttrString,
+	}
+	for name, kind := range expected {
+		if kinds[name] != kind {
+			t.Errorf("Expected attribute %s to be %s, got %s", name, kind, kinds[name])
+		}
+	}
+	if _, ok := kinds[graphKeyName]; ok {
+		t.Error("Expected attribute clashing with reserved key to be skipped")
+	}
+
+	if got := formatGraphValue([]interface{}{"alice", "bob"}, graphAttrString); got != `["alice","bob"]` {
+		t.Errorf("Expected list to be JSON encoded, got %s", got)
+	}
+	if got := mergeGraphAttrKind(graphAttrLong, graphAttrDouble); got != graphAttrDouble {
+		t.Errorf("Expected long and double to merge into double, got %s", got)
+	}
+}
+
+// TestExportGraphMLAndGEXF 测试导出文件为合法XML并包含视图边属性
+func TestExportGraphMLAndGEXF(t *testing.T) {
+	for _, format := range []string{"graphml", "gexf"} {
+		t.Run(format, func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "trees."+format)
+			exporter := NewExporter(format, true, zap.NewNop())
+			if err := exporter.ExportServiceTrees(buildGraphTrees(), outputPath); err != nil {
+				t.Fatalf("ExportServiceTrees failed: %v", err)
+			}
+
+			content, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatalf("Failed to read export: %v", err)
+			}
+
+			var doc struct {
+				Nodes []struct{} `xml:"graph>node"`
+				Edges []struct{} `xml:"graph>edge"`
+				// GEXF将节点和边包在nodes/edges元素中
+				GEXFNodes []struct{} `xml:"graph>nodes>node"`
+				GEXFEdges []struct{} `xml:"graph>edges>edge"`
+			}
+			if err := xml.Unmarshal(content, &doc); err != nil {
+				t.Fatalf("Expected valid XML, got error: %v", err)
+			}
+
+			nodes, edges := len(doc.Nodes)+len(doc.GEXFNodes), len(doc.Edges)+len(doc.GEXFEdges)
+			if nodes != 3 || edges != 4 {
+				t.Errorf("Expected 3 nodes and 4 edges, got %d nodes and %d edges", nodes, edges)
+			}
+		})
+	}
+}
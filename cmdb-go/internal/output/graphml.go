@@ -0,0 +1,151 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// graphMLDocument GraphML文档结构
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// graphMLKey 属性定义
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// graphMLGraph 图
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+// graphMLNode 节点
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// graphMLEdge 边
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// graphMLData 属性值
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// exportGraphML 导出为GraphML格式，所有视图合并为一个有向图，边上记录所属视图
+func (e *Exporter) exportGraphML(data []*models.ServiceTreeData, outputPath string) error {
+	return e.writeGraphML(e.buildServiceGraph(data), "service_trees", outputPath)
+}
+
+// writeGraphML 将图写入GraphML文件
+func (e *Exporter) writeGraphML(graph *serviceGraph, graphID, outputPath string) error {
+	doc := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: graphKeyName, For: "node", AttrName: graphKeyName, AttrType: string(graphAttrString)},
+			{ID: graphKeyType, For: "node", AttrName: graphKeyType, AttrType: string(graphAttrLong)},
+			{ID: graphKeyTypeName, For: "node", AttrName: graphKeyTypeName, AttrType: string(graphAttrString)},
+			{ID: graph.EdgeKey, For: "edge", AttrName: graph.EdgeKey, AttrType: string(graphAttrString)},
+		},
+		Graph: graphMLGraph{ID: graphID, EdgeDefault: "directed"},
+	}
+
+	// CI属性名可能包含XML id不允许的字符，key id使用序号
+	attrKeys := make(map[string]string, len(graph.Attrs))
+	for i, attr := range graph.Attrs {
+		id := "a" + strconv.Itoa(i)
+		attrKeys[attr.Name] = id
+		doc.Keys = append(doc.Keys, graphMLKey{ID: id, For: "node", AttrName: attr.Name, AttrType: string(attr.Kind)})
+	}
+
+	for _, node := range graph.Nodes {
+		gNode := graphMLNode{
+			ID: graphNodeID(node.ID),
+			Data: []graphMLData{
+				{Key: graphKeyName, Value: node.Name},
+				{Key: graphKeyType, Value: strconv.Itoa(node.Type)},
+				{Key: graphKeyTypeName, Value: node.TypeName},
+			},
+		}
+		for _, attr := range graph.Attrs {
+			value, ok := node.Attributes[attr.Name]
+			if !ok || value == nil {
+				continue
+			}
+			gNode.Data = append(gNode.Data, graphMLData{Key: attrKeys[attr.Name], Value: formatGraphValue(value, attr.Kind)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gNode)
+	}
+
+	for i, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: graphNodeID(edge.Source),
+			Target: graphNodeID(edge.Target),
+			Data:   []graphMLData{{Key: graph.EdgeKey, Value: edge.Label}},
+		})
+	}
+
+	if err := e.writeXML(outputPath, doc); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+
+	e.logger.Info("Successfully exported GraphML",
+		zap.String("file", outputPath),
+		zap.Int("nodes", len(graph.Nodes)),
+		zap.Int("edges", len(graph.Edges)))
+	return nil
+}
+
+// graphNodeID 生成图节点ID
+func graphNodeID(id int) string {
+	return "n" + strconv.Itoa(id)
+}
+
+// writeXML 写入带XML声明的文档
+func (e *Exporter) writeXML(outputPath string, doc interface{}) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(file)
+	if e.prettyPrint {
+		encoder.Indent("", "  ")
+	}
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if _, err := file.WriteString("\n"); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"time"
+
+	"cmdb-crawler/internal/models"
+)
+
+// newTestTree 构建各导出格式共用的三层测试服务树：电商产品线(#1) > 电商APP(#2) > 生产环境(#3)
+// 各格式的测试在此基础上修改节点名称、属性等自身关注的部分
+func newTestTree(viewName string) *models.ServiceTreeData {
+	root := &models.ServiceTreeNode{ID: 1, Type: 1, TypeName: "产品线", Name: "电商产品线",
+		Attributes: map[string]interface{}{"owner": "alice"}}
+	app := &models.ServiceTreeNode{ID: 2, Type: 2, TypeName: "产品", Name: "电商APP",
+		Attributes: map[string]interface{}{"port": float64(8080)}}
+	env := &models.ServiceTreeNode{ID: 3, Type: 3, TypeName: "环境", Name: "生产环境", IsLeaf: true}
+	root.AddChild(app)
+	app.AddChild(env)
+	root.ChildCount = 1
+	app.ChildCount = 1
+
+	types := []models.CIType{{ID: 1, Name: "product_line"}, {ID: 2, Name: "product"}, {ID: 3, Name: "env"}}
+	tree := &models.ServiceTreeData{
+		ViewName: viewName,
+		ViewID:   1,
+		Config: models.ServiceTreeView{
+			Topo:      [][]int{{1}, {2}, {3}},
+			ShowTypes: types,
+		},
+		ID2Type:   map[string]models.CIType{"1": types[0], "2": types[1], "3": types[2]},
+		RootNodes: []*models.ServiceTreeNode{root},
+		CrawledAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tree.CountNodes()
+	tree.CalculateMaxDepth()
+	return tree
+}
+
+// testNode 返回测试服务树中指定ID的节点
+func testNode(tree *models.ServiceTreeData, id int) *models.ServiceTreeNode {
+	var find func(nodes []*models.ServiceTreeNode) *models.ServiceTreeNode
+	find = func(nodes []*models.ServiceTreeNode) *models.ServiceTreeNode {
+		for _, node := range nodes {
+			if node.ID == id {
+				return node
+			}
+			if found := find(node.Children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(tree.RootNodes)
+}
@@ -0,0 +1,189 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// inventoryCSVHeaders 全量CI清单CSV的固定列，属性列追加在后面
+var inventoryCSVHeaders = []string{"ci_id", "type_id", "type_name", "name"}
+
+// CIInventoryExport 全量CI清单导出文件结构（JSON/YAML）
+type CIInventoryExport struct {
+	Metadata  InventoryMetadata   `json:"metadata"`
+	Inventory *models.CIInventory `json:"inventory"`
+}
+
+// InventoryMetadata 全量CI清单导出元数据
+type InventoryMetadata struct {
+	ExportedAt time.Time `json:"exported_at" yaml:"exported_at"`
+	Format     string    `json:"format" yaml:"format"`
+	Version    string    `json:"version" yaml:"version"`
+	TypeCount  int       `json:"type_count" yaml:"type_count"`
+	TotalCIs   int       `json:"total_cis" yaml:"total_cis"`
+}
+
+// SupportsInventory 判断当前格式是否支持导出全量CI清单
+func (e *Exporter) SupportsInventory() bool {
+	switch e.format {
+	case FormatJSON, FormatYAML, FormatCSV, FormatNDJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportCIInventory 导出全量CI清单
+// CSV每个CI类型一个文件（如 cis.csv -> cis_1_server.csv），属性列为该类型属性键的并集；NDJSON每行一个CI
+func (e *Exporter) ExportCIInventory(inventory *models.CIInventory, outputPath string) error {
+	e.logger.Info("Exporting CI inventory",
+		zap.String("format", string(e.format)),
+		zap.String("output_path", outputPath),
+		zap.Int("type_count", len(inventory.Types)),
+		zap.Int("total_cis", inventory.TotalCIs))
+
+	if err := e.ensureOutputDir(outputPath); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	switch e.format {
+	case FormatJSON, FormatYAML:
+		return e.exportInventoryDocument(inventory, outputPath)
+	case FormatCSV:
+		return e.exportInventoryCSV(inventory, outputPath)
+	case FormatNDJSON:
+		return e.exportInventoryNDJSON(inventory, outputPath)
+	default:
+		return fmt.Errorf("unsupported format for CI inventory: %s", e.format)
+	}
+}
+
+// exportInventoryDocument 导出为带元数据的JSON或YAML文件
+func (e *Exporter) exportInventoryDocument(inventory *models.CIInventory, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create inventory file: %w", err)
+	}
+	defer file.Close()
+
+	export := CIInventoryExport{
+		Metadata: InventoryMetadata{
+			ExportedAt: time.Now(),
+			Format:     string(e.format),
+			Version:    "1.0",
+			TypeCount:  len(inventory.Types),
+			TotalCIs:   inventory.TotalCIs,
+		},
+		Inventory: inventory,
+	}
+
+	if e.format == FormatYAML {
+		encoder := yaml.NewEncoder(file)
+		defer encoder.Close()
+		if err := encoder.Encode(export); err != nil {
+			return fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	} else {
+		encoder := json.NewEncoder(file)
+		if e.prettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(export); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	}
+
+	e.logger.Info("Successfully exported CI inventory", zap.String("file", outputPath))
+	return nil
+}
+
+// exportInventoryCSV 每个CI类型导出一个CSV文件，属性列受CSV包含/排除/展开选项控制
+func (e *Exporter) exportInventoryCSV(inventory *models.CIInventory, outputPath string) error {
+	for _, typeInventory := range inventory.Types {
+		ciType := typeInventory.Type
+		path := CSVGroupFilePath(outputPath, strconv.Itoa(ciType.ID)+"_"+ciType.Name)
+
+		keys := make(map[string]bool)
+		rows := make([]map[string]string, len(typeInventory.CIs))
+		for i, ci := range typeInventory.CIs {
+			rows[i] = e.flattenAttributes(ci.Attributes)
+			for key := range rows[i] {
+				keys[key] = true
+			}
+		}
+		columns := e.selectAttributeColumns(keys)
+
+		if err := writeInventoryCSV(path, typeInventory.CIs, rows, columns); err != nil {
+			return err
+		}
+		e.logger.Info("Successfully exported CSV",
+			zap.String("file", path),
+			zap.Int("rows", len(typeInventory.CIs)))
+	}
+	return nil
+}
+
+// writeInventoryCSV 写入单个类型的CI
+func writeInventoryCSV(path string, cis []models.CIRecord, attrs []map[string]string, columns []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(append(append([]string{}, inventoryCSVHeaders...), columns...)); err != nil {
+		return fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+
+	for i, ci := range cis {
+		record := []string{strconv.Itoa(ci.ID), strconv.Itoa(ci.Type), ci.TypeName, ci.Name}
+		for _, column := range columns {
+			record = append(record, attrs[i][column])
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	return nil
+}
+
+// exportInventoryNDJSON 每行一个CI
+func (e *Exporter) exportInventoryNDJSON(inventory *models.CIInventory, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create NDJSON file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, typeInventory := range inventory.Types {
+		for _, ci := range typeInventory.CIs {
+			if err := encoder.Encode(ci); err != nil {
+				return fmt.Errorf("failed to encode CI %d: %w", ci.ID, err)
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write NDJSON file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported NDJSON", zap.String("file", outputPath))
+	return nil
+}
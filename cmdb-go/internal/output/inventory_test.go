@@ -0,0 +1,85 @@
+package output
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildTestInventory 构建两个类型的CI清单
+func buildTestInventory() *models.CIInventory {
+	inventory := &models.CIInventory{
+		Types: []*models.CITypeInventory{
+			{
+				Type: models.CITypeDefinition{ID: 4, Name: "server", Alias: "服务器"},
+				CIs: []models.CIRecord{
+					{ID: 10, Type: 4, TypeName: "服务器", Name: "web-1", Attributes: map[string]interface{}{"cpu": float64(8), "password": "x"}},
+					{ID: 11, Type: 4, TypeName: "服务器", Name: "web-2", Attributes: map[string]interface{}{"mem": float64(16)}},
+				},
+			},
+			{
+				Type: models.CITypeDefinition{ID: 5, Name: "ip", Alias: "IP"},
+				CIs:  []models.CIRecord{{ID: 20, Type: 5, TypeName: "IP", Name: "10.0.0.1"}},
+			},
+		},
+	}
+	inventory.CountCIs()
+	return inventory
+}
+
+// TestExportCIInventoryCSV 测试每个CI类型导出一个CSV文件并带属性列
+func TestExportCIInventoryCSV(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewExporter("csv", false, zap.NewNop()).SetCSVOptions(CSVOptions{Exclude: []string{"password"}})
+	if err := exporter.ExportCIInventory(buildTestInventory(), filepath.Join(dir, "cis.csv")); err != nil {
+		t.Fatalf("ExportCIInventory failed: %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "cis_4_server.csv"))
+	if err != nil {
+		t.Fatalf("Expected per-type CSV file: %v", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if got := strings.Join(records[0], ","); got != "ci_id,type_id,type_name,name,cpu,mem" {
+		t.Errorf("Unexpected headers: %s", got)
+	}
+	if len(records) != 3 || records[1][4] != "8" || records[2][5] != "16" {
+		t.Errorf("Unexpected rows: %v", records)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "cis_5_ip.csv")); err != nil {
+		t.Errorf("Expected CSV file for ip type: %v", err)
+	}
+}
+
+// TestExportCIInventoryNDJSON 测试每行一个CI
+func TestExportCIInventoryNDJSON(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "cis.ndjson")
+	if err := NewExporter("ndjson", false, zap.NewNop()).ExportCIInventory(buildTestInventory(), outputPath); err != nil {
+		t.Fatalf("ExportCIInventory failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read NDJSON: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"cpu":8`) {
+		t.Errorf("Unexpected NDJSON content: %s", content)
+	}
+
+	if NewExporter("dot", false, zap.NewNop()).SupportsInventory() {
+		t.Error("Expected DOT not to support CI inventory export")
+	}
+}
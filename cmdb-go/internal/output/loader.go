@@ -0,0 +1,35 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadServiceTrees 读取ExportServiceTrees导出的JSON或YAML文件
+// 格式根据文件扩展名判断，.yaml/.yml为YAML，其余按JSON解析
+func LoadServiceTrees(path string) (*ServiceTreeExport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export file: %w", err)
+	}
+
+	var export ServiceTreeExport
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &export); err != nil {
+			return nil, fmt.Errorf("failed to decode YAML export: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(content, &export); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON export: %w", err)
+		}
+	}
+
+	return &export, nil
+}
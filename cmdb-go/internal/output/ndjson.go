@@ -0,0 +1,161 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// NodeRecord NDJSON导出中的单行节点记录
+type NodeRecord struct {
+	ViewName   string                 `json:"view_name"`
+	ViewID     int                    `json:"view_id"`
+	NodeID     int                    `json:"node_id"`
+	Type       int                    `json:"type"`
+	TypeName   string                 `json:"type_name"`
+	Name       string                 `json:"name"`
+	Path       string                 `json:"path"`
+	ParentID   int                    `json:"parent_id"`
+	Level      int                    `json:"level"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Statistics map[string]int         `json:"statistics,omitempty"`
+}
+
+// NewNodeRecord 根据服务树节点创建记录，根节点的parentID为0
+func NewNodeRecord(viewName string, viewID int, parentID int, node *models.ServiceTreeNode) NodeRecord {
+	return NodeRecord{
+		ViewName:   viewName,
+		ViewID:     viewID,
+		NodeID:     node.ID,
+		Type:       node.Type,
+		TypeName:   node.TypeName,
+		Name:       node.Name,
+		Path:       node.BuildTreePath(),
+		ParentID:   parentID,
+		Level:      node.Level,
+		Attributes: node.Attributes,
+		Statistics: node.Statistics,
+	}
+}
+
+// NodeStream NDJSON流式写入器，每写入一个节点输出一行
+// 可并发调用WriteNode，用于爬取过程中边发现边导出
+type NodeStream struct {
+	logger *zap.Logger
+	path   string
+	// maxDepth 与其他导出格式一致，超过最大深度的节点不输出
+	maxDepth int
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+// OpenNodeStream 创建NDJSON流式写入器
+func (e *Exporter) OpenNodeStream(outputPath string) (*NodeStream, error) {
+	if err := e.ensureOutputDir(outputPath); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NDJSON file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	return &NodeStream{
+		logger:   e.logger,
+		path:     outputPath,
+		maxDepth: e.maxDepth,
+		file:     file,
+		writer:   writer,
+		encoder:  json.NewEncoder(writer),
+	}, nil
+}
+
+// WriteNode 写入一个节点，不包含其子节点，超过最大深度的节点直接忽略
+func (s *NodeStream) WriteNode(viewName string, viewID int, parentID int, node *models.ServiceTreeNode) error {
+	if !withinDepth(s.maxDepth, node.Level) {
+		return nil
+	}
+
+	record := NewNodeRecord(viewName, viewID, parentID, node)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode node %d: %w", node.ID, err)
+	}
+	s.count++
+	return nil
+}
+
+// Count 返回已写入的节点数
+func (s *NodeStream) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Close 刷新缓冲并关闭文件
+func (s *NodeStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return fmt.Errorf("failed to write NDJSON file: %w", err)
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close NDJSON file: %w", err)
+	}
+
+	s.logger.Info("Successfully exported NDJSON",
+		zap.String("file", s.path),
+		zap.Int("nodes", s.count))
+	return nil
+}
+
+// exportNDJSON 将已爬取的服务树按深度优先顺序逐行导出
+func (e *Exporter) exportNDJSON(data []*models.ServiceTreeData, outputPath string) error {
+	stream, err := e.OpenNodeStream(outputPath)
+	if err != nil {
+		return err
+	}
+
+	var walk func(tree *models.ServiceTreeData, parentID int, node *models.ServiceTreeNode) error
+	walk = func(tree *models.ServiceTreeData, parentID int, node *models.ServiceTreeNode) error {
+		if !e.withinMaxDepth(node.Level) {
+			return nil
+		}
+		if err := stream.WriteNode(tree.ViewName, tree.ViewID, parentID, node); err != nil {
+			return err
+		}
+		for _, child := range node.Children {
+			if err := walk(tree, node.ID, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, tree := range data {
+		for _, root := range tree.RootNodes {
+			if err := walk(tree, 0, root); err != nil {
+				stream.Close()
+				return err
+			}
+		}
+	}
+
+	return stream.Close()
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// TestExportNDJSON 测试每行一个节点并包含父节点和路径
+func TestExportNDJSON(t *testing.T) {
+	tree := newTestTree("产品服务树")
+	tree.ViewID = 7
+
+	outputPath := filepath.Join(t.TempDir(), "nodes.ndjson")
+	exporter := NewExporter("ndjson", true, zap.NewNop())
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{tree}, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	defer file.Close()
+
+	var records []NodeRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record NodeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Expected one JSON object per line, got %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	last := records[2]
+	if last.ParentID != 2 || last.Level != 2 || last.Path != "电商产品线 > 电商APP > 生产环境" || last.ViewID != 7 {
+		t.Errorf("Unexpected leaf record: %+v", last)
+	}
+	if records[0].Attributes["owner"] != "alice" {
+		t.Errorf("Expected attributes to be exported, got %+v", records[0].Attributes)
+	}
+}
+
+// TestExportNDJSONMaxDepth 测试与其他格式一致地按最大深度截断，流式写入同样生效
+func TestExportNDJSONMaxDepth(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "nodes.ndjson")
+	exporter := NewExporter("ndjson", false, zap.NewNop()).SetMaxDepth(2)
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{newTestTree("产品服务树")}, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 2 || strings.Contains(string(content), "生产环境") {
+		t.Errorf("Expected only the first 2 levels, got %d lines:\n%s", lines, content)
+	}
+
+	stream, err := exporter.OpenNodeStream(filepath.Join(t.TempDir(), "stream.ndjson"))
+	if err != nil {
+		t.Fatalf("OpenNodeStream failed: %v", err)
+	}
+	env := testNode(newTestTree("产品服务树"), 3)
+	if err := stream.WriteNode("产品服务树", 1, 2, env); err != nil {
+		t.Fatalf("WriteNode failed: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if stream.Count() != 0 {
+		t.Errorf("Expected node beyond max depth to be skipped, got %d nodes", stream.Count())
+	}
+}
@@ -0,0 +1,247 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// reportView 报告中单个服务树的数据
+type reportView struct {
+	Summary     ServiceTreeSummary
+	LevelCounts []int
+	RootNodes   []*models.ServiceTreeNode
+}
+
+// reportData 报告数据
+type reportData struct {
+	GeneratedAt time.Time
+	TotalNodes  int
+	Views       []reportView
+}
+
+// buildReportData 构建报告数据，每个视图附带摘要和每层节点数
+func buildReportData(data []*models.ServiceTreeData) reportData {
+	summaries := BuildSummaries(data)
+	report := reportData{
+		GeneratedAt: time.Now(),
+		TotalNodes:  countTotalNodes(data),
+		Views:       make([]reportView, len(data)),
+	}
+
+	for i, tree := range data {
+		report.Views[i] = reportView{
+			Summary:     summaries[i],
+			LevelCounts: countNodesPerLevel(tree),
+			RootNodes:   tree.RootNodes,
+		}
+	}
+	return report
+}
+
+// countNodesPerLevel 统计每一层的节点数，下标为层级
+func countNodesPerLevel(tree *models.ServiceTreeData) []int {
+	var counts []int
+
+	var walk func(node *models.ServiceTreeNode)
+	walk = func(node *models.ServiceTreeNode) {
+		for len(counts) <= node.Level {
+			counts = append(counts, 0)
+		}
+		counts[node.Level]++
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, root := range tree.RootNodes {
+		walk(root)
+	}
+	return counts
+}
+
+// exportMarkdown 导出为Markdown报告，每个服务树一节，节点为嵌套列表
+func (e *Exporter) exportMarkdown(data []*models.ServiceTreeData, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create Markdown file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := e.writeMarkdownReport(writer, buildReportData(data)); err != nil {
+		return fmt.Errorf("failed to write Markdown report: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write Markdown file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported Markdown", zap.String("file", outputPath))
+	return nil
+}
+
+// writeMarkdownReport 输出Markdown报告
+func (e *Exporter) writeMarkdownReport(w io.Writer, report reportData) error {
+	var b strings.Builder
+
+	b.WriteString("# Service Tree Report\n\n")
+	fmt.Fprintf(&b, "Generated at %s, %d views, %d nodes.\n",
+		report.GeneratedAt.Format("2006-01-02 15:04:05"), len(report.Views), report.TotalNodes)
+
+	for _, view := range report.Views {
+		summary := view.Summary
+		fmt.Fprintf(&b, "\n## %s\n\n", escapeMarkdown(summary.ViewName))
+		b.WriteString("| View ID | Roots | Nodes | Max Depth | Public | Crawled At | Leaf Types |\n")
+		b.WriteString("|---------|-------|-------|-----------|--------|------------|------------|\n")
+		fmt.Fprintf(&b, "| %d | %d | %d | %d | %t | %s | %s |\n\n",
+			summary.ViewID, summary.RootCount, summary.TotalNodes, summary.MaxDepth, summary.IsPublic,
+			summary.CrawledAt.Format("2006-01-02 15:04:05"), escapeMarkdown(strings.Join(summary.LeafTypes, ", ")))
+
+		b.WriteString("| Level | Nodes |\n")
+		b.WriteString("|-------|-------|\n")
+		for level, count := range view.LevelCounts {
+			fmt.Fprintf(&b, "| %d | %d |\n", level, count)
+		}
+		b.WriteString("\n")
+
+		for _, root := range view.RootNodes {
+			e.writeMarkdownNode(&b, root, 0)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownNode 递归输出嵌套列表项
+func (e *Exporter) writeMarkdownNode(b *strings.Builder, node *models.ServiceTreeNode, indent int) {
+	fmt.Fprintf(b, "%s- **%s**", strings.Repeat("  ", indent), escapeMarkdown(node.Name))
+	if node.TypeName != "" {
+		fmt.Fprintf(b, " (%s)", escapeMarkdown(node.TypeName))
+	}
+	fmt.Fprintf(b, " `#%d`\n", node.ID)
+
+	if !e.withinMaxDepth(node.Level + 1) {
+		return
+	}
+	for _, child := range node.Children {
+		e.writeMarkdownNode(b, child, indent+1)
+	}
+}
+
+// exportHTML 导出为HTML报告，节点可折叠并支持按名称搜索
+func (e *Exporter) exportHTML(data []*models.ServiceTreeData, outputPath string) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"expand": func(node *models.ServiceTreeNode) bool {
+			return len(node.Children) > 0 && e.withinMaxDepth(node.Level+1)
+		},
+		"formatTime": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+		"join": strings.Join,
+	}).Parse(htmlReportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := tmpl.Execute(writer, buildReportData(data)); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write HTML file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported HTML", zap.String("file", outputPath))
+	return nil
+}
+
+// htmlReportTemplate HTML报告模板，搜索时显示匹配节点及其祖先并展开
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>Service Tree Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; }
+th { background: #f6f8fa; }
+ul.tree { list-style: none; padding-left: 1.2em; }
+ul.tree li { margin: 2px 0; }
+.type { color: #57606a; font-size: 0.9em; }
+.id { color: #8c959f; font-size: 0.8em; }
+.match > summary .name, .match > .name { background: #fff8c5; }
+.hidden { display: none; }
+#search { width: 24em; padding: 4px 8px; margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>Service Tree Report</h1>
+<p>Generated at {{formatTime .GeneratedAt}}, {{len .Views}} views, {{.TotalNodes}} nodes.</p>
+<input id="search" type="search" placeholder="Search nodes by name or type">
+{{range .Views}}
+<section class="view">
+<h2>{{.Summary.ViewName}}</h2>
+<table>
+<tr><th>View ID</th><th>Roots</th><th>Nodes</th><th>Max Depth</th><th>Public</th><th>Crawled At</th><th>Leaf Types</th></tr>
+<tr><td>{{.Summary.ViewID}}</td><td>{{.Summary.RootCount}}</td><td>{{.Summary.TotalNodes}}</td><td>{{.Summary.MaxDepth}}</td><td>{{.Summary.IsPublic}}</td><td>{{formatTime .Summary.CrawledAt}}</td><td>{{join .Summary.LeafTypes ", "}}</td></tr>
+</table>
+<table>
+<tr><th>Level</th><th>Nodes</th></tr>
+{{range $level, $count := .LevelCounts}}<tr><td>{{$level}}</td><td>{{$count}}</td></tr>
+{{end}}</table>
+<ul class="tree">
+{{range .RootNodes}}{{template "node" .}}{{end}}</ul>
+</section>
+{{end}}
+<script>
+(function () {
+  var search = document.getElementById("search");
+  var items = Array.prototype.slice.call(document.querySelectorAll("ul.tree li"));
+  search.addEventListener("input", function () {
+    var query = search.value.trim().toLowerCase();
+    items.forEach(function (li) {
+      li.classList.remove("match");
+      li.classList.toggle("hidden", query !== "");
+    });
+    if (query === "") {
+      return;
+    }
+    items.forEach(function (li) {
+      if (li.getAttribute("data-search").toLowerCase().indexOf(query) === -1) {
+        return;
+      }
+      li.classList.add("match");
+      for (var el = li; el && el.tagName !== "SECTION"; el = el.parentElement) {
+        if (el.tagName === "LI") {
+          el.classList.remove("hidden");
+        }
+        if (el.tagName === "DETAILS" && el !== li.firstElementChild) {
+          el.open = true;
+        }
+      }
+    });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "node"}}<li data-search="{{.Name}} {{.TypeName}}">{{if expand .}}<details><summary><span class="name">{{.Name}}</span> <span class="type">{{.TypeName}}</span> <span class="id">#{{.ID}}</span></summary>
+<ul class="tree">
+{{range .Children}}{{template "node" .}}{{end}}</ul>
+</details>{{else}}<span class="name">{{.Name}}</span> <span class="type">{{.TypeName}}</span> <span class="id">#{{.ID}}</span>{{end}}</li>
+{{end}}`
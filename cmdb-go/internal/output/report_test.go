@@ -0,0 +1,70 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// exportReportString 导出报告并返回文件内容
+func exportReportString(t *testing.T, exporter *Exporter, fileName string) string {
+	outputPath := filepath.Join(t.TempDir(), fileName)
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{buildDOTTree()}, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	return string(content)
+}
+
+// TestExportMarkdown 测试Markdown报告的摘要、每层节点数和嵌套列表
+func TestExportMarkdown(t *testing.T) {
+	content := exportReportString(t, NewExporter("md", false, zap.NewNop()), "trees.md")
+
+	expected := []string{
+		"## 产品服务树",
+		"| Level | Nodes |",
+		"| 0 | 1 |\n| 1 | 1 |\n| 2 | 1 |",
+		"- **电商产品线** (产品线) `#1`\n  - **电商\"APP\"** (产品) `#2`\n    - **生产环境** (环境) `#3`\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("Markdown report missing %q:\n%s", want, content)
+		}
+	}
+
+	trimmed := exportReportString(t, NewExporter("markdown", false, zap.NewNop()).SetMaxDepth(2), "trees.md")
+	if strings.Contains(trimmed, "生产环境") {
+		t.Errorf("Expected max depth to trim level 2 nodes:\n%s", trimmed)
+	}
+}
+
+// TestExportHTML 测试HTML报告的折叠节点、搜索框和转义
+func TestExportHTML(t *testing.T) {
+	content := exportReportString(t, NewExporter("html", false, zap.NewNop()), "trees.html")
+
+	expected := []string{
+		`<input id="search"`,
+		`<details><summary><span class="name">电商产品线</span>`,
+		`<span class="name">电商&#34;APP&#34;</span>`,
+		`<li data-search="生产环境 环境"><span class="name">生产环境</span>`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("HTML report missing %q:\n%s", want, content)
+		}
+	}
+
+	trimmed := exportReportString(t, NewExporter("html", false, zap.NewNop()).SetMaxDepth(2), "trees.html")
+	if strings.Contains(trimmed, "生产环境") {
+		t.Error("Expected max depth to trim level 2 nodes")
+	}
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// EmbedsSchema 判断当前格式是否把CI类型模型图写入服务树导出文件本身
+// 其他格式需要通过ExportSchema单独导出
+func (e *Exporter) EmbedsSchema() bool {
+	switch e.format {
+	case FormatJSON, FormatYAML, FormatSQLite:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportSchema 单独导出CI类型模型图，YAML格式导出为YAML，其余格式导出为JSON
+func (e *Exporter) ExportSchema(schema *models.CITypeSchema, outputPath string) error {
+	if err := e.ensureOutputDir(outputPath); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create schema file: %w", err)
+	}
+	defer file.Close()
+
+	if e.format == FormatYAML {
+		encoder := yaml.NewEncoder(file)
+		defer encoder.Close()
+		if err := encoder.Encode(schema); err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+	} else {
+		encoder := json.NewEncoder(file)
+		if e.prettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(schema); err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+	}
+
+	e.logger.Info("Successfully exported CI type schema",
+		zap.String("file", outputPath),
+		zap.Int("type_count", len(schema.Types)))
+	return nil
+}
+
+// SchemaFilePath 生成单独导出CI类型模型图的文件路径，如 trees.csv -> trees_schema.json
+func (e *Exporter) SchemaFilePath(outputPath string) string {
+	ext := ".json"
+	if e.format == FormatYAML {
+		ext = ".yaml"
+	}
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_schema" + ext
+}
+
+// writeSchema 写入CI类型模型图：类型、属性定义和类型关系
+func (w *sqliteWriter) writeSchema(tx *sql.Tx, schema *models.CITypeSchema) error {
+	attribute, err := tx.Prepare(`INSERT OR IGNORE INTO ci_type_attributes (snapshot_id, type_id, attr_id, name, alias, value_type,
+		is_list, is_unique, is_required, is_reference, reference_type, inherited_from, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer attribute.Close()
+
+	relation, err := tx.Prepare(`INSERT OR IGNORE INTO ci_type_relations (snapshot_id, parent_type_id, child_type_id, relation_type, constraint_type)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer relation.Close()
+
+	for _, ciType := range schema.Types {
+		if _, err := w.ciType.Exec(w.snapshotID, ciType.ID, ciType.Name, ciType.Alias, ciType.UniqueKey, ciType.ShowName); err != nil {
+			return err
+		}
+		for _, attr := range ciType.Attributes {
+			if _, err := attribute.Exec(w.snapshotID, ciType.ID, attr.ID, attr.Name, attr.Alias, attr.ValueType,
+				attr.IsList, attr.IsUnique, attr.IsRequired, attr.IsReference, attr.ReferenceTypeID,
+				attr.InheritedFrom, attr.Order); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, rel := range schema.Relations {
+		relationType := ""
+		if rel.RelationType != nil {
+			relationType = rel.RelationType.Name
+		}
+		if _, err := relation.Exec(w.snapshotID, rel.ParentID, rel.ChildID, relationType, rel.Constraint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildTestSchema 构建产品线 > 产品两个类型的模型图
+func buildTestSchema() *models.CITypeSchema {
+	contain := &models.RelationType{ID: 1, Name: "contain"}
+	return &models.CITypeSchema{
+		Types: []models.CITypeDefinition{
+			{ID: 1, Name: "product_line", Alias: "产品线", UniqueKey: "name",
+				Attributes: []models.CIAttribute{{ID: 10, Name: "name", ValueType: models.ValueTypeText, IsUnique: true}}},
+			{ID: 2, Name: "product", Alias: "产品", UniqueKey: "name",
+				Attributes: []models.CIAttribute{{ID: 10, Name: "name"}, {ID: 11, Name: "port", ValueType: models.ValueTypeInt}}},
+			{ID: 3, Name: "server", Alias: "服务器"},
+		},
+		Relations: []models.CITypeRelation{
+			{ID: 1, ParentID: 1, ChildID: 2, RelationTypeID: 1, Constraint: models.ConstraintOneToMany, RelationType: contain},
+		},
+		RelationTypes: []models.RelationType{*contain},
+		CrawledAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// TestExportSchemaEmbedded 测试JSON导出内嵌模型图并可被重新读取
+func TestExportSchemaEmbedded(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "trees.json")
+	exporter := NewExporter("json", false, zap.NewNop()).SetSchema(buildTestSchema())
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{newTestTree("产品服务树")}, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	export, err := LoadServiceTrees(outputPath)
+	if err != nil {
+		t.Fatalf("LoadServiceTrees failed: %v", err)
+	}
+	if export.Schema == nil || len(export.Schema.Types) != 3 {
+		t.Fatalf("Expected schema with 3 types, got %+v", export.Schema)
+	}
+	if ciType, ok := export.Schema.TypeByID(2); !ok || len(ciType.Attributes) != 2 {
+		t.Errorf("Expected type 2 with 2 attributes, got %+v", ciType)
+	}
+	if len(export.Schema.ChildRelations(1)) != 1 {
+		t.Errorf("Expected relation product_line -> product, got %+v", export.Schema.Relations)
+	}
+}
+
+// TestExportSchemaSQLite 测试SQLite导出写入类型、属性和类型关系
+func TestExportSchemaSQLite(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "cmdb.db")
+	exporter := NewExporter("sqlite", false, zap.NewNop()).SetSchema(buildTestSchema())
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{newTestTree("产品服务树")}, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// 视图和模型图中的类型按ID合并
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM ci_types`); got != 3 {
+		t.Errorf("Expected 3 CI types, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM ci_type_attributes WHERE type_id = 2`); got != 2 {
+		t.Errorf("Expected 2 attributes for type 2, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM ci_type_relations WHERE parent_type_id = 1 AND child_type_id = 2 AND relation_type = 'contain'`); got != 1 {
+		t.Errorf("Expected contain relation, got %d", got)
+	}
+}
+
+// TestExportSchemaSidecar 测试不内嵌模型图的格式单独导出
+func TestExportSchemaSidecar(t *testing.T) {
+	exporter := NewExporter("csv", false, zap.NewNop())
+	if exporter.EmbedsSchema() {
+		t.Fatal("Expected CSV not to embed the schema")
+	}
+
+	outputPath := exporter.SchemaFilePath(filepath.Join(t.TempDir(), "trees.csv"))
+	if filepath.Base(outputPath) != "trees_schema.json" {
+		t.Errorf("Unexpected schema file path: %s", outputPath)
+	}
+	if err := exporter.ExportSchema(buildTestSchema(), outputPath); err != nil {
+		t.Fatalf("ExportSchema failed: %v", err)
+	}
+}
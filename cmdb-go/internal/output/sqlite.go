@@ -0,0 +1,346 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeLayout 时间统一以UTC的RFC3339格式保存，字符串顺序即时间顺序
+const sqliteTimeLayout = time.RFC3339Nano
+
+// sqliteSchema SQLite导出的表结构，所有数据表都以snapshot_id区分快照
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS snapshots (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		crawled_at  TEXT NOT NULL UNIQUE,
+		exported_at TEXT NOT NULL,
+		tree_count  INTEGER NOT NULL,
+		total_nodes INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS views (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+		view_id     INTEGER NOT NULL,
+		view_name   TEXT NOT NULL,
+		is_public   INTEGER NOT NULL,
+		topo        TEXT NOT NULL,
+		root_count  INTEGER NOT NULL,
+		total_nodes INTEGER NOT NULL,
+		max_depth   INTEGER NOT NULL,
+		crawled_at  TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, view_name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ci_types (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+		type_id     INTEGER NOT NULL,
+		name        TEXT NOT NULL,
+		alias       TEXT NOT NULL,
+		unique_name TEXT NOT NULL,
+		show_name   TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, type_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS nodes (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+		view_name   TEXT NOT NULL,
+		node_id     INTEGER NOT NULL,
+		type_id     INTEGER NOT NULL,
+		type_name   TEXT NOT NULL,
+		name        TEXT NOT NULL,
+		path        TEXT NOT NULL,
+		level       INTEGER NOT NULL,
+		parent_id   INTEGER NOT NULL,
+		is_leaf     INTEGER NOT NULL,
+		child_count INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS edges (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+		view_name   TEXT NOT NULL,
+		parent_id   INTEGER NOT NULL,
+		child_id    INTEGER NOT NULL,
+		PRIMARY KEY (snapshot_id, view_name, parent_id, child_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS attributes (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+		node_id     INTEGER NOT NULL,
+		key         TEXT NOT NULL,
+		value       TEXT NOT NULL,
+		value_type  TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, node_id, key)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ci_type_attributes (
+		snapshot_id    INTEGER NOT NULL REFERENCES snapshots(id),
+		type_id        INTEGER NOT NULL,
+		attr_id        INTEGER NOT NULL,
+		name           TEXT NOT NULL,
+		alias          TEXT NOT NULL,
+		value_type     TEXT NOT NULL,
+		is_list        INTEGER NOT NULL,
+		is_unique      INTEGER NOT NULL,
+		is_required    INTEGER NOT NULL,
+		is_reference   INTEGER NOT NULL,
+		reference_type INTEGER NOT NULL,
+		inherited_from TEXT NOT NULL,
+		sort_order     INTEGER NOT NULL,
+		PRIMARY KEY (snapshot_id, type_id, attr_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ci_type_relations (
+		snapshot_id     INTEGER NOT NULL REFERENCES snapshots(id),
+		parent_type_id  INTEGER NOT NULL,
+		child_type_id   INTEGER NOT NULL,
+		relation_type   TEXT NOT NULL,
+		constraint_type TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, parent_type_id, child_type_id, relation_type)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_nodes_node_id ON nodes (node_id, snapshot_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_nodes_type_id ON nodes (type_id, snapshot_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_nodes_parent_id ON nodes (parent_id, snapshot_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_nodes_view ON nodes (snapshot_id, view_name)`,
+	`CREATE INDEX IF NOT EXISTS idx_edges_child_id ON edges (child_id, snapshot_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_attributes_key ON attributes (key, snapshot_id)`,
+}
+
+// SetAppend 设置是否追加快照，目前只对SQLite导出生效，关闭时覆盖已有文件
+func (e *Exporter) SetAppend(enabled bool) *Exporter {
+	e.appendSnapshot = enabled
+	return e
+}
+
+// exportSQLite 导出为SQLite数据库，每次导出写入一个以爬取时间为键的快照
+func (e *Exporter) exportSQLite(data []*models.ServiceTreeData, outputPath string) error {
+	if !e.appendSnapshot {
+		if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove existing SQLite file: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range sqliteSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create SQLite schema: %w", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin SQLite transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	snapshotID, err := insertSnapshot(tx, data)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newSQLiteWriter(tx, snapshotID)
+	if err != nil {
+		return err
+	}
+	defer writer.close()
+
+	for _, tree := range data {
+		if err := writer.writeTree(tree); err != nil {
+			return fmt.Errorf("failed to write view %s: %w", tree.ViewName, err)
+		}
+	}
+
+	if e.schema != nil {
+		if err := writer.writeSchema(tx, e.schema); err != nil {
+			return fmt.Errorf("failed to write CI type schema: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit SQLite transaction: %w", err)
+	}
+
+	e.logger.Info("Successfully exported SQLite",
+		zap.String("file", outputPath),
+		zap.Int64("snapshot_id", snapshotID),
+		zap.Bool("append", e.appendSnapshot))
+	return nil
+}
+
+// snapshotTime 快照时间取最早的视图爬取时间，即本次爬取的开始时间
+func snapshotTime(data []*models.ServiceTreeData) time.Time {
+	var earliest time.Time
+	for _, tree := range data {
+		if earliest.IsZero() || (!tree.CrawledAt.IsZero() && tree.CrawledAt.Before(earliest)) {
+			earliest = tree.CrawledAt
+		}
+	}
+	if earliest.IsZero() {
+		earliest = time.Now()
+	}
+	return earliest.UTC()
+}
+
+// insertSnapshot 写入快照记录，同一爬取时间的快照不能重复写入
+func insertSnapshot(tx *sql.Tx, data []*models.ServiceTreeData) (int64, error) {
+	crawledAt := snapshotTime(data).Format(sqliteTimeLayout)
+
+	var existing int64
+	err := tx.QueryRow(`SELECT id FROM snapshots WHERE crawled_at = ?`, crawledAt).Scan(&existing)
+	if err == nil {
+		return 0, fmt.Errorf("snapshot crawled at %s already exists (id %d)", crawledAt, existing)
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+
+	result, err := tx.Exec(`INSERT INTO snapshots (crawled_at, exported_at, tree_count, total_nodes) VALUES (?, ?, ?, ?)`,
+		crawledAt, time.Now().UTC().Format(sqliteTimeLayout), len(data), countTotalNodes(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// sqliteWriter 在同一事务中复用预编译语句写入一个快照
+type sqliteWriter struct {
+	snapshotID int64
+	view       *sql.Stmt
+	ciType     *sql.Stmt
+	node       *sql.Stmt
+	edge       *sql.Stmt
+	attribute  *sql.Stmt
+}
+
+// newSQLiteWriter 预编译写入语句，边、类型和属性按主键去重
+func newSQLiteWriter(tx *sql.Tx, snapshotID int64) (*sqliteWriter, error) {
+	w := &sqliteWriter{snapshotID: snapshotID}
+
+	statements := []struct {
+		target **sql.Stmt
+		query  string
+	}{
+		{&w.view, `INSERT INTO views (snapshot_id, view_id, view_name, is_public, topo, root_count, total_nodes, max_depth, crawled_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`},
+		{&w.ciType, `INSERT OR IGNORE INTO ci_types (snapshot_id, type_id, name, alias, unique_name, show_name)
+			VALUES (?, ?, ?, ?, ?, ?)`},
+		{&w.node, `INSERT INTO nodes (snapshot_id, view_name, node_id, type_id, type_name, name, path, level, parent_id, is_leaf, child_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`},
+		{&w.edge, `INSERT OR IGNORE INTO edges (snapshot_id, view_name, parent_id, child_id) VALUES (?, ?, ?, ?)`},
+		{&w.attribute, `INSERT OR IGNORE INTO attributes (snapshot_id, node_id, key, value, value_type) VALUES (?, ?, ?, ?, ?)`},
+	}
+
+	for _, s := range statements {
+		stmt, err := tx.Prepare(s.query)
+		if err != nil {
+			w.close()
+			return nil, fmt.Errorf("failed to prepare SQLite statement: %w", err)
+		}
+		*s.target = stmt
+	}
+
+	return w, nil
+}
+
+// close 释放预编译语句
+func (w *sqliteWriter) close() {
+	for _, stmt := range []*sql.Stmt{w.view, w.ciType, w.node, w.edge, w.attribute} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// writeTree 写入视图、视图用到的CI类型以及全部节点
+func (w *sqliteWriter) writeTree(tree *models.ServiceTreeData) error {
+	topo, err := json.Marshal(tree.Config.Topo)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.view.Exec(w.snapshotID, tree.ViewID, tree.ViewName, tree.Config.IsPublic, string(topo),
+		len(tree.RootNodes), tree.TotalNodes, tree.MaxDepth, tree.CrawledAt.UTC().Format(sqliteTimeLayout)); err != nil {
+		return err
+	}
+
+	typeIDs := make([]string, 0, len(tree.ID2Type))
+	for id := range tree.ID2Type {
+		typeIDs = append(typeIDs, id)
+	}
+	sort.Strings(typeIDs)
+
+	for _, id := range typeIDs {
+		ciType := tree.ID2Type[id]
+		if _, err := w.ciType.Exec(w.snapshotID, ciType.ID, ciType.Name, ciType.Alias, ciType.UniqueName, ciType.ShowName); err != nil {
+			return err
+		}
+	}
+
+	for _, root := range tree.RootNodes {
+		if err := w.writeNode(tree.ViewName, 0, root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNode 递归写入节点、父子边和属性
+func (w *sqliteWriter) writeNode(viewName string, parentID int, node *models.ServiceTreeNode) error {
+	if _, err := w.node.Exec(w.snapshotID, viewName, node.ID, node.Type, node.TypeName, node.Name,
+		node.BuildTreePath(), node.Level, parentID, node.IsLeaf, node.ChildCount); err != nil {
+		return err
+	}
+
+	if parentID != 0 {
+		if _, err := w.edge.Exec(w.snapshotID, viewName, parentID, node.ID); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range node.Attributes {
+		if value == nil {
+			continue
+		}
+		text, valueType := sqliteAttributeValue(value)
+		if _, err := w.attribute.Exec(w.snapshotID, node.ID, key, text, valueType); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := w.writeNode(viewName, node.ID, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteAttributeValue 将属性值转为文本，字符串原样保存，其他类型保存为JSON
+func sqliteAttributeValue(value interface{}) (string, string) {
+	switch v := value.(type) {
+	case string:
+		return v, "string"
+	case bool:
+		return strconv.FormatBool(v), "bool"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), "number"
+	case int, int32, int64, float32, json.Number:
+		return fmt.Sprintf("%v", v), "number"
+	case []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded), "list"
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v), "string"
+		}
+		return string(encoded), "object"
+	}
+}
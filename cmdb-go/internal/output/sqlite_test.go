@@ -0,0 +1,96 @@
+package output
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cmdb-crawler/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// buildSQLiteTree 构建指定爬取时间的测试服务树，产品节点带有列表属性
+func buildSQLiteTree(crawledAt time.Time) *models.ServiceTreeData {
+	tree := newTestTree("产品服务树")
+	tree.CrawledAt = crawledAt
+	testNode(tree, 2).Attributes["tags"] = []interface{}{"a", "b"}
+	return tree
+}
+
+// queryInt 执行返回单个整数的查询
+func queryInt(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	var value int
+	if err := db.QueryRow(query, args...).Scan(&value); err != nil {
+		t.Fatalf("Query %q failed: %v", query, err)
+	}
+	return value
+}
+
+// TestExportSQLite 测试表结构、追加快照和重复快照
+func TestExportSQLite(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "cmdb.db")
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	exporter := NewExporter("sqlite", false, zap.NewNop())
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{buildSQLiteTree(first)}, outputPath); err != nil {
+		t.Fatalf("First export failed: %v", err)
+	}
+
+	exporter.SetAppend(true)
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{buildSQLiteTree(second)}, outputPath); err != nil {
+		t.Fatalf("Appending snapshot failed: %v", err)
+	}
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{buildSQLiteTree(second)}, outputPath); err == nil {
+		t.Error("Expected appending a snapshot with the same crawl time to fail")
+	}
+
+	db, err := sql.Open("sqlite", outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM snapshots`); got != 2 {
+		t.Errorf("Expected 2 snapshots, got %d", got)
+	}
+	latest := queryInt(t, db, `SELECT id FROM snapshots ORDER BY crawled_at DESC LIMIT 1`)
+
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM nodes WHERE snapshot_id = ?`, latest); got != 3 {
+		t.Errorf("Expected 3 nodes in latest snapshot, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT parent_id FROM nodes WHERE snapshot_id = ? AND node_id = 2`, latest); got != 1 {
+		t.Errorf("Expected parent 1 for node 2, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM edges WHERE snapshot_id = ?`, latest); got != 2 {
+		t.Errorf("Expected 2 edges, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM ci_types WHERE snapshot_id = ?`, latest); got != 3 {
+		t.Errorf("Expected 3 CI types, got %d", got)
+	}
+	if got := queryInt(t, db, `SELECT CAST(value AS INTEGER) FROM attributes WHERE snapshot_id = ? AND key = 'port'`, latest); got != 8080 {
+		t.Errorf("Expected port attribute 8080, got %d", got)
+	}
+
+	var tags string
+	if err := db.QueryRow(`SELECT value FROM attributes WHERE snapshot_id = ? AND key = 'tags'`, latest).Scan(&tags); err != nil || tags != `["a","b"]` {
+		t.Errorf("Expected tags encoded as JSON, got %q (%v)", tags, err)
+	}
+
+	// 不追加时覆盖已有文件
+	db.Close()
+	exporter.SetAppend(false)
+	if err := exporter.ExportServiceTrees([]*models.ServiceTreeData{buildSQLiteTree(second)}, outputPath); err != nil {
+		t.Fatalf("Overwrite export failed: %v", err)
+	}
+	db, err = sql.Open("sqlite", outputPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if got := queryInt(t, db, `SELECT COUNT(*) FROM snapshots`); got != 1 {
+		t.Errorf("Expected overwrite to leave 1 snapshot, got %d", got)
+	}
+}
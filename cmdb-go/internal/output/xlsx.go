@@ -0,0 +1,285 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cmdb-crawler/internal/models"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// xlsxSummarySheet 摘要工作表名称
+	xlsxSummarySheet = "Summary"
+	// xlsxMaxSheetName Excel工作表名称的最大长度
+	xlsxMaxSheetName = 31
+	// xlsxMaxOutlineLevel Excel支持的最大分组层级
+	xlsxMaxOutlineLevel = 7
+	// xlsxMaxIndent 名称列的最大缩进
+	xlsxMaxIndent = 15
+)
+
+// xlsxNodeHeaders 节点工作表的固定列，属性列追加在后面
+var xlsxNodeHeaders = []string{
+	"level", "node_id", "name", "type_id", "type_name", "path", "parent_id", "is_leaf", "child_count",
+}
+
+// xlsxSummaryHeaders 摘要工作表的列
+var xlsxSummaryHeaders = []string{
+	"view_name", "sheet", "view_id", "root_count", "total_nodes", "max_depth", "is_public", "crawled_at", "leaf_types",
+}
+
+// exportXLSX 导出为Excel文件，第一个工作表为摘要，之后每个服务树一个工作表
+// 节点按深度优先顺序排列，名称列按层级缩进，行按层级分组可折叠
+func (e *Exporter) exportXLSX(data []*models.ServiceTreeData, outputPath string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", xlsxSummarySheet); err != nil {
+		return fmt.Errorf("failed to create summary sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	// 每个缩进层级一个样式
+	indentStyles := make([]int, xlsxMaxIndent+1)
+	for level := range indentStyles {
+		indentStyles[level], err = f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Indent: level}})
+		if err != nil {
+			return fmt.Errorf("failed to create indent style: %w", err)
+		}
+	}
+
+	usedNames := map[string]bool{strings.ToLower(xlsxSummarySheet): true}
+	sheetNames := make([]string, len(data))
+	for i, tree := range data {
+		sheetNames[i] = xlsxSheetName(tree.ViewName, usedNames)
+		if _, err := f.NewSheet(sheetNames[i]); err != nil {
+			return fmt.Errorf("failed to create sheet for view %s: %w", tree.ViewName, err)
+		}
+		if err := e.writeXLSXTree(f, sheetNames[i], tree, headerStyle, indentStyles); err != nil {
+			return fmt.Errorf("failed to write sheet for view %s: %w", tree.ViewName, err)
+		}
+	}
+
+	if err := writeXLSXSummary(f, data, sheetNames, headerStyle); err != nil {
+		return fmt.Errorf("failed to write summary sheet: %w", err)
+	}
+
+	f.SetActiveSheet(0)
+	if err := f.SaveAs(outputPath); err != nil {
+		return fmt.Errorf("failed to save XLSX file: %w", err)
+	}
+
+	e.logger.Info("Successfully exported XLSX",
+		zap.String("file", outputPath),
+		zap.Int("sheets", len(data)+1))
+	return nil
+}
+
+// writeXLSXSummary 写入摘要工作表
+func writeXLSXSummary(f *excelize.File, data []*models.ServiceTreeData, sheetNames []string, headerStyle int) error {
+	sw, err := f.NewStreamWriter(xlsxSummarySheet)
+	if err != nil {
+		return err
+	}
+
+	if err := sw.SetColWidth(1, 2, 24); err != nil {
+		return err
+	}
+	if err := sw.SetRow("A1", xlsxHeaderRow(xlsxSummaryHeaders, headerStyle)); err != nil {
+		return err
+	}
+
+	for i, summary := range BuildSummaries(data) {
+		row := []interface{}{
+			summary.ViewName,
+			sheetNames[i],
+			summary.ViewID,
+			summary.RootCount,
+			summary.TotalNodes,
+			summary.MaxDepth,
+			summary.IsPublic,
+			summary.CrawledAt.Format("2006-01-02 15:04:05"),
+			strings.Join(summary.LeafTypes, ", "),
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
+
+// writeXLSXTree 写入单个服务树的节点工作表
+func (e *Exporter) writeXLSXTree(f *excelize.File, sheet string, tree *models.ServiceTreeData,
+	headerStyle int, indentStyles []int) error {
+
+	attrKeys := collectAttributeKeys(tree)
+
+	// 汇总行在明细行上方，折叠后仍能看到父节点
+	summaryBelow := false
+	if err := f.SetSheetProps(sheet, &excelize.SheetPropsOptions{OutlineSummaryBelow: &summaryBelow}); err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(3, 3, 40); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(6, 6, 60); err != nil {
+		return err
+	}
+
+	headers := append(append([]string{}, xlsxNodeHeaders...), attrKeys...)
+	if err := sw.SetRow("A1", xlsxHeaderRow(headers, headerStyle)); err != nil {
+		return err
+	}
+
+	rowNum := 2
+	var walk func(parentID int, node *models.ServiceTreeNode) error
+	walk = func(parentID int, node *models.ServiceTreeNode) error {
+		indent := node.Level
+		if indent > xlsxMaxIndent {
+			indent = xlsxMaxIndent
+		}
+
+		row := []interface{}{
+			node.Level,
+			node.ID,
+			excelize.Cell{StyleID: indentStyles[indent], Value: node.Name},
+			node.Type,
+			node.TypeName,
+			node.BuildTreePath(),
+			parentID,
+			node.IsLeaf,
+			node.ChildCount,
+		}
+		for _, key := range attrKeys {
+			row = append(row, xlsxCellValue(node.Attributes[key]))
+		}
+
+		outline := node.Level
+		if outline > xlsxMaxOutlineLevel {
+			outline = xlsxMaxOutlineLevel
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := sw.SetRow(cell, row, excelize.RowOpts{OutlineLevel: outline}); err != nil {
+			return err
+		}
+		rowNum++
+
+		for _, child := range node.Children {
+			if err := walk(node.ID, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, root := range tree.RootNodes {
+		if err := walk(0, root); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
+
+// collectAttributeKeys 收集服务树中所有节点属性键的并集，按名称排序
+func collectAttributeKeys(tree *models.ServiceTreeData) []string {
+	seen := make(map[string]bool)
+
+	var walk func(node *models.ServiceTreeNode)
+	walk = func(node *models.ServiceTreeNode) {
+		for key := range node.Attributes {
+			seen[key] = true
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, root := range tree.RootNodes {
+		walk(root)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// xlsxHeaderRow 生成带样式的表头行
+func xlsxHeaderRow(headers []string, styleID int) []interface{} {
+	row := make([]interface{}, len(headers))
+	for i, header := range headers {
+		row[i] = excelize.Cell{StyleID: styleID, Value: header}
+	}
+	return row
+}
+
+// xlsxCellValue 转换属性值，标量保持原类型，列表和对象编码为JSON
+func xlsxCellValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, int, int32, int64, float32, float64:
+		return v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// xlsxSheetName 生成合法且不重复的工作表名称
+func xlsxSheetName(viewName string, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, viewName)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "view"
+	}
+
+	candidate := truncateRunes(name, xlsxMaxSheetName)
+	// Excel工作表名称不区分大小写
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		suffix := " (" + strconv.Itoa(i) + ")"
+		candidate = truncateRunes(name, xlsxMaxSheetName-len(suffix)) + suffix
+	}
+
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+// TestExportXLSX 测试摘要工作表、每视图工作表、属性列和行分组
+func TestExportXLSX(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "trees.xlsx")
+	exporter := NewExporter("xlsx", false, zap.NewNop())
+	data := []*models.ServiceTreeData{newTestTree("产品/服务树"), newTestTree("产品:服务树")}
+	if err := exporter.ExportServiceTrees(data, outputPath); err != nil {
+		t.Fatalf("ExportServiceTrees failed: %v", err)
+	}
+
+	f, err := excelize.OpenFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open XLSX: %v", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	expected := []string{"Summary", "产品_服务树", "产品_服务树 (2)"}
+	if len(sheets) != len(expected) {
+		t.Fatalf("Expected sheets %v, got %v", expected, sheets)
+	}
+	for i, name := range expected {
+		if sheets[i] != name {
+			t.Errorf("Expected sheet %d to be %s, got %s", i, name, sheets[i])
+		}
+	}
+
+	rows, err := f.GetRows(sheets[1])
+	if err != nil {
+		t.Fatalf("Failed to read rows: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected header and 3 node rows, got %d rows", len(rows))
+	}
+	header := rows[0]
+	if header[len(header)-2] != "owner" || header[len(header)-1] != "port" {
+		t.Errorf("Expected attribute columns owner and port, got %v", header)
+	}
+	if rows[2][len(rows[2])-1] != "8080" {
+		t.Errorf("Expected port value in last column, got %v", rows[2])
+	}
+
+	level, err := f.GetRowOutlineLevel(sheets[1], 3)
+	if err != nil || level != 1 {
+		t.Errorf("Expected child row outline level 1, got %d (%v)", level, err)
+	}
+
+	props, err := f.GetSheetProps(sheets[1])
+	if err != nil || props.OutlineSummaryBelow == nil || *props.OutlineSummaryBelow {
+		t.Errorf("Expected outline summary rows above details, got %+v (%v)", props, err)
+	}
+
+	summary, err := f.GetRows("Summary")
+	if err != nil || len(summary) != 3 || summary[1][4] != "3" {
+		t.Errorf("Expected summary rows with total nodes, got %v (%v)", summary, err)
+	}
+}
@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// Redacted 日志中替换敏感信息的占位符
+	Redacted = "******"
+	// execTimeout exec: 引用执行命令的超时
+	execTimeout = 10 * time.Second
+	// minRedactLength 参与日志脱敏的最短敏感值长度，过短的值会误替换普通文本
+	minRedactLength = 4
+)
+
+// IsReference 值是否为 env:、file: 或 exec: 引用
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, "env:") ||
+		strings.HasPrefix(value, "file:") ||
+		strings.HasPrefix(value, "exec:")
+}
+
+// Resolve 解析敏感配置值，非引用的值原样返回
+//   - env:NAME 读取环境变量
+//   - file:/path 读取文件内容，去掉末尾换行
+//   - exec:command 通过 sh -c 执行命令，取标准输出并去掉末尾换行
+func Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return resolved, nil
+	case strings.HasPrefix(value, "file:"):
+		path := strings.TrimPrefix(value, "file:")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, "exec:"):
+		ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+		defer cancel()
+
+		command := strings.TrimPrefix(value, "exec:")
+		out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+		if err != nil {
+			// 不返回命令输出，避免错误信息中带出敏感内容
+			return "", fmt.Errorf("secret command failed: %w", err)
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	default:
+		return value, nil
+	}
+}
+
+// Mask 遮盖敏感值，只保留足够长的值的前4个字符便于核对
+func Mask(value string) string {
+	if len(value) < 12 {
+		return Redacted
+	}
+	return value[:4] + Redacted
+}
+
+// Redactor 记录已知的敏感值并将其从文本中替换为 Redacted
+type Redactor struct {
+	mu       sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+// NewRedactor 创建脱敏器
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Add 添加需要脱敏的值，过短或已登记的值被忽略
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) >= minRedactLength && !slices.Contains(r.values, value) {
+			r.values = append(r.values, value)
+		}
+	}
+
+	// 长的值优先替换，避免一个敏感值是另一个的子串时替换不完整
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	pairs := make([]string, 0, len(r.values)*2)
+	for _, value := range r.values {
+		pairs = append(pairs, value, Redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact 替换文本中的敏感值
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// WrapCore 包装日志Core，写出前对消息和字符串、错误类型字段脱敏，用于 zap.WrapCore
+func (r *Redactor) WrapCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core, redactor: r}
+}
+
+// redactCore 脱敏日志Core
+type redactCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// With 添加上下文字段
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactFields(fields)), redactor: c.redactor}
+}
+
+// Check 判断日志是否需要写出
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write 脱敏后写出日志
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Redact(entry.Message)
+	return c.Core.Write(entry, c.redactFields(fields))
+}
+
+// redactFields 对字符串、字节串和错误类型的字段脱敏
+func (c *redactCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = c.redactor.Redact(field.String)
+		case zapcore.ByteStringType:
+			field = zap.String(field.Key, c.redactor.Redact(string(field.Interface.([]byte))))
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok && err != nil {
+				field = zap.String(field.Key, c.redactor.Redact(err.Error()))
+			}
+		}
+		redacted[i] = field
+	}
+	return redacted
+}
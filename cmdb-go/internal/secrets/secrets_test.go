@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestResolve 测试解析 env:、file:、exec: 引用
+func TestResolve(t *testing.T) {
+	t.Setenv("CMDB_TEST_SECRET", "from-env")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain-value", "plain-value"},
+		{"env:CMDB_TEST_SECRET", "from-env"},
+		{"file:" + path, "from-file"},
+		{"exec:echo from-exec", "from-exec"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.value)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"env:CMDB_TEST_SECRET_MISSING", "file:" + path + ".missing", "exec:exit 1"} {
+		if _, err := Resolve(value); err == nil {
+			t.Errorf("Expected error resolving %q", value)
+		}
+	}
+
+	if IsReference("plain-value") || !IsReference("env:X") || !IsReference("file:/x") || !IsReference("exec:x") {
+		t.Error("IsReference returned unexpected result")
+	}
+}
+
+// TestRedactor 测试日志消息和字段中的敏感值被替换
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor()
+	redactor.Add("s3cr3t-value", "abc", "")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redactor.WrapCore(core)).With(zap.String("context", "key=s3cr3t-value"))
+	logger.Debug("signing with s3cr3t-value",
+		zap.String("sign_string", "/api/v0.1/ci/s3cr3t-value10"),
+		zap.ByteString("body", []byte("s3cr3t-value")),
+		zap.Error(errors.New("bad secret s3cr3t-value")),
+		zap.String("short", "abc"),
+		zap.Int("count", 1))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if strings.Contains(entry.Message, "s3cr3t-value") {
+		t.Errorf("Message not redacted: %s", entry.Message)
+	}
+	fields := entry.ContextMap()
+	for key, value := range fields {
+		if s, ok := value.(string); ok && strings.Contains(s, "s3cr3t-value") {
+			t.Errorf("Field %s not redacted: %s", key, s)
+		}
+	}
+	if fields["sign_string"] != "/api/v0.1/ci/"+Redacted+"10" {
+		t.Errorf("Unexpected sign_string: %v", fields["sign_string"])
+	}
+	// 过短的值不参与脱敏
+	if fields["short"] != "abc" || fields["count"] != int64(1) {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+}
+
+// TestMask 测试遮盖API Key
+func TestMask(t *testing.T) {
+	if got := Mask("example-api-key-0000"); got != "exam"+Redacted {
+		t.Errorf("Mask = %q", got)
+	}
+	if got := Mask("short"); got != Redacted {
+		t.Errorf("Mask = %q", got)
+	}
+}
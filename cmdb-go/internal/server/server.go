@@ -0,0 +1,382 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cmdb-crawler/internal/models"
+	"cmdb-crawler/internal/output"
+
+	"go.uber.org/zap"
+)
+
+// defaultSearchLimit 搜索默认返回的最大结果数
+const defaultSearchLimit = 100
+
+// nodeRef 节点索引项，记录节点所在视图和祖先链
+type nodeRef struct {
+	viewName  string
+	node      *models.ServiceTreeNode
+	ancestors []*models.ServiceTreeNode
+}
+
+// Server 服务树查询服务
+// 在内存中保存最近一次爬取结果，通过REST API提供查询
+type Server struct {
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	trees     []*models.ServiceTreeData
+	views     map[string]*models.ServiceTreeData
+	nodes     map[int][]nodeRef
+	updatedAt time.Time
+	lastError string
+}
+
+// NewServer 创建服务树查询服务
+func NewServer(logger *zap.Logger) *Server {
+	return &Server{
+		logger: logger,
+		views:  make(map[string]*models.ServiceTreeData),
+		nodes:  make(map[int][]nodeRef),
+	}
+}
+
+// Update 替换内存中的服务树数据并重建索引
+func (s *Server) Update(trees []*models.ServiceTreeData) {
+	views := make(map[string]*models.ServiceTreeData, len(trees))
+	nodes := make(map[int][]nodeRef)
+
+	for _, tree := range trees {
+		views[tree.ViewName] = tree
+		for _, root := range tree.RootNodes {
+			indexNode(nodes, tree.ViewName, root, nil)
+		}
+	}
+
+	s.mu.Lock()
+	s.trees = trees
+	s.views = views
+	s.nodes = nodes
+	s.updatedAt = time.Now()
+	s.lastError = ""
+	s.mu.Unlock()
+
+	s.logger.Info("Service tree data updated",
+		zap.Int("tree_count", len(trees)),
+		zap.Int("indexed_nodes", len(nodes)))
+}
+
+// SetError 记录最近一次爬取失败的原因，已有数据保持不变
+func (s *Server) SetError(err error) {
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+}
+
+// indexNode 递归建立节点索引
+func indexNode(index map[int][]nodeRef, viewName string, node *models.ServiceTreeNode, ancestors []*models.ServiceTreeNode) {
+	index[node.ID] = append(index[node.ID], nodeRef{
+		viewName:  viewName,
+		node:      node,
+		ancestors: ancestors,
+	})
+
+	childAncestors := make([]*models.ServiceTreeNode, len(ancestors)+1)
+	copy(childAncestors, ancestors)
+	childAncestors[len(ancestors)] = node
+
+	for _, child := range node.Children {
+		indexNode(index, viewName, child, childAncestors)
+	}
+}
+
+// Handler 返回HTTP处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/views", s.handleViews)
+	mux.HandleFunc("/views/", s.handleViewTree)
+	mux.HandleFunc("/nodes/", s.handleNode)
+	mux.HandleFunc("/search", s.handleSearch)
+	return s.logRequests(mux)
+}
+
+// logRequests 记录请求日志
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		s.logger.Debug("HTTP request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Duration("duration", time.Since(start)))
+	})
+}
+
+// HealthResponse 健康检查响应
+type HealthResponse struct {
+	Status    string    `json:"status"`
+	TreeCount int       `json:"tree_count"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// handleHealth GET /healthz
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	resp := HealthResponse{
+		Status:    "ok",
+		TreeCount: len(s.trees),
+		UpdatedAt: s.updatedAt,
+		LastError: s.lastError,
+	}
+	s.mu.RUnlock()
+
+	status := http.StatusOK
+	if resp.UpdatedAt.IsZero() {
+		resp.Status = "initializing"
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+// handleViews GET /views，返回与摘要导出相同的结构
+func (s *Server) handleViews(w http.ResponseWriter, r *http.Request) {
+	if !s.ready(w, r) {
+		return
+	}
+
+	s.mu.RLock()
+	resp := output.NewSummaryExport(string(output.FormatJSON), s.trees)
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleViewTree GET /views/{name}/tree，返回与JSON导出相同的结构
+func (s *Server) handleViewTree(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.EscapedPath(), "/views/")
+	escapedName, suffix, found := strings.Cut(rest, "/")
+	if !found || suffix != "tree" || escapedName == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	name, err := url.PathUnescape(escapedName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid view name")
+		return
+	}
+
+	if !s.ready(w, r) {
+		return
+	}
+
+	s.mu.RLock()
+	tree, ok := s.views[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "view not found: "+name)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, output.NewServiceTreeExport(string(output.FormatJSON), []*models.ServiceTreeData{tree}))
+}
+
+// NodeOccurrence 节点在某个视图中的出现位置
+type NodeOccurrence struct {
+	ViewName  string                    `json:"view_name"`
+	Node      *models.ServiceTreeNode   `json:"node"`
+	Ancestors []*models.ServiceTreeNode `json:"ancestors,omitempty"`
+	// DescendantIDs 该位置下子树中所有后代CI的ID（去重、升序）
+	DescendantIDs []int `json:"descendant_ids"`
+}
+
+// NodeResponse 节点查询响应
+type NodeResponse struct {
+	NodeID      int              `json:"node_id"`
+	Occurrences []NodeOccurrence `json:"occurrences"`
+}
+
+// handleNode GET /nodes/{id} 和 GET /nodes/{id}/ancestors
+func (s *Server) handleNode(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/nodes/")
+	idStr, suffix, _ := strings.Cut(rest, "/")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid node id: "+idStr)
+		return
+	}
+
+	withAncestors := false
+	switch suffix {
+	case "":
+	case "ancestors":
+		withAncestors = true
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if !s.ready(w, r) {
+		return
+	}
+
+	s.mu.RLock()
+	refs := s.nodes[id]
+	s.mu.RUnlock()
+
+	if len(refs) == 0 {
+		writeError(w, http.StatusNotFound, "node not found: "+idStr)
+		return
+	}
+
+	resp := NodeResponse{NodeID: id, Occurrences: make([]NodeOccurrence, 0, len(refs))}
+	for _, ref := range refs {
+		occurrence := NodeOccurrence{ViewName: ref.viewName, DescendantIDs: descendantIDs(ref.node)}
+		if withAncestors {
+			occurrence.Node = shallowCopy(ref.node)
+			occurrence.Ancestors = make([]*models.ServiceTreeNode, len(ref.ancestors))
+			for i, ancestor := range ref.ancestors {
+				occurrence.Ancestors[i] = shallowCopy(ancestor)
+			}
+		} else {
+			occurrence.Node = ref.node
+		}
+		resp.Occurrences = append(resp.Occurrences, occurrence)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// descendantIDs 返回节点子树中所有后代CI的ID，同一CI出现多次时只保留一个
+func descendantIDs(node *models.ServiceTreeNode) []int {
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, descendant := range node.GetAllDescendants() {
+		if !seen[descendant.ID] {
+			seen[descendant.ID] = true
+			ids = append(ids, descendant.ID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// SearchResult 搜索结果项
+type SearchResult struct {
+	ViewName string                  `json:"view_name"`
+	Path     string                  `json:"path"`
+	Node     *models.ServiceTreeNode `json:"node"`
+}
+
+// SearchResponse 搜索响应
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
+}
+
+// handleSearch GET /search?q=&view=&limit=，按节点名称或类型名称做不区分大小写的子串匹配
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+limitStr)
+			return
+		}
+		limit = parsed
+	}
+	viewFilter := r.URL.Query().Get("view")
+
+	if !s.ready(w, r) {
+		return
+	}
+
+	needle := strings.ToLower(query)
+	resp := SearchResponse{Query: query, Results: make([]SearchResult, 0)}
+
+	s.mu.RLock()
+	ids := make([]int, 0, len(s.nodes))
+	for id := range s.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		for _, ref := range s.nodes[id] {
+			if viewFilter != "" && ref.viewName != viewFilter {
+				continue
+			}
+			if !strings.Contains(strings.ToLower(ref.node.Name), needle) &&
+				!strings.Contains(strings.ToLower(ref.node.TypeName), needle) {
+				continue
+			}
+
+			resp.Total++
+			if len(resp.Results) < limit {
+				resp.Results = append(resp.Results, SearchResult{
+					ViewName: ref.viewName,
+					Path:     ref.node.BuildTreePath(),
+					Node:     shallowCopy(ref.node),
+				})
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ready 检查数据是否已加载，并只允许GET请求
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+
+	s.mu.RLock()
+	loaded := !s.updatedAt.IsZero()
+	s.mu.RUnlock()
+
+	if !loaded {
+		writeError(w, http.StatusServiceUnavailable, "service tree data not loaded yet")
+		return false
+	}
+	return true
+}
+
+// shallowCopy 复制节点但不包含子节点，避免响应中带出整棵子树
+func shallowCopy(node *models.ServiceTreeNode) *models.ServiceTreeNode {
+	copied := *node
+	copied.Children = nil
+	return &copied
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
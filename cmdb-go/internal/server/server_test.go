@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"cmdb-crawler/internal/models"
+	"cmdb-crawler/internal/output"
+
+	"go.uber.org/zap"
+)
+
+// newTestServer 创建带测试数据的服务
+func newTestServer(t *testing.T) *httptest.Server {
+	root := &models.ServiceTreeNode{ID: 1, Type: 1, TypeName: "产品线", Name: "电商产品线"}
+	product := &models.ServiceTreeNode{ID: 2, Type: 2, TypeName: "产品", Name: "电商APP"}
+	env := &models.ServiceTreeNode{ID: 3, Type: 3, TypeName: "环境", Name: "生产环境"}
+	root.AddChild(product)
+	product.AddChild(env)
+
+	tree := &models.ServiceTreeData{ViewName: "产品服务树", ViewID: 1, RootNodes: []*models.ServiceTreeNode{root}}
+	tree.CountNodes()
+	tree.CalculateMaxDepth()
+
+	srv := NewServer(zap.NewNop())
+	srv.Update([]*models.ServiceTreeData{tree})
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// getJSON 请求并解析JSON响应
+func getJSON(t *testing.T, url string, expectedStatus int, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		t.Fatalf("GET %s: expected status %d, got %d", url, expectedStatus, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: failed to decode response: %v", url, err)
+		}
+	}
+}
+
+// TestServerNotReady 测试数据加载前返回503
+func TestServerNotReady(t *testing.T) {
+	ts := httptest.NewServer(NewServer(zap.NewNop()).Handler())
+	defer ts.Close()
+
+	getJSON(t, ts.URL+"/views", http.StatusServiceUnavailable, nil)
+	getJSON(t, ts.URL+"/healthz", http.StatusServiceUnavailable, nil)
+}
+
+// TestServerViews 测试视图列表和视图树
+func TestServerViews(t *testing.T) {
+	ts := newTestServer(t)
+
+	var summary output.SummaryExport
+	getJSON(t, ts.URL+"/views", http.StatusOK, &summary)
+	if len(summary.Summary) != 1 || summary.Summary[0].TotalNodes != 3 {
+		t.Errorf("Unexpected views summary: %+v", summary)
+	}
+
+	var export output.ServiceTreeExport
+	getJSON(t, ts.URL+"/views/"+url.PathEscape("产品服务树")+"/tree", http.StatusOK, &export)
+	if len(export.ServiceTrees) != 1 || export.ServiceTrees[0].RootNodes[0].Children[0].ID != 2 {
+		t.Errorf("Unexpected view tree: %+v", export)
+	}
+
+	getJSON(t, ts.URL+"/views/missing/tree", http.StatusNotFound, nil)
+}
+
+// TestServerNodes 测试节点和祖先查询
+func TestServerNodes(t *testing.T) {
+	ts := newTestServer(t)
+
+	var node NodeResponse
+	getJSON(t, ts.URL+"/nodes/2", http.StatusOK, &node)
+	if len(node.Occurrences) != 1 || len(node.Occurrences[0].Node.Children) != 1 {
+		t.Errorf("Expected node 2 with its subtree, got %+v", node)
+	}
+
+	var root NodeResponse
+	getJSON(t, ts.URL+"/nodes/1", http.StatusOK, &root)
+	if ids := root.Occurrences[0].DescendantIDs; len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("Expected descendant ids [2 3], got %v", ids)
+	}
+
+	var ancestors NodeResponse
+	getJSON(t, ts.URL+"/nodes/3/ancestors", http.StatusOK, &ancestors)
+	chain := ancestors.Occurrences[0].Ancestors
+	if len(chain) != 2 || chain[0].ID != 1 || chain[1].ID != 2 {
+		t.Errorf("Expected ancestors [1 2], got %+v", chain)
+	}
+	if len(chain[0].Children) != 0 {
+		t.Error("Expected ancestors to be returned without children")
+	}
+	if ids := ancestors.Occurrences[0].DescendantIDs; ids == nil || len(ids) != 0 {
+		t.Errorf("Expected empty descendant ids for leaf node, got %v", ids)
+	}
+
+	getJSON(t, ts.URL+"/nodes/999", http.StatusNotFound, nil)
+	getJSON(t, ts.URL+"/nodes/abc", http.StatusBadRequest, nil)
+}
+
+// TestServerSearch 测试搜索
+func TestServerSearch(t *testing.T) {
+	ts := newTestServer(t)
+
+	var resp SearchResponse
+	getJSON(t, ts.URL+"/search?q="+url.QueryEscape("电商"), http.StatusOK, &resp)
+	if resp.Total != 2 {
+		t.Errorf("Expected 2 matches, got %d", resp.Total)
+	}
+
+	getJSON(t, ts.URL+"/search?q="+url.QueryEscape("电商")+"&limit=1", http.StatusOK, &resp)
+	if resp.Total != 2 || len(resp.Results) != 1 {
+		t.Errorf("Expected limit to cap results but not total, got total=%d results=%d", resp.Total, len(resp.Results))
+	}
+
+	getJSON(t, ts.URL+"/search", http.StatusBadRequest, nil)
+}
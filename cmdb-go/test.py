@@ -1,4 +1,5 @@
 import hashlib
+import os
 import requests
 from urllib.parse import urlparse
 import warnings
@@ -7,8 +8,8 @@ import warnings
 warnings.filterwarnings('ignore', message='urllib3 v2 only supports OpenSSL 1.1.1+')
 
 BASE_URL = "https://cmdb.veops.cn"
-KEY = "d0a8fb5aeedf466c92cc5142a18d1a68"
-SECRET = "DSGYH81jqfw~%A&vgyJKXrO*UFVaW2xt"
+KEY = os.environ["CMDB_API_KEY"]
+SECRET = os.environ["CMDB_API_SECRET"]
 
 
 def build_api_key(path, params):
@@ -22,7 +23,7 @@ def build_api_key(path, params):
     # 调试信息
     print(f"请求路径: {path}")
     print(f"参数: {params}")
-    print(f"签名字符串: {path + SECRET + values}")
+    print(f"签名字符串: {path + '******' + values}")
     print(f"签名结果: {signature}")
     print()
 